@@ -28,6 +28,9 @@ import (
 	"time"
 )
 
+// Transport is used by the Qodana Cloud requests, nil means http.DefaultTransport.
+var Transport http.RoundTripper
+
 const (
 	QodanaEndpoint     = "ENDPOINT"
 	DefaultEndpoint    = "qodana.cloud"
@@ -59,7 +62,8 @@ type QdClient struct {
 func NewQdClient(token string) *QdClient {
 	return &QdClient{
 		httpClient: &http.Client{
-			Timeout: requestTimeout,
+			Transport: Transport,
+			Timeout:   requestTimeout,
 		},
 		token: token,
 	}
@@ -134,7 +134,8 @@ func requestLicenseDataAttempt(endpoint string, token string) ([]byte, error) {
 	timeout := getTimeout()
 
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Transport: Transport,
+		Timeout:   time.Duration(timeout) * time.Second,
 	}
 
 	url := fmt.Sprintf("%s%s", endpoint, qodanaLicenseUri)
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newBaselineCommand returns a new instance of the baseline command.
+func newBaselineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Inspect the baseline report",
+		Long:  `Inspect the baseline SARIF report used with qodana scan --baseline.`,
+	}
+	cmd.AddCommand(newBaselineStatsCommand())
+	return cmd
+}
+
+// newBaselineStatsCommand returns a new instance of the baseline stats command.
+func newBaselineStatsCommand() *cobra.Command {
+	projectDir := ""
+	cmd := &cobra.Command{
+		Use:   "stats [baseline SARIF file]",
+		Short: "Print the number of problems accepted in the baseline",
+		Long:  `Print how many problems the baseline report accepts, by severity and by rule, and when it was generated. Without the argument, the baseline from qodana.yaml is used.`,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			baselinePath := ""
+			if len(args) > 0 {
+				baselinePath = args[0]
+			} else if baseline := core.LoadQodanaYaml(filepath.Join(projectDir, core.FindQodanaYaml(projectDir))).Baseline; baseline != "" {
+				baselinePath = filepath.Join(projectDir, baseline)
+			} else {
+				core.ErrorMessage("No baseline is configured in qodana.yaml, pass the path of the baseline SARIF report")
+				os.Exit(core.QodanaConfigErrorExitCode)
+			}
+			stats, err := core.ReadBaselineStats(baselinePath)
+			if err != nil {
+				log.Fatalf("Failed to read the baseline %s: %s", baselinePath, err)
+			}
+			if err = core.PrintBaselineStats(cmd.OutOrStdout(), baselinePath, stats); err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&projectDir, "project-dir", "i", ".", "Root directory of the project, the baseline from its qodana.yaml is used if the path is not passed")
+	return cmd
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	"github.com/spf13/cobra"
+)
+
+// newCleanCommand returns a new instance of the clean command.
+func newCleanCommand() *cobra.Command {
+	images := false
+	yes := false
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove Qodana caches and pulled linter images",
+		Long: `Report the disk space taken by the linter caches and, with --images, by the pulled linter images.
+Nothing is removed unless --yes is passed.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			core.Clean(images, yes)
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(&images, "images", false, "Also remove the pulled Qodana linter images")
+	flags.BoolVar(&yes, "yes", false, "Remove the reported items, without it the command only shows what would be removed")
+	return cmd
+}
@@ -30,7 +30,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 
 	"github.com/JetBrains/qodana-cli/v2023/core"
 )
@@ -164,6 +166,69 @@ func TestDeprecatedScanFlags(t *testing.T) {
 	}
 }
 
+func TestSetFlagsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"QODANA_PROFILE_NAME":   "qodana.recommended",
+		"QODANA_FAIL_THRESHOLD": "10",
+		"QODANA_LINTER":         "jvm",
+		"QODANA_IDE":            "QDNET",
+		"QODANA_ENV":            "reserved",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	command := newScanCommand()
+	err := command.ParseFlags([]string{"--fail-threshold", "5", "--ide", "QDPHP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setFlagsFromEnv(command)
+
+	for flag, expected := range map[string]string{
+		"profile-name":   "qodana.recommended", // env var is used
+		"fail-threshold": "5",                  // explicit flag wins
+		"ide":            "QDPHP",
+		"linter":         "", // mutually exclusive with the explicit --ide
+		"env":            "[]",
+	} {
+		actual := command.Flags().Lookup(flag).Value.String()
+		if actual != expected {
+			t.Fatalf("expected --%s=\"%s\", got \"%s\"", flag, expected, actual)
+		}
+	}
+}
+
+func TestApplyScenario(t *testing.T) {
+	command := newScanCommand()
+	err := command.ParseFlags([]string{"--fail-threshold", "5", "--commit", "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := applyScenario(command, "pr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(applied, " ") != "--print-problems=true" {
+		t.Fatalf("expected only --print-problems to be applied, got %v", applied)
+	}
+	for flag, expected := range map[string]string{
+		"fail-threshold":       "5", // explicit flag wins
+		"changes-since-branch": "",  // mutually exclusive with the explicit --commit
+		"print-problems":       "true",
+	} {
+		actual := command.Flags().Lookup(flag).Value.String()
+		if actual != expected {
+			t.Fatalf("expected --%s=\"%s\", got \"%s\"", flag, expected, actual)
+		}
+	}
+
+	_, err = applyScenario(newScanCommand(), "nightly")
+	expected := `unknown scenario "nightly", available scenarios are: baseline, full, pr`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
 func TestInitCommand(t *testing.T) {
 	projectPath := createProject(t, "qodana_init")
 	err := os.WriteFile(projectPath+"/qodana.yml", []byte("version: 1.0"), 0o755)
@@ -185,7 +250,7 @@ func TestInitCommand(t *testing.T) {
 		t.Fatalf("expected \"qodana.yml\" got \"%s\"", filename)
 	}
 
-	qodanaYaml := core.LoadQodanaYaml(projectPath, filename)
+	qodanaYaml := core.LoadQodanaYaml(filepath.Join(projectPath, filename))
 
 	if qodanaYaml.Linter != core.Image(core.QDPYC) {
 		t.Fatalf("expected \"%s\", but got %s", core.Image(core.QDPYC), qodanaYaml.Linter)
@@ -197,6 +262,61 @@ func TestInitCommand(t *testing.T) {
 	}
 }
 
+func TestInitCommandWithLinter(t *testing.T) {
+	projectPath := createProject(t, "qodana_init_linter")
+	out := bytes.NewBufferString("")
+	command := newInitCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"-i", projectPath, "--linter", "jvm"})
+	err := command.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qodanaYaml := core.LoadQodanaYaml(filepath.Join(projectPath, core.FindQodanaYaml(projectPath)))
+	if qodanaYaml.Linter != core.Image(core.QDJVM) {
+		t.Fatalf("expected \"%s\", but got %s", core.Image(core.QDJVM), qodanaYaml.Linter)
+	}
+
+	err = os.RemoveAll(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanDryRun(t *testing.T) {
+	projectPath := createProject(t, "qodana_scan_dry_run")
+	resultsDir := filepath.Join(projectPath, "results")
+	out := bytes.NewBufferString("")
+	command := newScanCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"-i", projectPath, "-o", resultsDir, "-l", "python-community", "--property", "idea.log.level=debug", "--dry-run"})
+	err := command.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := out.String()
+	for _, expected := range []string{
+		"docker run ",
+		fmt.Sprintf("-v %s:/data/project ", projectPath),
+		fmt.Sprintf("-v %s:/data/results ", resultsDir),
+		core.Image(core.QDPYC),
+		"--property=idea.log.level=debug",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in %q", expected, output)
+		}
+	}
+	if _, err = os.Stat(resultsDir); !os.IsNotExist(err) {
+		t.Fatalf("results directory should not be created on dry run")
+	}
+
+	err = os.RemoveAll(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestExclusiveFixesCommand(t *testing.T) {
 	if os.Getenv("GITHUB_ACTIONS") == "true" {
 		//goland:noinspection GoBoolExpressions
@@ -238,6 +358,119 @@ func TestContributorsCommand(t *testing.T) {
 	}
 }
 
+func TestListLintersCommand(t *testing.T) {
+	projectPath := createProject(t, "qodana_list_linters")
+	out := bytes.NewBufferString("")
+	command := newListLintersCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"-i", projectPath, "--json"})
+	err := command.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var linters []map[string]interface{}
+	err = json.Unmarshal(output, &linters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(linters) != len(core.AllCodes) {
+		t.Fatalf("expected %d linters, but got %d", len(core.AllCodes), len(linters))
+	}
+	for _, l := range linters {
+		selected := l["selected"].(bool)
+		if selected != (l["code"] == core.QDPYC) {
+			t.Fatalf("unexpected selection for %s: %v", l["code"], selected)
+		}
+	}
+
+	err = os.RemoveAll(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProfilesCommand(t *testing.T) {
+	out := bytes.NewBufferString("")
+	command := newProfilesCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"-i", t.TempDir(), "--json"})
+	err := command.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var profiles []map[string]string
+	err = json.Unmarshal(out.Bytes(), &profiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) == 0 || profiles[0]["source"] != "built-in" {
+		t.Fatalf("unexpected profiles: %v", profiles)
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range completionShells {
+		out := bytes.NewBufferString("")
+		command := newCompletionCommand()
+		command.SetOut(out)
+		command.SetArgs([]string{shell})
+		if err := command.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "__complete") {
+			t.Fatalf("unexpected %s completion script: %s", shell, out.String())
+		}
+	}
+
+	out := bytes.NewBufferString("")
+	command := newRootCommand()
+	command.AddCommand(newPullCommand())
+	command.SetOut(out)
+	command.SetArgs([]string{cobra.ShellCompRequestCmd, "pull", "--linter", "python"})
+	if err := command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "python\tjetbrains/qodana-python:"
+	if !strings.Contains(out.String(), expected) || strings.Contains(out.String(), "jvm") {
+		t.Fatalf("expected %s completion, got: %s", expected, out.String())
+	}
+}
+
+func TestColorFlags(t *testing.T) {
+	defer core.DisableColor()
+	for _, tc := range []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"--color", "profiles", "--json"}, true},
+		{[]string{"--no-color", "profiles", "--json"}, false},
+	} {
+		command := newRootCommand()
+		command.AddCommand(newProfilesCommand())
+		command.SetOut(io.Discard)
+		command.SetArgs(tc.args)
+		if err := command.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		if pterm.PrintColor != tc.expected {
+			t.Fatalf("%v: expected colored output %v", tc.args, tc.expected)
+		}
+	}
+
+	command := newRootCommand()
+	command.AddCommand(newProfilesCommand())
+	command.SetOut(io.Discard)
+	command.SetErr(io.Discard)
+	command.SetArgs([]string{"--color", "--no-color", "profiles"})
+	if err := command.Execute(); err == nil {
+		t.Fatal("expected an error for --color with --no-color")
+	}
+}
+
 func TestPullInNative(t *testing.T) {
 	projectPath := createProject(t, "qodana_scan_python_native")
 	yamlFile := filepath.Join(projectPath, "qodana.yaml")
@@ -413,3 +646,26 @@ func TestScanWithIde(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestConfigValidateWithConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("version: \"1.0\"\nunknownKey: true\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "custom.yaml")
+	err = os.WriteFile(configPath, []byte("version: \"1.0\"\nlinter: jetbrains/qodana-jvm:2023.3\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBufferString("")
+	command := newConfigValidateCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"-i", projectDir, "--config", configPath})
+	if err = command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "config is valid" {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
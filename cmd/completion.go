@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// completionShells are the shells the completion command generates scripts for.
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// newCompletionCommand returns a new instance of the completion command.
+func newCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate the shell completion script",
+		Long: `Generate the completion script of Qodana CLI for the given shell, for example:
+
+  bash:        source <(qodana completion bash)
+  zsh:         qodana completion zsh > "${fpath[1]}/_qodana"
+  fish:        qodana completion fish > ~/.config/fish/completions/qodana.fish
+  powershell:  qodana completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:             completionShells,
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+			var err error
+			switch args[0] {
+			case "bash":
+				err = root.GenBashCompletionV2(out, true)
+			case "zsh":
+				err = root.GenZshCompletion(out)
+			case "fish":
+				err = root.GenFishCompletion(out, true)
+			case "powershell":
+				err = root.GenPowerShellCompletionWithDesc(out)
+			}
+			if err != nil {
+				log.Fatalf("Failed to generate the %s completion script: %s", args[0], err)
+			}
+		},
+	}
+	return cmd
+}
+
+// registerLinterCompletion completes --linter values with the known linter aliases, if the command has the flag.
+func registerLinterCompletion(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("linter") == nil {
+		return
+	}
+	err := cmd.RegisterFlagCompletionFunc("linter", func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return core.LinterCompletions(toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// registerProfileNameCompletion completes --profile-name values with the profiles of the linter (--linter or the one
+// from qodana.yaml) and the project profiles.
+func registerProfileNameCompletion(cmd *cobra.Command) {
+	err := cmd.RegisterFlagCompletionFunc("profile-name", func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		projectDir, err := cmd.Flags().GetString("project-dir")
+		if err != nil {
+			projectDir = "."
+		}
+		linter, _ := cmd.Flags().GetString("linter")
+		return core.ProfileNames(projectDir, configuredLinter(projectDir, linter)), cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
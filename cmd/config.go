@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// configOptions represents config command options.
+type configOptions struct {
+	ProjectDir string
+	YamlName   string
+	ConfigPath string
+}
+
+// newConfigCommand returns a new instance of the config command.
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage qodana.yaml",
+		Long:  `Manage Qodana configuration file qodana.yaml.`,
+	}
+	cmd.AddCommand(newConfigValidateCommand())
+	return cmd
+}
+
+// newConfigValidateCommand returns a new instance of the config validate command.
+func newConfigValidateCommand() *cobra.Command {
+	options := &configOptions{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate qodana.yaml",
+		Long:  `Check qodana.yaml for unknown keys, wrong value types and invalid linter names. Exits with a non-zero code if the configuration is not valid.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if options.ConfigPath != "" {
+				options.ProjectDir, options.YamlName = filepath.Split(options.ConfigPath)
+			} else if options.YamlName == "" {
+				options.YamlName = core.FindQodanaYaml(options.ProjectDir)
+			}
+			problems, err := core.ValidateQodanaYaml(options.ProjectDir, options.YamlName)
+			if err != nil {
+				core.ErrorMessage("Could not read %s: %s", options.YamlName, err)
+				os.Exit(core.QodanaConfigErrorExitCode)
+			}
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					core.ErrorMessage("%s: %s", options.YamlName, problem)
+				}
+				os.Exit(core.QodanaConfigErrorExitCode)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+			if err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.StringVar(&options.YamlName, "yaml-name", "", "Override qodana.yaml name")
+	flags.StringVar(&options.ConfigPath, "config", "", "Path to the configuration file to validate instead of qodana.yaml from the project")
+	cmd.MarkFlagsMutuallyExclusive("config", "yaml-name")
+	return cmd
+}
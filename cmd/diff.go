@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// diffOptions represents diff command options.
+type diffOptions struct {
+	OldSarifFile string
+	NewSarifFile string
+	Format       string
+}
+
+// newDiffCommand returns a new instance of the diff command.
+func newDiffCommand() *cobra.Command {
+	options := &diffOptions{}
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare two SARIF files",
+		Long: `Compare two SARIF files and show which problems were introduced or fixed.
+
+Problems are matched by rule id, file and fingerprint.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			diff, err := core.DiffSarif(options.OldSarifFile, options.NewSarifFile)
+			if err != nil {
+				log.Fatalf("Failed to compare SARIF files: %s", err)
+			}
+			switch options.Format {
+			case "text":
+				core.PrintSarifDiff(diff)
+			case "json":
+				out, err := core.SarifDiffToJSON(diff)
+				if err != nil {
+					log.Fatalf("Failed to convert to JSON: %s", err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), out)
+				if err != nil {
+					log.Fatalf("Failed to write to stdout: %s", err)
+				}
+			default:
+				log.Fatalf("Unknown output format: %s", options.Format)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&options.OldSarifFile, "old", "", "Path to the old SARIF file")
+	flags.StringVar(&options.NewSarifFile, "new", "", "Path to the new SARIF file")
+	flags.StringVar(&options.Format, "format", "text", "Output format, can be text or json")
+	if err := cmd.MarkFlagRequired("old"); err != nil {
+		log.Fatal(err)
+	}
+	if err := cmd.MarkFlagRequired("new"); err != nil {
+		log.Fatal(err)
+	}
+	return cmd
+}
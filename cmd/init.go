@@ -21,6 +21,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2023/core"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"os"
 	"path/filepath"
 )
 
@@ -28,6 +29,8 @@ import (
 func newInitCommand() *cobra.Command {
 	options := &core.QodanaOptions{}
 	force := false
+	linter := ""
+	stubProfile := false
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Configure a project for Qodana",
@@ -36,8 +39,20 @@ func newInitCommand() *cobra.Command {
 			if options.YamlName == "" {
 				options.YamlName = core.FindQodanaYaml(options.ProjectDir)
 			}
-			qodanaYaml := core.LoadQodanaYaml(options.ProjectDir, options.YamlName)
-			if (qodanaYaml.Linter == "" && qodanaYaml.Ide == "") || force {
+			qodanaYaml := core.LoadQodanaYaml(filepath.Join(options.ProjectDir, options.YamlName))
+			if linter != "" {
+				absPath, err := filepath.Abs(options.ProjectDir)
+				if err != nil {
+					log.Fatal(err)
+				}
+				options.ProjectDir = absPath
+				analyzer := core.SetAnalyzer(options.ProjectDir, linter, options.YamlName)
+				if core.IsNativeAnalyzer(analyzer) {
+					options.Ide = analyzer
+				} else {
+					options.Linter = analyzer
+				}
+			} else if (qodanaYaml.Linter == "" && qodanaYaml.Ide == "") || force {
 				absPath, err := filepath.Abs(options.ProjectDir)
 				if err != nil {
 					log.Fatal(err)
@@ -71,6 +86,18 @@ func newInitCommand() *cobra.Command {
 					core.SuccessMessage("The .NET configuration was successfully set")
 				}
 			}
+			if stubProfile {
+				path, err := core.WriteStubProfile(options.ProjectDir, options.YamlName, force)
+				if err != nil {
+					core.ErrorMessage("Could not write the starter profile: %s", err)
+					os.Exit(core.QodanaConfigErrorExitCode)
+				}
+				if path != "" {
+					core.SuccessMessage("The starter profile was written to %s", core.PrimaryBold(path))
+				} else {
+					core.WarningMessage("The profile is already configured, run the command with %s flag to overwrite it", core.PrimaryBold("-f"))
+				}
+			}
 			core.PrintFile(filepath.Join(options.ProjectDir, options.YamlName))
 			options.Linter = qodanaYaml.Linter
 			options.Ide = qodanaYaml.Ide
@@ -82,6 +109,9 @@ func newInitCommand() *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project to configure")
 	flags.BoolVarP(&force, "force", "f", false, "Force initialization (overwrite existing valid qodana.yaml)")
+	flags.StringVarP(&linter, "linter", "l", "", "Linter (image, its alias or native IDE code) to write to qodana.yaml without prompting, overrides the configured one")
 	flags.StringVar(&options.YamlName, "yaml-name", "", "Override qodana.yaml name")
+	flags.BoolVar(&stubProfile, "stub-profile", false, "Write a starter inspection profile for the detected languages to .idea/inspectionProfiles and reference it from qodana.yaml")
+	registerLinterCompletion(cmd)
 	return cmd
 }
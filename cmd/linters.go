@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// listLintersOptions represents list-linters command options.
+type listLintersOptions struct {
+	ProjectDir string
+	Json       bool
+}
+
+// newListLintersCommand returns a new instance of the list-linters command.
+func newListLintersCommand() *cobra.Command {
+	options := &listLintersOptions{}
+	cmd := &cobra.Command{
+		Use:   "list-linters",
+		Short: "List all supported linters",
+		Long:  `List all supported Qodana linters: product codes, Docker images and languages they target. The linter that would be auto-selected for the project is marked.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			linters := core.GetLinters(options.ProjectDir)
+			if !options.Json {
+				core.PrintLintersTable(linters)
+				return
+			}
+			out, err := core.LintersToJSON(linters)
+			if err != nil {
+				log.Fatalf("Failed to convert to JSON: %s", err)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), out)
+			if err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project to detect the linter for")
+	flags.BoolVar(&options.Json, "json", false, "Print linters in JSON format")
+
+	return cmd
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newMergeCommand returns a new instance of the merge command.
+func newMergeCommand() *cobra.Command {
+	output := ""
+	cmd := &cobra.Command{
+		Use:   "merge <results-dir or SARIF file>...",
+		Short: "Merge SARIF reports of several linters",
+		Long: `Combine the qodana.sarif.json reports of several linters (e.g. of a polyglot project) into one SARIF report,
+so a single quality gate or report covers all languages.
+
+Each linter run is kept as a separate run with its tool metadata, results identical to the ones of the previous runs are dropped.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			duplicates, err := core.MergeSarif(args, output)
+			if err != nil {
+				log.Fatalf("Failed to merge SARIF files: %s", err)
+			}
+			core.SuccessMessage("Merged %d reports into %s, %d duplicate results dropped", len(args), output, duplicates)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&output, "output", "o", core.QodanaSarifName, "Path of the merged SARIF report")
+	return cmd
+}
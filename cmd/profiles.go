@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// profilesOptions represents profiles command options.
+type profilesOptions struct {
+	ProjectDir string
+	Linter     string
+	Json       bool
+}
+
+// newProfilesCommand returns a new instance of the profiles command.
+func newProfilesCommand() *cobra.Command {
+	options := &profilesOptions{}
+	cmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List inspection profiles available for --profile-name",
+		Long: `List the inspection profiles that can be passed to --profile-name: the profiles shipped with the linter image and the profiles stored in .idea/inspectionProfiles of the project.
+
+The linter image is run once to list its profiles, the result is cached by the image digest. If the image is not pulled, the profiles built into every Qodana linter are listed instead.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			profiles := core.GetProfiles(options.ProjectDir, configuredLinter(options.ProjectDir, options.Linter))
+			if !options.Json {
+				core.PrintProfilesTable(profiles)
+				return
+			}
+			out, err := core.ProfilesToJSON(profiles)
+			if err != nil {
+				log.Fatalf("Failed to convert to JSON: %s", err)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), out)
+			if err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project to list the profiles of")
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Linter (image or alias) to list the profiles of (default: the linter from qodana.yaml)")
+	flags.BoolVar(&options.Json, "json", false, "Print profiles in JSON format")
+	registerLinterCompletion(cmd)
+
+	return cmd
+}
+
+// configuredLinter returns the given linter or the linter configured in qodana.yaml of the project.
+func configuredLinter(projectDir string, linter string) string {
+	if linter != "" {
+		return linter
+	}
+	return core.LoadQodanaYaml(filepath.Join(projectDir, core.FindQodanaYaml(projectDir))).Linter
+}
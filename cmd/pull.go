@@ -17,6 +17,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/JetBrains/qodana-cli/v2023/core"
 	"github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
@@ -26,11 +28,17 @@ import (
 // newPullCommand returns a new instance of the show command.
 func newPullCommand() *cobra.Command {
 	options := &core.QodanaOptions{}
+	all := false
+	jobs := core.DefaultPullJobs
 	cmd := &cobra.Command{
 		Use:   "pull",
 		Short: "Pull latest version of linter",
 		Long:  `An alternative to pull an image.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if all {
+				pullAllImages(options.Registry, options.PullRetries, jobs)
+				return
+			}
 			options.FetchAnalyzerSettings()
 			if options.Ide != "" {
 				log.Println("Native mode is used, skipping pull")
@@ -38,15 +46,49 @@ func newPullCommand() *cobra.Command {
 				core.PrepareContainerEnvSettings()
 				containerClient, err := client.NewClientWithOpts()
 				if err != nil {
-					log.Fatal("couldn't connect to container engine ", err)
+					core.ErrorMessage("Couldn't connect to container engine: %s", err)
+					os.Exit(core.QodanaDockerErrorExitCode)
 				}
-				core.PullImage(containerClient, options.Linter)
+				core.PrepareImage(containerClient, options.Linter, options.PullPolicy, options.PullRetries)
 			}
 		},
 	}
 	flags := cmd.Flags()
 	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
 	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(&options.PullPolicy, "pull-policy", core.PullPolicyMissing, "Pull the image: 'always', 'missing' (only if it is not present locally) or 'never'")
+	flags.IntVar(&options.PullRetries, "pull-retries", core.DefaultPullRetries, "Number of retries for network and registry server failures while pulling the image")
+	flags.StringVar(&options.Registry, "registry", "", "Registry mirror to pull the linter image from, e.g. registry.example.com (images already qualified with a registry are used as is)")
 	flags.StringVarP(&options.YamlName, "yaml-name", "y", core.FindQodanaYaml(options.ProjectDir), "Override qodana.yaml name")
+	flags.BoolVar(&all, "all", false, "Pull the images of all supported linters, e.g. to populate a mirror for offline use")
+	flags.IntVar(&jobs, "jobs", jobs, "Number of images pulled at a time with --all (default: the number of CPUs, at most 4)")
+	cmd.MarkFlagsMutuallyExclusive("all", "linter")
+	cmd.MarkFlagsMutuallyExclusive("all", "pull-policy")
+	registerLinterCompletion(cmd)
 	return cmd
 }
+
+// pullAllImages pulls the images of all supported linters, exits with an error if any of them failed.
+func pullAllImages(registry string, pullRetries int, jobs int) {
+	if jobs < 1 {
+		core.ErrorMessage("--jobs must be at least 1, got %d", jobs)
+		os.Exit(core.QodanaConfigErrorExitCode)
+	}
+	images := core.AllImages
+	if registry != "" {
+		var err error
+		if images, err = core.ImagesWithRegistry(images, registry); err != nil {
+			core.ErrorMessage("%s", err)
+			os.Exit(core.QodanaConfigErrorExitCode)
+		}
+	}
+	core.PrepareContainerEnvSettings()
+	containerClient, err := client.NewClientWithOpts()
+	if err != nil {
+		core.ErrorMessage("Couldn't connect to container engine: %s", err)
+		os.Exit(core.QodanaDockerErrorExitCode)
+	}
+	if failed := core.PullImages(containerClient, images, pullRetries, jobs); len(failed) > 0 {
+		os.Exit(core.QodanaDockerErrorExitCode)
+	}
+}
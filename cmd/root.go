@@ -21,10 +21,15 @@ import (
 	"github.com/JetBrains/qodana-cli/v2023/core"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"os"
+	"strings"
 )
 
+// mutuallyExclusiveAnnotation is the annotation cobra uses to store mutually exclusive flag groups.
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
 // isHelp checks if only help was requested.
 func isHelp(args []string) bool {
 	return len(args) == 2 && (args[1] == "--help" || args[1] == "-h")
@@ -42,21 +47,60 @@ func isCommandRequested(commands []*cobra.Command, args []string) string {
 	return ""
 }
 
+// isCompletionRequest checks if the shell completion script requests the completions.
+func isCompletionRequest(args []string) bool {
+	return len(args) > 1 && (args[1] == cobra.ShellCompRequestCmd || args[1] == cobra.ShellCompNoDescRequestCmd)
+}
+
 // setDefaultCommandIfNeeded sets default scan command if no other command is requested.
 func setDefaultCommandIfNeeded(rootCmd *cobra.Command, args []string) {
-	if !(isHelp(args) || isCommandRequested(rootCmd.Commands(), args[1:]) != "") {
+	if !(isHelp(args) || isCompletionRequest(args) || isCommandRequested(rootCmd.Commands(), args[1:]) != "") {
 		newArgs := append([]string{"scan"}, args[1:]...)
 		rootCmd.SetArgs(newArgs)
 	}
 }
 
+// isExcludedByChangedFlag checks if any flag mutually exclusive with the given one was set explicitly.
+func isExcludedByChangedFlag(flags *pflag.FlagSet, flag *pflag.Flag) bool {
+	for _, group := range flag.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Split(group, " ") {
+			if f := flags.Lookup(name); f != nil && name != flag.Name && f.Changed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setFlagsFromEnv sets the flags that were not set explicitly from QODANA_<FLAG_NAME> environment variables.
+// Explicit flags override environment variables, environment variables override qodana.yaml.
+func setFlagsFromEnv(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || flag.Name == "help" || isExcludedByChangedFlag(flags, flag) {
+			return
+		}
+		value, ok := core.LookupOptionEnv(flag.Name)
+		if !ok {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			log.Fatalf("Invalid value of %s environment variable: %s", core.OptionEnv(flag.Name), err)
+		}
+		log.Debugf("Set --%s from %s environment variable", flag.Name, core.OptionEnv(flag.Name))
+	})
+}
+
 // Execute is a main CLI entrypoint: handles user interrupt, CLI start and everything else.
 func Execute() {
 	if !core.IsContainer() && os.Geteuid() == 0 {
 		core.WarningMessage("Running the tool as root is dangerous: please run it as a regular user")
 	}
+	if core.IsNoStatisticsRequested(os.Args[1:]) {
+		core.DisableCheckUpdates = true
+	}
 	go core.CheckForUpdates(core.Version)
-	if !core.IsInteractive() || os.Getenv("NO_COLOR") != "" { // http://no-color.org
+	if !core.IsColorSupported() {
 		core.DisableColor()
 	}
 
@@ -67,7 +111,7 @@ func Execute() {
 		if err != nil {
 			return
 		}
-		os.Exit(1)
+		core.Exit(1)
 	}
 
 	core.CheckForUpdates(core.Version)
@@ -75,17 +119,25 @@ func Execute() {
 
 // newRootCommand constructs root command.
 func newRootCommand() *cobra.Command {
+	color, noColor := false, false
 	rootCmd := &cobra.Command{
 		Use:     "qodana",
 		Short:   "Run Qodana CLI",
 		Long:    core.Info,
 		Version: core.Version,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			logLevel, err := log.ParseLevel(viper.GetString("log-level"))
-			if err != nil {
+			setFlagsFromEnv(cmd)
+			if err := core.SetLogLevel(viper.GetString("log-level")); err != nil {
 				log.Fatal(err)
 			}
-			log.SetLevel(logLevel)
+			if err := core.SetLogFormat(viper.GetString("log-format")); err != nil {
+				log.Fatal(err)
+			}
+			if color {
+				core.EnableColor()
+			} else if noColor {
+				core.DisableColor()
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -96,11 +148,22 @@ func newRootCommand() *cobra.Command {
 			}
 		},
 	}
-	rootCmd.PersistentFlags().String("log-level", "error", "Set log-level for output")
+	rootCmd.PersistentFlags().String("log-level", "error", "Set log-level for output: "+strings.Join(core.LogLevels, ", ")+". The debug level prints the full container command and SARIF parsing details")
+	rootCmd.PersistentFlags().String("log-format", core.LogFormatText, "Set log format for output: text or json (one JSON object with timestamp, level and message fields per line, the linter output included)")
+	rootCmd.PersistentFlags().BoolVarP(&core.Quiet, "quiet", "q", false, "Print only errors, the same as --log-level error without progress and informational messages")
 	rootCmd.PersistentFlags().BoolVar(&core.DisableCheckUpdates, "disable-update-checks", false, "Disable check for updates")
+	rootCmd.PersistentFlags().BoolVar(&core.DisableCheckUpdates, "no-update-check", false, "Disable check for updates, the same as --disable-update-checks (default: true in CI, otherwise updates are checked once per 24 hours)")
+	rootCmd.PersistentFlags().BoolVar(&core.DisableMasking, "no-mask", false, "Print secrets as is in the logged commands, only for local debugging")
+	rootCmd.PersistentFlags().BoolVar(&color, "color", false, "Force colored output, even if the output is not a terminal or NO_COLOR is set")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output, the same as NO_COLOR=1 (default: colors are disabled if the output is not a terminal)")
+	rootCmd.MarkFlagsMutuallyExclusive("log-level", "quiet")
+	rootCmd.MarkFlagsMutuallyExclusive("color", "no-color")
 	if err := viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level")); err != nil {
 		log.Fatal(err)
 	}
+	if err := viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format")); err != nil {
+		log.Fatal(err)
+	}
 	return rootCmd
 }
 
@@ -114,8 +177,16 @@ func init() {
 		newShowCommand(),
 		newSendCommand(),
 		newPullCommand(),
+		newCleanCommand(),
 		newViewCommand(),
+		newDiffCommand(),
+		newMergeCommand(),
+		newBaselineCommand(),
+		newConfigCommand(),
 		newContributorsCommand(),
 		newClocCommand(),
+		newListLintersCommand(),
+		newProfilesCommand(),
+		newCompletionCommand(),
 	)
 }
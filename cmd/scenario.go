@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// scenarioFlag is a flag value set by a --scenario preset.
+type scenarioFlag struct {
+	name  string
+	value string
+}
+
+// scenarios are the --scenario presets for common workflows.
+var scenarios = map[string][]scenarioFlag{
+	// pr checks the changes of a pull request: any problem in the changed files fails the run.
+	"pr": {
+		{"changes-since-branch", "origin/HEAD"},
+		{"fail-threshold", "0"},
+		{"print-problems", "true"},
+	},
+	// full analyzes the whole project with the stricter recommended profile.
+	"full": {
+		{"profile-name", "qodana.recommended"},
+		{"print-problems", "true"},
+	},
+	// baseline fails only on the problems absent from the baseline, the baseline is taken from --baseline or qodana.yaml.
+	"baseline": {
+		{"fail-on-new", "true"},
+		{"print-problems", "true"},
+	},
+}
+
+// scenarioNames returns the sorted names of the --scenario presets.
+func scenarioNames() []string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyScenario sets the flags of the --scenario preset, the flags passed explicitly (or with environment variables)
+// and the flags mutually exclusive with them are kept. It returns the applied flags.
+func applyScenario(cmd *cobra.Command, scenario string) ([]string, error) {
+	preset, ok := scenarios[scenario]
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q, available scenarios are: %s", scenario, strings.Join(scenarioNames(), ", "))
+	}
+	flags := cmd.Flags()
+	applied := make([]string, 0, len(preset))
+	for _, f := range preset {
+		flag := flags.Lookup(f.name)
+		if flag == nil || flag.Changed || isExcludedByChangedFlag(flags, flag) {
+			log.Debugf("Scenario %s: --%s is set explicitly", scenario, f.name)
+			continue
+		}
+		if err := flags.Set(f.name, f.value); err != nil {
+			return nil, err
+		}
+		applied = append(applied, fmt.Sprintf("--%s=%s", f.name, f.value))
+	}
+	return applied, nil
+}
+
+// useScenario applies the --scenario preset and prints the options it has chosen.
+func useScenario(cmd *cobra.Command, scenario string, options *core.QodanaOptions) {
+	applied, err := applyScenario(cmd, scenario)
+	if err != nil {
+		core.ErrorMessage("%s", err)
+		core.Exit(options.ExitCode(core.QodanaConfigErrorExitCode))
+	}
+	if len(applied) > 0 {
+		core.SuccessMessage("Scenario %s: %s", scenario, strings.Join(applied, " "))
+	}
+}
@@ -47,6 +47,8 @@ This command serves the Qodana report locally and opens a browser to it.`,
 					options.ResultsDir,
 					options.ReportDir,
 					options.Port,
+					options.OpenIn,
+					options.WaitForReport,
 				)
 			}
 		},
@@ -56,8 +58,11 @@ This command serves the Qodana report locally and opens a browser to it.`,
 	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
 	flags.StringVarP(&options.ResultsDir, "results-dir", "o", "", "Override directory to save Qodana inspection results to (default <userCacheDir>/JetBrains/<linter>/results)")
 	flags.StringVarP(&options.ReportDir, "report-dir", "r", "", "Override directory to save Qodana HTML report to (default <userCacheDir>/JetBrains/<linter>/results/report)")
-	flags.IntVarP(&options.Port, "port", "p", 8080, "Specify port to serve report at")
+	flags.IntVarP(&options.Port, "port", "p", 8080, "Specify port to serve report at, 0 picks a free port")
+	flags.StringVar(&options.OpenIn, "open-in", "", "Command to open the report URL with, e.g. 'firefox' (the URL is passed as the last argument, quote a path with spaces), or 'none' to only print the URL (default: the system browser)")
+	flags.BoolVar(&options.WaitForReport, "wait-for-report", false, "Wait until the report responds (up to 30 seconds) before printing and opening its URL")
 	flags.BoolVarP(&openDir, "dir-only", "d", false, "Open report directory only, don't serve it")
 	flags.StringVarP(&options.YamlName, "yaml-name", "y", core.FindQodanaYaml(options.ProjectDir), "Override qodana.yaml name")
+	registerLinterCompletion(cmd)
 	return cmd
 }
@@ -17,13 +17,23 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/JetBrains/qodana-cli/v2023/core"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // viewOptions represents view command options.
 type viewOptions struct {
-	SarifFile string
+	SarifFile  string
+	Format     string
+	MaxRows    int
+	SortBy     string
+	GroupBy    string
+	Filter     core.ProblemFilter
+	OpenFile   int
+	ProjectDir string
 }
 
 // newViewCommand returns a new instance of the show command.
@@ -34,10 +44,66 @@ func newViewCommand() *cobra.Command {
 		Short: "View SARIF files in CLI",
 		Long:  `Preview all problems found in SARIF files in CLI.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			core.ReadSarif(options.SarifFile, true)
+			if err := core.ValidateProblemsOrder(options.SortBy, options.GroupBy); err != nil {
+				log.Fatal(err)
+			}
+			if err := options.Filter.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			if options.OpenFile != 0 && options.Format != "text" {
+				log.Fatal("--open-file is supported only for the text format")
+			}
+			core.RequireCompleteSarif(options.SarifFile)
+			switch options.Format {
+			case "text":
+				core.ReadSarif(options.SarifFile, options.ProjectDir, true, options.SortBy, options.GroupBy, 0, options.Filter)
+				if options.OpenFile != 0 {
+					err := core.OpenProblem(options.SarifFile, options.ProjectDir, options.SortBy, options.GroupBy, options.Filter, options.OpenFile)
+					if err != nil {
+						log.Fatalf("Failed to open problem #%d: %s", options.OpenFile, err)
+					}
+				}
+			case "json":
+				out, hidden, err := core.SarifToJSON(options.SarifFile, options.Filter)
+				if err != nil {
+					log.Fatalf("Failed to convert to JSON: %s", err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), out)
+				if err != nil {
+					log.Fatalf("Failed to write to stdout: %s", err)
+				}
+				printHiddenProblems(cmd, hidden)
+			case "markdown":
+				out, hidden, err := core.SarifToMarkdown(options.SarifFile, options.MaxRows, options.Filter)
+				if err != nil {
+					log.Fatalf("Failed to convert to Markdown: %s", err)
+				}
+				_, err = fmt.Fprint(cmd.OutOrStdout(), out)
+				if err != nil {
+					log.Fatalf("Failed to write to stdout: %s", err)
+				}
+				printHiddenProblems(cmd, hidden)
+			default:
+				log.Fatalf("Unknown output format: %s", options.Format)
+			}
 		},
 	}
 	flags := cmd.Flags()
 	flags.StringVarP(&options.SarifFile, "sarif-file", "f", core.QodanaSarifName, "Path to the SARIF file")
+	flags.StringVar(&options.Format, "format", "text", "Output format, can be text, json or markdown (a collapsible summary of new problems for PR comments)")
+	flags.IntVar(&options.MaxRows, "max-rows", core.DefaultMarkdownRows, "Maximum number of problems listed in the markdown output, negative – no limit")
+	flags.StringVar(&options.SortBy, "sort-by", core.SortBySeverity, "Order of the problems in the text output: severity (the most severe first), file or rule")
+	flags.StringVar(&options.GroupBy, "group-by", "", "Group the problems in the text output by file or rule")
+	flags.StringSliceVar(&options.Filter.Severities, "severity", []string{}, "Show only the problems with the given severities or levels, e.g. error,warning or critical,high")
+	flags.StringSliceVar(&options.Filter.Rules, "rule", []string{}, "Show only the problems reported by the given rules (you can use the flag multiple times)")
+	flags.IntVar(&options.OpenFile, "open-file", 0, "Open the file of the problem with the given number (as printed, e.g. #3) at the problem line in $VISUAL or $EDITOR")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project the problem paths are relative to")
 	return cmd
 }
+
+// printHiddenProblems reports the number of problems hidden by the filter to stderr, so the output stays machine-readable.
+func printHiddenProblems(cmd *cobra.Command, hidden int) {
+	if hidden > 0 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d problem(s) hidden by the filter\n", hidden)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// githubActionsEnv is set to "true" by GitHub Actions runners.
+	githubActionsEnv = "GITHUB_ACTIONS"
+
+	annotationError   = "error"
+	annotationWarning = "warning"
+	annotationNotice  = "notice"
+)
+
+// IsGitHubActions returns true if the CLI is running inside a GitHub Actions job.
+func IsGitHubActions() bool {
+	return os.Getenv(githubActionsEnv) == "true"
+}
+
+// severityGate tells which problem severities fail the analysis.
+type severityGate struct {
+	any        bool
+	severities map[string]bool
+}
+
+// newSeverityGate builds the gate from the fail threshold and qodana.yaml failure conditions, nil means no gate is configured.
+func newSeverityGate(failThreshold string, q *QodanaYaml) *severityGate {
+	if failThreshold != "" || q.FailThreshold != nil {
+		return &severityGate{any: true}
+	}
+	t := q.FailureConditions.SeverityThresholds
+	if t == nil {
+		return nil
+	}
+	gate := &severityGate{any: t.Any != nil, severities: map[string]bool{}}
+	for severity, threshold := range map[string]*int{
+		"critical": t.Critical,
+		"high":     t.High,
+		"moderate": t.Moderate,
+		"low":      t.Low,
+		"info":     t.Info,
+	} {
+		if threshold != nil {
+			gate.severities[severity] = true
+		}
+	}
+	return gate
+}
+
+// annotationLevel returns the GitHub annotation level for the given Qodana (or SARIF) severity.
+// If a gate is configured, only the gated severities are reported as errors.
+func annotationLevel(severity string, gate *severityGate) string {
+	severity = lower(severity)
+	level := annotationNotice
+	switch severity {
+	case "critical", "high", "error":
+		level = annotationError
+	case "moderate", "warning":
+		level = annotationWarning
+	}
+	if gate == nil {
+		return level
+	}
+	if gate.any || gate.severities[severity] {
+		return annotationError
+	}
+	if level == annotationError {
+		return annotationWarning
+	}
+	return level
+}
+
+// escapeAnnotationData escapes the message part of a workflow command.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a property value of a workflow command.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// githubAnnotations returns workflow commands for new problems, identical annotations are reported once.
+func githubAnnotations(problems []Result, gate *severityGate) []string {
+	seen := map[string]bool{}
+	annotations := make([]string, 0)
+	for _, p := range problems {
+		if p.BaselineState == baselineStateUnchanged || p.BaselineState == baselineStateAbsent {
+			continue
+		}
+		properties := make([]string, 0)
+		if p.File != "" {
+			properties = append(properties, "file="+escapeAnnotationProperty(p.File))
+			if p.Line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", p.Line))
+			}
+			if p.Column > 0 {
+				properties = append(properties, fmt.Sprintf("col=%d", p.Column))
+			}
+		}
+		properties = append(properties, "title="+escapeAnnotationProperty(p.RuleId))
+		annotation := fmt.Sprintf(
+			"::%s %s::%s",
+			annotationLevel(p.Severity, gate),
+			strings.Join(properties, ","),
+			escapeAnnotationData(p.Message),
+		)
+		if !seen[annotation] {
+			seen[annotation] = true
+			annotations = append(annotations, annotation)
+		}
+	}
+	return annotations
+}
+
+// PrintGitHubAnnotations writes GitHub Actions annotations for the problems found in the given SARIF file.
+func PrintGitHubAnnotations(w io.Writer, sarifPath string, options *QodanaOptions) error {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return err
+	}
+	gate := newSeverityGate(options.FailThreshold, LoadQodanaYaml(options.qodanaYamlPath()))
+	for _, annotation := range githubAnnotations(problems, gate) {
+		if _, err = fmt.Fprintln(w, annotation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGithubAnnotations(t *testing.T) {
+	one := 1
+	problems := []Result{
+		{RuleId: "PyUnusedLocal", Severity: "High", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 5}},
+		{RuleId: "PyUnusedLocal", Severity: "High", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 5}},
+		{RuleId: "PyTypeChecker", Severity: "Moderate", Message: "Wrong type\nexpected int", Location: Location{File: "a,b.py", Line: 10}},
+		{RuleId: "PyOld", Severity: "Critical", Message: "Old problem", Location: Location{File: "old.py", Line: 1}, BaselineState: baselineStateUnchanged},
+		{RuleId: "ProjectLevel", Severity: "Info", Message: "100% done"},
+	}
+
+	tests := []struct {
+		name     string
+		gate     *severityGate
+		expected []string
+	}{
+		{
+			"Default severity mapping",
+			nil,
+			[]string{
+				"::error file=main.py,line=3,col=5,title=PyUnusedLocal::Unused variable",
+				"::warning file=a%2Cb.py,line=10,title=PyTypeChecker::Wrong type%0Aexpected int",
+				"::notice title=ProjectLevel::100%25 done",
+			},
+		},
+		{
+			"Fail threshold gates all severities",
+			newSeverityGate("10", &QodanaYaml{}),
+			[]string{
+				"::error file=main.py,line=3,col=5,title=PyUnusedLocal::Unused variable",
+				"::error file=a%2Cb.py,line=10,title=PyTypeChecker::Wrong type%0Aexpected int",
+				"::error title=ProjectLevel::100%25 done",
+			},
+		},
+		{
+			"Severity thresholds gate only configured severities",
+			newSeverityGate("", &QodanaYaml{FailureConditions: FailureConditions{SeverityThresholds: &SeverityThresholds{Moderate: &one}}}),
+			[]string{
+				"::warning file=main.py,line=3,col=5,title=PyUnusedLocal::Unused variable",
+				"::error file=a%2Cb.py,line=10,title=PyTypeChecker::Wrong type%0Aexpected int",
+				"::notice title=ProjectLevel::100%25 done",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, githubAnnotations(problems, tt.gate))
+		})
+	}
+}
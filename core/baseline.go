@@ -0,0 +1,275 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cp "github.com/otiai10/copy"
+	log "github.com/sirupsen/logrus"
+)
+
+// baselineDownloadTimeout is the timeout of the baseline download from a URL.
+const baselineDownloadTimeout = 5 * time.Minute
+
+// baselineComparison is the number of current problems that are new or unchanged compared to the baseline,
+// and the number of baseline problems absent in the current run.
+type baselineComparison struct {
+	New       int `json:"new"`
+	Unchanged int `json:"unchanged"`
+	Absent    int `json:"absent"`
+}
+
+// baselineProblems returns the baseline problems with the duplicates across runs removed: a problem reported by
+// several runs is kept as many times as the run reporting it most often, so it's not counted twice as absent.
+func baselineProblems(report *SarifReport) []Result {
+	problems := make([]Result, 0)
+	kept := make(map[string]int)
+	for _, run := range report.Runs {
+		inRun := make(map[string]int)
+		for _, p := range run.Results {
+			if p.BaselineState == baselineStateAbsent {
+				continue
+			}
+			key := p.key()
+			inRun[key]++
+			if inRun[key] > kept[key] {
+				kept[key]++
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// diffWithBaseline matches the current problems with the baseline ones by their fingerprints, the absent problems
+// carried over to the current report by --baseline-include-absent are not counted as current.
+func diffWithBaseline(baseline []Result, current []Result) sarifDiff {
+	problems := make([]Result, 0, len(current))
+	for _, p := range current {
+		if p.BaselineState != baselineStateAbsent {
+			problems = append(problems, p)
+		}
+	}
+	return diffProblems(baseline, problems)
+}
+
+// compareWithBaseline counts the new, unchanged and absent problems compared to the baseline.
+func compareWithBaseline(baseline []Result, current []Result) baselineComparison {
+	diff := diffWithBaseline(baseline, current)
+	return baselineComparison{New: len(diff.Added), Unchanged: diff.Unchanged, Absent: len(diff.Removed)}
+}
+
+// readBaselineDiff compares the problems of the SARIF report with the baseline SARIF report.
+func readBaselineDiff(baselinePath string, sarifPath string) (sarifDiff, error) {
+	baseline, err := ParseSarif(baselinePath)
+	if err != nil {
+		return sarifDiff{}, err
+	}
+	current, err := readProblems(sarifPath)
+	if err != nil {
+		return sarifDiff{}, err
+	}
+	return diffWithBaseline(baselineProblems(baseline), current), nil
+}
+
+// CompareWithBaseline compares the problems of the SARIF report with the baseline SARIF report.
+func CompareWithBaseline(baselinePath string, sarifPath string) (baselineComparison, error) {
+	diff, err := readBaselineDiff(baselinePath, sarifPath)
+	if err != nil {
+		return baselineComparison{}, err
+	}
+	return baselineComparison{New: len(diff.Added), Unchanged: diff.Unchanged, Absent: len(diff.Removed)}, nil
+}
+
+// baselinePath returns the path of the --baseline report, a relative path is resolved against the project directory.
+func (o *QodanaOptions) baselinePath() string {
+	if filepath.IsAbs(o.Baseline) {
+		return o.Baseline
+	}
+	return filepath.Join(o.ProjectDir, o.Baseline)
+}
+
+// PrintBaselineComparison prints how many problems are new, unchanged and absent compared to the --baseline report.
+func PrintBaselineComparison(sarifPath string, options *QodanaOptions) {
+	if options.Baseline == "" {
+		return
+	}
+	baselinePath := options.baselinePath()
+	comparison, err := CompareWithBaseline(baselinePath, sarifPath)
+	if err != nil {
+		log.Warnf("Could not compare the results with the baseline %s: %s", baselinePath, err)
+		return
+	}
+	SuccessMessage(
+		"Compared to the baseline: %d new, %d unchanged, %d absent problems",
+		comparison.New,
+		comparison.Unchanged,
+		comparison.Absent,
+	)
+}
+
+// FailOnNewExitCode returns QodanaFailThresholdExitCode if the SARIF report has problems absent from the --baseline
+// report and prints them, QodanaSuccessExitCode otherwise: the fail thresholds are ignored.
+// The exit codes of failed analyses are returned as is.
+func FailOnNewExitCode(exitCode int, sarifPath string, options *QodanaOptions) int {
+	if exitCode != QodanaSuccessExitCode && exitCode != QodanaFailThresholdExitCode {
+		return exitCode
+	}
+	diff, err := readBaselineDiff(options.baselinePath(), sarifPath)
+	if err != nil {
+		ErrorMessage("Could not compare the results with the baseline %s: %s", options.baselinePath(), err)
+		Exit(options.ExitCode(QodanaConfigErrorExitCode))
+	}
+	if len(diff.Added) == 0 {
+		SuccessMessage("No new problems compared to the baseline")
+		return QodanaSuccessExitCode
+	}
+	ErrorMessage("Found %s compared to the baseline:", pluralize(len(diff.Added), "new problem", "new problems"))
+	sortProblems(diff.Added, SortBySeverity)
+	for _, p := range diff.Added {
+		WarningMessage("%s:%d:%d [%s] %s: %s", p.File, p.Line, p.Column, p.Severity, p.RuleId, p.Message)
+	}
+	return QodanaFailThresholdExitCode
+}
+
+// validateBaselineWriteOnPass checks --baseline-write-on-pass has a local baseline to overwrite and warns that the
+// file is changed in the project.
+func (o *QodanaOptions) validateBaselineWriteOnPass() error {
+	if !o.BaselineWriteOnPass {
+		return nil
+	}
+	if o.Baseline == "" {
+		return configErrorf("--baseline-write-on-pass requires --baseline, --baseline-dir or baseline: in qodana.yaml")
+	}
+	if isBaselineUrl(o.Baseline) {
+		return configErrorf("--baseline-write-on-pass can't overwrite the baseline downloaded from %s", o.Baseline)
+	}
+	if scope := o.partialScopeOption(); scope != "" {
+		return configErrorf("--baseline-write-on-pass can't be combined with %s: the baseline would keep only the results of a part of the project", scope)
+	}
+	WarningMessage("The baseline %s will be overwritten if the run passes, commit the change to keep it", o.Baseline)
+	return nil
+}
+
+// partialScopeOption returns the option narrowing the analysis to a part of the project, or "" if the whole project is analyzed.
+func (o *QodanaOptions) partialScopeOption() string {
+	switch {
+	case o.Since != "":
+		return "--since"
+	case o.Commit != "":
+		return "--commit"
+	case o.ChangesSinceBranch != "":
+		return "--changes-since-branch"
+	case len(o.Files) > 0:
+		return "--files"
+	case len(o.IncludeDirs) > 0:
+		return "--include-dir"
+	case len(o.Excludes) > 0:
+		return "--exclude"
+	case o.InspectRule != "":
+		return "--inspect-rule"
+	}
+	return ""
+}
+
+// WriteBaselineOnPass overwrites the --baseline report with the SARIF report of the run if the run passed,
+// so the next runs are compared with the latest accepted state. It returns the written path or "" if nothing is written.
+func WriteBaselineOnPass(exitCode int, sarifPath string, options *QodanaOptions) (string, error) {
+	if !options.BaselineWriteOnPass || exitCode != QodanaSuccessExitCode {
+		return "", nil
+	}
+	baselinePath := options.baselinePath()
+	if err := cp.Copy(sarifPath, baselinePath); err != nil {
+		return "", err
+	}
+	return baselinePath, nil
+}
+
+// isBaselineUrl checks if the baseline is given as an http(s) URL instead of a path.
+func isBaselineUrl(baseline string) bool {
+	return strings.HasPrefix(baseline, "http://") || strings.HasPrefix(baseline, "https://")
+}
+
+// downloadBaseline downloads the baseline SARIF report from baselineUrl to dir and returns the path of the local copy.
+// The ETag of the previous download is sent back, so an unchanged baseline is not downloaded again.
+func downloadBaseline(baselineUrl string, dir string) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	baselinePath := filepath.Join(dir, getHash(baselineUrl)[:16]+".sarif.json")
+	etagPath := baselinePath + ".etag"
+
+	req, err := http.NewRequest(http.MethodGet, baselineUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(baselinePath); err == nil {
+		if etag, err := os.ReadFile(etagPath); err == nil {
+			req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+		}
+	}
+	resp, err := httpClient(baselineDownloadTimeout).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		log.Debugf("The baseline %s is not modified, using %s", baselineUrl, baselinePath)
+		return baselinePath, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("access to %s is denied (%s), check the credentials in the URL", baselineUrl, resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("baseline %s is not found (%s)", baselineUrl, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return "", fmt.Errorf("%s responded with %s", baselineUrl, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid(body) {
+		return "", fmt.Errorf("%s is not a SARIF report", baselineUrl)
+	}
+	if err = os.WriteFile(baselinePath, body, 0o644); err != nil {
+		return "", err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		err = os.WriteFile(etagPath, []byte(etag), 0o644)
+	} else {
+		err = os.Remove(etagPath)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Warnf("Could not save the ETag of the baseline %s: %s", baselineUrl, err)
+	}
+	log.Debugf("Downloaded the baseline %s to %s", baselineUrl, baselinePath)
+	return baselinePath, nil
+}
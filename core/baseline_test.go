@@ -0,0 +1,208 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareWithBaseline(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Message: "Unused variable", Location: Location{File: "main.py", Line: 3}, Fingerprint: "1"}
+	wrongType := Result{RuleId: "PyTypeChecker", Message: "Wrong type", Location: Location{File: "util.py", Line: 10}, Fingerprint: "2"}
+	fixed := Result{RuleId: "PyTypeChecker", Message: "Wrong type", Location: Location{File: "util.py", Line: 30}, Fingerprint: "3"}
+	added := Result{RuleId: "PyTypeChecker", Message: "Wrong type", Location: Location{File: "util.py", Line: 40}, Fingerprint: "4"}
+	absent := fixed
+	absent.BaselineState = baselineStateAbsent
+
+	tests := []struct {
+		name     string
+		baseline *SarifReport
+		current  []Result
+		expected baselineComparison
+	}{
+		{
+			name: "duplicates across runs are counted once",
+			baseline: &SarifReport{Runs: []Run{
+				{Results: []Result{unused, wrongType, fixed}},
+				{Results: []Result{unused, wrongType, fixed}},
+			}},
+			current:  []Result{unused, wrongType, added},
+			expected: baselineComparison{New: 1, Unchanged: 2, Absent: 1},
+		},
+		{
+			name: "repeated problems within a run are kept",
+			baseline: &SarifReport{Runs: []Run{
+				{Results: []Result{unused, unused}},
+				{Results: []Result{unused}},
+			}},
+			current:  []Result{unused},
+			expected: baselineComparison{New: 0, Unchanged: 1, Absent: 1},
+		},
+		{
+			name: "absent problems from the current report are not current",
+			baseline: &SarifReport{Runs: []Run{
+				{Results: []Result{unused, fixed}},
+			}},
+			current:  []Result{unused, absent},
+			expected: baselineComparison{New: 0, Unchanged: 1, Absent: 1},
+		},
+		{
+			name: "identical run",
+			baseline: &SarifReport{Runs: []Run{
+				{Results: []Result{unused, wrongType}},
+				{Results: []Result{wrongType}},
+			}},
+			current:  []Result{unused, wrongType},
+			expected: baselineComparison{New: 0, Unchanged: 2, Absent: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := compareWithBaseline(baselineProblems(tt.baseline), tt.current)
+			second := compareWithBaseline(baselineProblems(tt.baseline), tt.current)
+			assert.Equal(t, tt.expected, first)
+			assert.Equal(t, first, second, "counts are stable across repeated runs")
+		})
+	}
+}
+
+func TestCompareWithBaselineFiles(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Message: "Unused variable", Location: Location{File: "main.py", Line: 3}, Fingerprint: "1"}
+	added := Result{RuleId: "PyTypeChecker", Message: "Wrong type", Location: Location{File: "util.py", Line: 40}, Fingerprint: "4"}
+	baselinePath := writeTestSarif(t, t.TempDir(), []Result{unused})
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{unused, added})
+
+	comparison, err := CompareWithBaseline(baselinePath, sarifPath)
+	assert.NoError(t, err)
+	assert.Equal(t, baselineComparison{New: 1, Unchanged: 1, Absent: 0}, comparison)
+}
+
+func TestDownloadBaseline(t *testing.T) {
+	downloads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/qodana.sarif.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"runs": []}`))
+	})
+	mux.HandleFunc("/private.sarif.json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html></html>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	dir := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		baselinePath, err := downloadBaseline(server.URL+"/qodana.sarif.json", dir)
+		assert.NoError(t, err)
+		content, err := os.ReadFile(baselinePath)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"runs": []}`, string(content))
+	}
+	assert.Equal(t, 1, downloads)
+
+	for _, name := range []string{"/missing.sarif.json", "/private.sarif.json", "/index.html"} {
+		_, err := downloadBaseline(server.URL+name, dir)
+		assert.Error(t, err, name)
+	}
+
+	assert.True(t, isBaselineUrl("https://ci.example.com/artifacts/qodana.sarif.json"))
+	assert.False(t, isBaselineUrl("qodana.sarif.json"))
+}
+
+func TestFailOnNewExitCode(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 1}, Fingerprint: "1"}
+	added := Result{RuleId: "PyTypeChecker", Level: LevelError, Message: "Wrong type", Location: Location{File: "util.py", Line: 40, Column: 1}, Fingerprint: "2"}
+	projectDir := t.TempDir()
+	writeTestSarif(t, projectDir, []Result{unused})
+	options := &QodanaOptions{ProjectDir: projectDir, Baseline: QodanaSarifName}
+
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{unused})
+	assert.Equal(t, QodanaSuccessExitCode, FailOnNewExitCode(QodanaFailThresholdExitCode, sarifPath, options))
+
+	sarifPath = writeTestSarif(t, t.TempDir(), []Result{unused, added})
+	assert.Equal(t, QodanaFailThresholdExitCode, FailOnNewExitCode(QodanaSuccessExitCode, sarifPath, options))
+	assert.Equal(t, QodanaOutOfMemoryExitCode, FailOnNewExitCode(QodanaOutOfMemoryExitCode, sarifPath, options))
+}
+
+func TestWriteBaselineOnPass(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 1}, Fingerprint: "1"}
+	added := Result{RuleId: "PyTypeChecker", Level: LevelError, Message: "Wrong type", Location: Location{File: "util.py", Line: 40, Column: 1}, Fingerprint: "2"}
+	projectDir := t.TempDir()
+	writeTestSarif(t, projectDir, []Result{unused})
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{unused, added})
+
+	options := &QodanaOptions{ProjectDir: projectDir, Baseline: QodanaSarifName}
+	baseline, err := WriteBaselineOnPass(QodanaSuccessExitCode, sarifPath, options)
+	assert.NoError(t, err)
+	assert.Equal(t, "", baseline)
+
+	options.BaselineWriteOnPass = true
+	baseline, err = WriteBaselineOnPass(QodanaFailThresholdExitCode, sarifPath, options)
+	assert.NoError(t, err)
+	assert.Equal(t, "", baseline)
+	problems, err := readProblems(filepath.Join(projectDir, QodanaSarifName))
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+
+	baseline, err = WriteBaselineOnPass(QodanaSuccessExitCode, sarifPath, options)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectDir, QodanaSarifName), baseline)
+	problems, err = readProblems(baseline)
+	assert.NoError(t, err)
+	assert.Len(t, problems, 2)
+}
+
+func TestValidateBaselineWriteOnPass(t *testing.T) {
+	options := &QodanaOptions{BaselineWriteOnPass: true}
+	assert.ErrorIs(t, options.validateBaselineWriteOnPass(), ErrInvalidConfig)
+
+	options.Baseline = "https://example.com/qodana.sarif.json"
+	assert.ErrorIs(t, options.validateBaselineWriteOnPass(), ErrInvalidConfig)
+
+	options.Baseline = QodanaSarifName
+	assert.NoError(t, options.validateBaselineWriteOnPass())
+
+	for scope, set := range map[string]func(o *QodanaOptions){
+		"--since":                func(o *QodanaOptions) { o.Since = "7d" },
+		"--commit":               func(o *QodanaOptions) { o.Commit = "HEAD~1" },
+		"--changes-since-branch": func(o *QodanaOptions) { o.ChangesSinceBranch = "origin/main" },
+		"--files":                func(o *QodanaOptions) { o.Files = []string{"a.py"} },
+		"--include-dir":          func(o *QodanaOptions) { o.IncludeDirs = []string{"src"} },
+		"--exclude":              func(o *QodanaOptions) { o.Excludes = []string{"tests"} },
+		"--inspect-rule":         func(o *QodanaOptions) { o.InspectRule = "PyUnusedLocal" },
+	} {
+		options := &QodanaOptions{BaselineWriteOnPass: true, Baseline: QodanaSarifName}
+		set(options)
+		err := options.validateBaselineWriteOnPass()
+		assert.ErrorIs(t, err, ErrInvalidConfig, scope)
+		assert.Contains(t, err.Error(), scope)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// BaselineStats is the number of problems accepted in a baseline report, by severity and by rule.
+type BaselineStats struct {
+	Total      int
+	Suppressed int
+	BySeverity map[string]int
+	ByRule     map[string]int
+	// Date is when the baseline run finished, nil if the report doesn't say.
+	Date *time.Time
+}
+
+// ReadBaselineStats counts the problems of the baseline SARIF report, the problems absent from the run that produced it
+// and the duplicates across runs are not counted.
+func ReadBaselineStats(baselinePath string) (BaselineStats, error) {
+	report, err := ParseSarif(baselinePath)
+	if err != nil {
+		return BaselineStats{}, err
+	}
+	stats := BaselineStats{
+		Suppressed: report.Suppressed(),
+		BySeverity: map[string]int{},
+		ByRule:     map[string]int{},
+	}
+	for _, p := range baselineProblems(report) {
+		stats.Total++
+		stats.BySeverity[p.Severity]++
+		stats.ByRule[p.RuleId]++
+	}
+	for _, run := range report.Runs {
+		if run.Date != nil && (stats.Date == nil || run.Date.After(*stats.Date)) {
+			stats.Date = run.Date
+		}
+	}
+	return stats, nil
+}
+
+// PrintBaselineStats writes the baseline problem counts: the severities from the most severe and the rules from
+// the most frequent.
+func PrintBaselineStats(w io.Writer, baselinePath string, stats BaselineStats) error {
+	date := "unknown"
+	if stats.Date != nil {
+		date = stats.Date.UTC().Format(time.DateTime) + " UTC"
+	}
+	lines := []string{
+		fmt.Sprintf("Baseline: %s (generated %s)", baselinePath, date),
+		fmt.Sprintf("Accepted problems: %d", stats.Total),
+	}
+	if stats.Suppressed > 0 {
+		lines = append(lines, fmt.Sprintf("Suppressed problems: %d", stats.Suppressed))
+	}
+	severities := sortedKeys(stats.BySeverity)
+	sort.SliceStable(severities, func(i, j int) bool {
+		return severityRank(severities[i]) < severityRank(severities[j])
+	})
+	rules := sortedKeys(stats.ByRule)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return stats.ByRule[rules[i]] > stats.ByRule[rules[j]]
+	})
+	for _, section := range []struct {
+		title  string
+		keys   []string
+		counts map[string]int
+	}{
+		{"By severity:", severities, stats.BySeverity},
+		{"By rule:", rules, stats.ByRule},
+	} {
+		if len(section.keys) == 0 {
+			continue
+		}
+		lines = append(lines, section.title)
+		width := 0
+		for _, key := range section.keys {
+			width = max(width, len(key))
+		}
+		for _, key := range section.keys {
+			lines = append(lines, fmt.Sprintf("  %-*s  %d", width, key, section.counts[key]))
+		}
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in alphabetical order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
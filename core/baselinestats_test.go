@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owenrumney/go-sarif/v2/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaselineStats(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 1}, Fingerprint: "1"}
+	unusedUtil := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate", Message: "Unused variable", Location: Location{File: "util.py", Line: 7, Column: 1}, Fingerprint: "2"}
+	wrongType := Result{RuleId: "PyTypeChecker", Level: LevelError, Severity: "High", Message: "Wrong type", Location: Location{File: "util.py", Line: 40, Column: 5}, Fingerprint: "3"}
+	absent := Result{RuleId: "PyTypeChecker", Level: LevelError, Severity: "High", Message: "Wrong type", Location: Location{File: "old.py", Line: 1, Column: 1}, Fingerprint: "4", BaselineState: baselineStateAbsent}
+	baselinePath := writeTestSarif(t, t.TempDir(), []Result{unused, unusedUtil, wrongType, absent})
+
+	stats, err := ReadBaselineStats(baselinePath)
+	assert.NoError(t, err)
+	assert.Equal(t, BaselineStats{
+		Total:      3,
+		BySeverity: map[string]int{"High": 1, "Moderate": 2},
+		ByRule:     map[string]int{"PyUnusedLocal": 2, "PyTypeChecker": 1},
+	}, stats)
+
+	var out bytes.Buffer
+	assert.NoError(t, PrintBaselineStats(&out, "qodana.sarif.json", stats))
+	assert.Equal(t, `Baseline: qodana.sarif.json (generated unknown)
+Accepted problems: 3
+By severity:
+  High      1
+  Moderate  2
+By rule:
+  PyUnusedLocal  2
+  PyTypeChecker  1
+`, out.String())
+}
+
+func TestBaselineStatsDate(t *testing.T) {
+	report, err := sarif.New(sarif.Version210)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Date(2023, 5, 4, 10, 1, 30, 0, time.UTC)
+	run := sarif.NewRunWithInformationURI("QDPY", "https://jb.gg/qodana")
+	run.AddInvocation(true).WithEndTimeUTC(end)
+	report.AddRun(run)
+	baselinePath := filepath.Join(t.TempDir(), QodanaSarifName)
+	if err = report.WriteFile(baselinePath); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ReadBaselineStats(baselinePath)
+	assert.NoError(t, err)
+	if assert.NotNil(t, stats.Date) {
+		assert.True(t, end.Equal(*stats.Date))
+	}
+	var out bytes.Buffer
+	assert.NoError(t, PrintBaselineStats(&out, baselinePath, stats))
+	assert.Contains(t, out.String(), "(generated 2023-05-04 10:01:30 UTC)\nAccepted problems: 0\n")
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheEntry is a top-level file or directory of the cache directory.
+type cacheEntry struct {
+	Name string
+	// Size is the total size of the entry files in bytes.
+	Size int64
+	// ModTime is the latest modification time of the entry files.
+	ModTime time.Time
+}
+
+// formatBytes returns the human-readable size: 512 B, 1.5 KB, 2.0 GB.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// cacheEntries returns the top-level cache entries sorted by size, the largest first.
+func cacheEntries(cacheDir string) ([]cacheEntry, error) {
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]cacheEntry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		entry := cacheEntry{Name: d.Name()}
+		err = filepath.WalkDir(filepath.Join(cacheDir, d.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				entry.Size += info.Size()
+			}
+			if info.ModTime().After(entry.ModTime) {
+				entry.ModTime = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	return entries, nil
+}
+
+// purgeCache removes the cache entries that have not been modified for maxAge, it returns the number of removed entries and freed bytes.
+func purgeCache(cacheDir string, maxAge time.Duration, now time.Time) (int, int64, error) {
+	entries, err := cacheEntries(cacheDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, freed := 0, int64(0)
+	for _, entry := range entries {
+		if now.Sub(entry.ModTime) < maxAge {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(cacheDir, entry.Name)); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += entry.Size
+	}
+	return removed, freed, nil
+}
+
+// prepareCache purges the stale cache entries if CacheMaxAge is set and reports the cache directory size.
+func prepareCache(opts *QodanaOptions) {
+	if _, err := os.Stat(opts.CacheDir); err != nil {
+		return
+	}
+	if opts.CacheMaxAge > 0 {
+		removed, freed, err := purgeCache(opts.CacheDir, time.Duration(opts.CacheMaxAge)*24*time.Hour, time.Now())
+		if err != nil {
+			log.Errorf("Could not purge the stale Qodana cache: %s", err)
+		} else if removed > 0 {
+			SuccessMessage("Removed %d cache entries older than %d days from %s (%s)", removed, opts.CacheMaxAge, opts.CacheDir, formatBytes(freed))
+		}
+	}
+	entries, err := cacheEntries(opts.CacheDir)
+	if err != nil {
+		log.Warnf("Could not compute the Qodana cache size: %s", err)
+		return
+	}
+	total := int64(0)
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	log.Infof("Qodana cache %s takes %s", opts.CacheDir, formatBytes(total))
+	if opts.CacheReport {
+		printCacheReport(opts.CacheDir, entries, total)
+	}
+}
+
+// printCacheReport prints the cache entries with their sizes and last modification dates.
+func printCacheReport(cacheDir string, entries []cacheEntry, total int64) {
+	cacheTableData := pterm.TableData{
+		[]string{
+			PrimaryBold("Entry"),
+			PrimaryBold("Size"),
+			PrimaryBold("Last modified"),
+		},
+	}
+	for _, entry := range entries {
+		cacheTableData = append(cacheTableData, []string{
+			entry.Name,
+			formatBytes(entry.Size),
+			entry.ModTime.Format("2006-01-02 15:04"),
+		})
+	}
+
+	if err := renderTable(cacheTableData); err != nil {
+		return
+	}
+	SuccessMessage("Qodana cache %s takes %s", cacheDir, PrimaryBold(formatBytes(total)))
+	EmptyMessage()
+}
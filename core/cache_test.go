@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatBytes(t *testing.T) {
+	for _, tc := range []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	} {
+		assert.Equal(t, tc.expected, formatBytes(tc.size))
+	}
+}
+
+func Test_purgeCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"idea":    time.Hour,
+		"plugins": 40 * 24 * time.Hour,
+	} {
+		file := filepath.Join(cacheDir, name, "file.bin")
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, make([]byte, 100), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{file, filepath.Dir(file)} {
+			if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	entries, err := cacheEntries(cacheDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	removed, freed, err := purgeCache(cacheDir, 30*24*time.Hour, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, int64(100), freed)
+	assert.DirExists(t, filepath.Join(cacheDir, "idea"))
+	assert.NoDirExists(t, filepath.Join(cacheDir, "plugins"))
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheKeyAuto is the --cache-key value that derives the key from the project content.
+const CacheKeyAuto = "auto"
+
+// cacheKeyLength is the length of the key computed from the project content.
+const cacheKeyLength = 16
+
+// cacheKeyPattern is the allowed format of the explicit --cache-key values, the key is used as a directory name.
+var cacheKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateCacheKey checks the --cache-key value.
+func validateCacheKey(key string) error {
+	if key != CacheKeyAuto && !cacheKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid --cache-key %q, expected %s or letters, digits, '.', '_' and '-'", key, CacheKeyAuto)
+	}
+	return nil
+}
+
+// projectContentHash returns the hash of the project files and their relative paths. The .git directory,
+// the paths matched by the excludes and the skipped directories (e.g. the results inside the project) are not hashed.
+func projectContentHash(projectDir string, excludes []string, skip ...string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(projectDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		excluded := d.IsDir() && (d.Name() == ".git" || Contains(skip, p))
+		for _, pattern := range excludes {
+			excluded = excluded || matchesExclude(pattern, rel)
+		}
+		if excluded {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			_, err = fmt.Fprintf(hash, "d %s\x00", rel)
+		case d.Type()&fs.ModeSymlink != 0:
+			target, linkErr := os.Readlink(p)
+			if linkErr != nil {
+				return linkErr
+			}
+			_, err = fmt.Fprintf(hash, "l %s\x00%s\x00", rel, target)
+		case d.Type().IsRegular():
+			_, err = fmt.Fprintf(hash, "f %s\x00", rel)
+			if err == nil {
+				err = hashFile(hash, p)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the project %s: %w", projectDir, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:cacheKeyLength], nil
+}
+
+// hashFile writes the file content to the hash.
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// resolveCacheKey namespaces the cache directory with the --cache-key: the explicit value or the project content hash.
+func (o *QodanaOptions) resolveCacheKey() error {
+	if o.CacheKey == "" || o.cacheKey != "" {
+		return nil
+	}
+	if err := validateCacheKey(o.CacheKey); err != nil {
+		return configErrorf("%s", err)
+	}
+	key := o.CacheKey
+	if key == CacheKeyAuto {
+		start := time.Now()
+		skip := make([]string, 0)
+		for _, dir := range []string{o.ResultsDir, o.CacheDir, o.ReportDir} {
+			if abs, err := filepath.Abs(dir); err == nil {
+				skip = append(skip, abs)
+			}
+		}
+		projectDir, err := filepath.Abs(o.ProjectDir)
+		if err == nil {
+			key, err = projectContentHash(projectDir, o.Excludes, skip...)
+		}
+		if err != nil {
+			return configErrorf("Could not compute the cache key: %s", err)
+		}
+		log.Debugf("Computed the cache key %s in %s", key, time.Since(start).Round(time.Millisecond))
+	}
+	o.CacheDir = filepath.Join(o.CacheDir, key)
+	o.cacheKey = key
+	log.Infof("Using the cache %s", o.CacheDir)
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeProjectFiles writes the files given by slash-separated relative paths to dir.
+func writeProjectFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProjectContentHash(t *testing.T) {
+	files := map[string]string{
+		"main.go":           "package main\n",
+		"pkg/util.go":       "package pkg\n",
+		"node_modules/a.js": "module.exports = {}\n",
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writeProjectFiles(t, first, files)
+	writeProjectFiles(t, second, files)
+	excludes := []string{"node_modules"}
+
+	key, err := projectContentHash(first, excludes)
+	assert.NoError(t, err)
+	assert.Len(t, key, cacheKeyLength)
+	same, err := projectContentHash(second, excludes)
+	assert.NoError(t, err)
+	assert.Equal(t, key, same, "the same content in another directory")
+
+	writeProjectFiles(t, second, map[string]string{"node_modules/b.js": "", ".git/HEAD": "ref: refs/heads/main\n", "results/qodana.sarif.json": "{}"})
+	same, err = projectContentHash(second, excludes, filepath.Join(second, "results"))
+	assert.NoError(t, err)
+	assert.Equal(t, key, same, "excluded and skipped paths are not hashed")
+
+	writeProjectFiles(t, second, map[string]string{"pkg/util.go": "package pkg\n\nfunc Util() {}\n"})
+	changed, err := projectContentHash(second, excludes)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, changed)
+
+	assert.NoError(t, os.Rename(filepath.Join(first, "pkg"), filepath.Join(first, "internal")))
+	moved, err := projectContentHash(first, excludes)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, moved)
+}
+
+func TestResolveCacheKey(t *testing.T) {
+	assert.NoError(t, validateCacheKey(CacheKeyAuto))
+	assert.NoError(t, validateCacheKey("deps-1a2b3c.v2"))
+	assert.Error(t, validateCacheKey("../other"))
+	assert.Error(t, validateCacheKey(".."))
+
+	systemDir := t.TempDir()
+	cacheDir := filepath.Join(systemDir, "linter-id", "cache")
+	options := &QodanaOptions{CacheDir: cacheDir, CacheKey: "deps-1a2b3c"}
+	assert.NoError(t, options.resolveCacheKey())
+	assert.NoError(t, options.resolveCacheKey())
+	assert.Equal(t, filepath.Join(cacheDir, "deps-1a2b3c"), options.CacheDir)
+	assert.Equal(t, systemDir, options.getQodanaSystemDir())
+
+	projectDir := t.TempDir()
+	writeProjectFiles(t, projectDir, map[string]string{"main.go": "package main\n"})
+	key, err := projectContentHash(projectDir, nil)
+	assert.NoError(t, err)
+	options = &QodanaOptions{ProjectDir: projectDir, CacheDir: cacheDir, CacheKey: CacheKeyAuto}
+	assert.NoError(t, options.resolveCacheKey())
+	assert.Equal(t, filepath.Join(cacheDir, key), options.CacheDir)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+)
+
+// cleanTarget is a linter cache directory or a pulled linter image that can be removed to reclaim disk space.
+type cleanTarget struct {
+	// Name is the cache directory path or the image references.
+	Name string
+	Size int64
+	// refs are the Qodana references of the image, empty for a cache directory.
+	refs []string
+}
+
+// cacheTargets returns the cache directories of the linters in the Qodana system directory.
+func cacheTargets(systemDir string) ([]cleanTarget, error) {
+	linterDirs, err := os.ReadDir(systemDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	targets := make([]cleanTarget, 0)
+	for _, d := range linterDirs {
+		cacheDir := filepath.Join(systemDir, d.Name(), "cache")
+		if info, err := os.Stat(cacheDir); !d.IsDir() || err != nil || !info.IsDir() {
+			continue
+		}
+		entries, err := cacheEntries(cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		target := cleanTarget{Name: cacheDir}
+		for _, entry := range entries {
+			target.Size += entry.Size
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// isQodanaImage checks if the image reference is a Qodana linter image, possibly pulled from a registry mirror.
+func isQodanaImage(ref string) bool {
+	if hasRegistry(ref) {
+		_, ref, _ = strings.Cut(ref, "/")
+	}
+	return strings.HasPrefix(ref, officialImagePrefix)
+}
+
+// imageTargets returns the pulled Qodana linter images.
+func imageTargets(ctx context.Context, client *client.Client) ([]cleanTarget, error) {
+	images, err := client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]cleanTarget, 0)
+	for _, image := range images {
+		refs := make([]string, 0)
+		for _, ref := range image.RepoTags {
+			if isQodanaImage(ref) {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) > 0 {
+			targets = append(targets, cleanTarget{Name: strings.Join(refs, ", "), Size: image.Size, refs: refs})
+		}
+	}
+	return targets, nil
+}
+
+// Clean reports the linter caches and, if images is set, the pulled linter images, and removes them if yes is set.
+func Clean(images bool, yes bool) {
+	systemDir := (&QodanaOptions{}).getQodanaSystemDir()
+	targets, err := cacheTargets(systemDir)
+	if err != nil {
+		ErrorMessage("Could not read the Qodana caches in %s: %s", systemDir, err)
+		Exit(1)
+	}
+	var containerClient *client.Client
+	if images {
+		PrepareContainerEnvSettings()
+		containerClient = getContainerClient()
+		pulled, err := imageTargets(context.Background(), containerClient)
+		if err != nil {
+			ErrorMessage("Could not list the images: %s", err)
+			Exit(QodanaDockerErrorExitCode)
+		}
+		targets = append(targets, pulled...)
+	}
+	if len(targets) == 0 {
+		SuccessMessage("Nothing to clean")
+		return
+	}
+	total := printCleanTargets(targets)
+	if !yes {
+		WarningMessage("Nothing is removed, run again with --yes to remove %s", formatBytes(total))
+		return
+	}
+	if failed := removeCleanTargets(context.Background(), containerClient, targets); failed > 0 {
+		ErrorMessage("Could not remove %d of %d items", failed, len(targets))
+		Exit(1)
+	}
+	SuccessMessage("Removed %d items, %s reclaimed", len(targets), formatBytes(total))
+}
+
+// printCleanTargets prints the items to remove with their sizes and returns the total size.
+func printCleanTargets(targets []cleanTarget) int64 {
+	tableData := pterm.TableData{
+		[]string{
+			PrimaryBold("Item"),
+			PrimaryBold("Size"),
+		},
+	}
+	total := int64(0)
+	for _, target := range targets {
+		tableData = append(tableData, []string{target.Name, formatBytes(target.Size)})
+		total += target.Size
+	}
+	if err := renderTable(tableData); err != nil {
+		log.Warnf("Could not print the items to remove: %s", err)
+	}
+	return total
+}
+
+// removeCleanTargets removes the cache directories and untags the images, it returns the number of failed items.
+// An image that also has non-Qodana tags is kept under those tags.
+func removeCleanTargets(ctx context.Context, client *client.Client, targets []cleanTarget) int {
+	failed := 0
+	for _, target := range targets {
+		var err error
+		if len(target.refs) == 0 {
+			err = os.RemoveAll(target.Name)
+		}
+		for _, ref := range target.refs {
+			if _, err = client.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Errorf("Could not remove %s: %s", target.Name, err)
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheTargets(t *testing.T) {
+	systemDir := t.TempDir()
+	for path, content := range map[string]string{
+		"jvm-123/cache/idea/index":  "12345",
+		"jvm-123/cache/.m2/pom.xml": "123",
+		"jvm-123/results/log.txt":   "1234567890",
+		"py-456/results/log.txt":    "1",
+		"last-update-check":         "2023-12-01T00:00:00Z",
+	} {
+		path = filepath.Join(systemDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets, err := cacheTargets(systemDir)
+	assert.NoError(t, err)
+	cacheDir := filepath.Join(systemDir, "jvm-123", "cache")
+	assert.Equal(t, []cleanTarget{{Name: cacheDir, Size: 8}}, targets)
+
+	assert.Equal(t, 0, removeCleanTargets(context.Background(), nil, targets))
+	assert.NoDirExists(t, cacheDir)
+	assert.FileExists(t, filepath.Join(systemDir, "jvm-123", "results", "log.txt"))
+
+	targets, err = cacheTargets(filepath.Join(systemDir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, targets)
+}
+
+func TestIsQodanaImage(t *testing.T) {
+	for ref, expected := range map[string]bool{
+		"jetbrains/qodana-jvm:2023.3":                      true,
+		"registry.example.com/jetbrains/qodana-py:2023.3":  true,
+		"localhost:5000/jetbrains/qodana-go:latest":        true,
+		"jetbrains/teamcity-agent:latest":                  false,
+		"registry.example.com/qodana/jetbrains-jvm:2023.3": false,
+		"ubuntu:22.04": false,
+	} {
+		assert.Equal(t, expected, isQodanaImage(ref), ref)
+	}
+}
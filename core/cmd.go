@@ -35,7 +35,7 @@ func RunCmd(cwd string, args ...string) int {
 // RunCmdWithTimeout executes subprocess with forwarding of signals, and returns its exit code.
 // If timeout occurs, subprocess is terminated, timeoutExitCode is returned
 func RunCmdWithTimeout(cwd string, timeout time.Duration, timeoutExitCode int, args ...string) int {
-	log.Debugf("Running command: %v", args)
+	log.Debugf("Running command: %v", maskSecrets(args))
 	cmd := exec.Command(args[0], args[1:]...)
 	if //goland:noinspection GoBoolExpressions
 	runtime.GOOS == "windows" {
@@ -53,6 +53,12 @@ func RunCmdWithTimeout(cwd string, timeout time.Duration, timeoutExitCode int, a
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if jsonOutput {
+		stdout, stderr := &linterLogWriter{stream: "stdout"}, &linterLogWriter{stream: "stderr"}
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		defer stdout.Flush()
+		defer stderr.Flush()
+	}
 	if err := cmd.Start(); err != nil {
 		log.Fatal(err)
 	}
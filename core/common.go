@@ -21,8 +21,10 @@ import (
 	"github.com/JetBrains/qodana-cli/v2023/cloud"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/owenrumney/go-sarif/v2/sarif"
 	"github.com/pterm/pterm"
 
 	log "github.com/sirupsen/logrus"
@@ -82,24 +84,12 @@ func Image(code string) string {
 func GetAnalyzer(path string, yamlName string) string {
 	var analyzers []string
 	printProcess(func(_ *pterm.SpinnerPrinter) {
-		languages := readIdeaDir(path)
-		if len(languages) == 0 {
-			languages, _ = recognizeDirLanguages(path)
-		}
+		languages := detectLanguages(path)
 		if len(languages) == 0 {
 			WarningMessage("No technologies detected (no source code files?)\n")
 		} else {
 			WarningMessage("Detected technologies: " + strings.Join(languages, ", ") + "\n")
-			for _, language := range languages {
-				if i, err := langsProductCodes[language]; err {
-					for _, l := range i {
-						analyzers = Append(analyzers, l)
-					}
-				}
-			}
-			if len(analyzers) == 0 {
-				analyzers = AllCodes
-			}
+			analyzers = rankAnalyzers(languages)
 		}
 	}, "Scanning project", "")
 
@@ -117,13 +107,98 @@ func GetAnalyzer(path string, yamlName string) string {
 	if analyzer == "" {
 		ErrorMessage("Could not configure project as it is not supported by Qodana")
 		WarningMessage("See https://www.jetbrains.com/help/qodana/supported-technologies.html for more details")
-		os.Exit(1)
+		Exit(1)
+	}
+	SetQodanaLinter(path, analyzer, yamlName)
+	SuccessMessage("Added %s", analyzer)
+	return analyzer
+}
+
+// SetAnalyzer writes the explicitly chosen linter (image, alias or native code) to qodana.yaml without prompting,
+// it only warns if the linter doesn't support the detected languages.
+func SetAnalyzer(path string, analyzer string, yamlName string) string {
+	opts := &QodanaOptions{}
+	if IsNativeAnalyzer(strings.TrimSuffix(analyzer, EapSuffix)) {
+		opts.Ide = analyzer
+	} else {
+		linter, err := resolveLinter(analyzer)
+		if err != nil {
+			ErrorMessage("%s", err)
+			Exit(QodanaConfigErrorExitCode)
+		}
+		opts.Linter = linter
+		analyzer = linter
+	}
+	languages := detectLanguages(path)
+	if code := opts.guessProduct(); code != "" && len(languages) > 0 && !Contains(languagesToAnalyzers(languages), code) {
+		WarningMessage("%s does not support the detected technologies (%s), using it as requested\n", analyzer, strings.Join(languages, ", "))
 	}
 	SetQodanaLinter(path, analyzer, yamlName)
 	SuccessMessage("Added %s", analyzer)
 	return analyzer
 }
 
+// detectLanguages returns the languages used in the given project: from .idea first, then from the sources.
+func detectLanguages(path string) []string {
+	languages := readIdeaDir(path)
+	if len(languages) == 0 {
+		languages, _ = recognizeDirLanguages(path)
+	}
+	return languages
+}
+
+// languagesToAnalyzers returns the product codes suitable for the given languages, all codes if none matches.
+func languagesToAnalyzers(languages []string) []string {
+	var analyzers []string
+	for _, language := range languages {
+		if i, ok := langsProductCodes[language]; ok {
+			for _, l := range i {
+				analyzers = Append(analyzers, l)
+			}
+		}
+	}
+	if len(analyzers) == 0 {
+		analyzers = AllCodes
+	}
+	return analyzers
+}
+
+// rankAnalyzers returns the product codes suitable for the given languages, the ones supporting more languages first.
+// On ties the order of languagesToAnalyzers is kept: the languages used more often first, community linters first.
+func rankAnalyzers(languages []string) []string {
+	analyzers := languagesToAnalyzers(languages)
+	coverage := make(map[string]int)
+	for _, language := range languages {
+		for _, code := range langsProductCodes[language] {
+			coverage[code]++
+		}
+	}
+	sort.SliceStable(analyzers, func(i, j int) bool {
+		return coverage[analyzers[i]] > coverage[analyzers[j]]
+	})
+	return analyzers
+}
+
+// GetLinterForProject returns the product code of the linter recommended for the project in dir (e.g. QDPYC),
+// for mixed-language projects it's the linter supporting most of the detected languages.
+func GetLinterForProject(dir string) (string, error) {
+	languages := detectLanguages(dir)
+	if len(languages) == 0 {
+		return "", fmt.Errorf("no source code files detected in %s", dir)
+	}
+	supported := false
+	for _, language := range languages {
+		if _, ok := langsProductCodes[language]; ok {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return "", fmt.Errorf("the detected technologies (%s) are not supported by Qodana", strings.Join(languages, ", "))
+	}
+	return rankAnalyzers(languages)[0], nil
+}
+
 func SelectAnalyzer(path string, analyzers []string, interactive bool, selectFunc func([]string) string) string {
 	var analyzer string
 	if len(analyzers) == 0 && !interactive {
@@ -165,21 +240,32 @@ func analyzerToSelect(analyzers []string, path string) (map[string]string, []str
 	return analyzersMap, analyzersList
 }
 
-// ShowReport serves the Qodana report
-func ShowReport(resultsDir string, reportPath string, port int) {
+// ShowReport serves the Qodana report and opens it as configured by openIn, see openUrl.
+func ShowReport(resultsDir string, reportPath string, port int, openIn string, wait bool) {
 	cloudUrl := cloud.GetReportUrl(resultsDir)
 	if cloudUrl != "" {
-		openReport(cloudUrl, reportPath, port)
+		openWhenReady(cloudUrl, openIn, wait)
 	} else {
+		if _, err := os.Stat(reportPath); os.IsNotExist(err) {
+			log.Fatal("Qodana report not found. Get a report by running `qodana scan`")
+		}
+		if _, err := os.Stat(filepath.Join(resultsDir, QodanaSarifName)); err == nil {
+			RequireCompleteSarif(filepath.Join(resultsDir, QodanaSarifName))
+		}
+		if s, err := sarif.Open(filepath.Join(resultsDir, QodanaSarifName)); err == nil {
+			checkSarifVersion(filepath.Join(resultsDir, QodanaSarifName), s)
+		}
+		listener, err := listenReport(port)
+		if err != nil {
+			ErrorMessage("Could not serve the report: %s", err)
+			Exit(1)
+		}
 		WarningMessage("Press Ctrl+C to stop serving the report\n")
 		printProcess(
 			func(_ *pterm.SpinnerPrinter) {
-				if _, err := os.Stat(reportPath); os.IsNotExist(err) {
-					log.Fatal("Qodana report not found. Get a report by running `qodana scan`")
-				}
-				openReport("", reportPath, port)
+				serveReport(listener, reportPath, openIn, wait)
 			},
-			fmt.Sprintf("Showing Qodana report from %s", fmt.Sprintf("http://localhost:%d/", port)),
+			fmt.Sprintf("Showing Qodana report from %s/", reportUrl(listener)),
 			"",
 		)
 	}
@@ -105,3 +105,78 @@ func TestSelectAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLinterForProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "Python",
+			files:    map[string]string{"main.py": "print('hello')\n"},
+			expected: QDPYC,
+		},
+		{
+			name: "Go",
+			files: map[string]string{
+				"main.go": "package main\n\nfunc main() {}\n",
+			},
+			expected: QDGO,
+		},
+		{
+			name: "Java and Kotlin",
+			files: map[string]string{
+				"src/A.java": "public class A {}\n",
+				"src/B.java": "public class B {}\n",
+				"src/C.kt":   "class C\n",
+			},
+			expected: QDJVMC,
+		},
+		{
+			name: "C# and F# prefer the linter supporting both",
+			files: map[string]string{
+				"src/A.cs": "namespace A { public class A {} }\n",
+				"src/B.cs": "namespace B { public class B {} }\n",
+				"src/C.fs": "module C\nlet c = 1\n",
+			},
+			expected: QDNET,
+		},
+		{
+			name: "Python with some JavaScript",
+			files: map[string]string{
+				"a.py":  "print('a')\n",
+				"b.py":  "print('b')\n",
+				"ui.js": "console.log('ui');\n",
+			},
+			expected: QDPYC,
+		},
+		{
+			name:  "No sources",
+			files: map[string]string{"README.md": "# Readme\n"},
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			linter, err := GetLinterForProject(dir)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, linter)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// minLinterRelease is the oldest linter release supported by this CLI version.
+var minLinterRelease = linterRelease{2023, 2}
+
+// minConfigRelease is the oldest linter release that reads the configuration passed with --config.
+var minConfigRelease = linterRelease{2023, 3}
+
+// minScriptRelease is the oldest linter release that runs the scripts passed with --before-script and --after-script.
+var minScriptRelease = linterRelease{2023, 3}
+
+// linterReleasePattern matches the release part of a linter tag or a CLI version, e.g. 2023.3 in 2023.3-eap.
+var linterReleasePattern = regexp.MustCompile(`^v?(\d{4})\.(\d+)`)
+
+// linterRelease is a linter release: the year and the release number within the year.
+type linterRelease struct {
+	year  int
+	minor int
+}
+
+func (r linterRelease) String() string {
+	return fmt.Sprintf("%d.%d", r.year, r.minor)
+}
+
+func (r linterRelease) before(other linterRelease) bool {
+	return r.year < other.year || (r.year == other.year && r.minor < other.minor)
+}
+
+// parseLinterRelease parses the release of a tag like 2023.3, 2023.3-eap or 2023.3.1.
+func parseLinterRelease(s string) (linterRelease, bool) {
+	match := linterReleasePattern.FindStringSubmatch(s)
+	if match == nil {
+		return linterRelease{}, false
+	}
+	year, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return linterRelease{year, minor}, true
+}
+
+// imageTag returns the tag of the image, empty if the image has no tag or is pinned by a digest.
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	_, tag, _ := strings.Cut(image[strings.LastIndex(image, "/")+1:], ":")
+	return tag
+}
+
+// maxLinterRelease returns the release of this CLI version, development builds support the default linter release.
+func maxLinterRelease() linterRelease {
+	if release, ok := parseLinterRelease(Version); ok {
+		return release
+	}
+	release, _ := parseLinterRelease(version)
+	return release
+}
+
+// linterCompatibilityProblem describes why the image release is outside the supported range, tags without a release
+// (e.g. latest) are not checked.
+func linterCompatibilityProblem(image string, min linterRelease, max linterRelease) string {
+	release, ok := parseLinterRelease(imageTag(image))
+	if !ok {
+		return ""
+	}
+	if release.before(min) || max.before(release) {
+		return fmt.Sprintf("linter %s is not supported by this CLI version %s, supported linter releases are %s–%s", image, Version, min, max)
+	}
+	return ""
+}
+
+// releaseAtLeast reports whether the linter is of the given release or newer: the image tag is checked for container
+// runs (tags without a release, e.g. latest, are assumed recent) and the IDE version for native runs.
+func (o *QodanaOptions) releaseAtLeast(min linterRelease) bool {
+	version := Prod.Version
+	if o.Linter != "" {
+		version = imageTag(o.Linter)
+		if _, ok := parseLinterRelease(version); !ok {
+			return true
+		}
+	}
+	release, ok := parseLinterRelease(version)
+	return ok && !release.before(min)
+}
+
+// checkConfigSupported stops the run if the explicit or generated configuration can't be passed to the linter,
+// as it would silently analyze the project with qodana.yaml from the project instead.
+func (o *QodanaOptions) checkConfigSupported() error {
+	if o.ConfigPath == "" || o.releaseAtLeast(minConfigRelease) {
+		return nil
+	}
+	return configErrorf(
+		"%s requires linter %s or newer to pass the configuration (--config, --exclude, --files or qodana.yaml includes), update the linter",
+		o.analyzerName(),
+		minConfigRelease,
+	)
+}
+
+// checkLinterCompatibility warns if the release of the official linter image is not supported by this CLI version,
+// with --strict-compat the scan is not started.
+func (o *QodanaOptions) checkLinterCompatibility() error {
+	if o.Linter == "" || !isOfficialImage(o.Linter, o.Registry) {
+		return nil
+	}
+	problem := linterCompatibilityProblem(o.Linter, minLinterRelease, maxLinterRelease())
+	if problem == "" {
+		log.Debugf("Linter %s is compatible with the CLI version %s", o.Linter, Version)
+		return nil
+	}
+	if o.StrictCompat {
+		return configErrorf("%s", problem)
+	}
+	WarningMessage("%s: update the linter or the CLI, use --strict-compat to fail on this\n", problem)
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinterCompatibility(t *testing.T) {
+	min, max := linterRelease{2023, 2}, linterRelease{2023, 3}
+	for image, supported := range map[string]bool{
+		"jetbrains/qodana-jvm:2023.3":                             true,
+		"jetbrains/qodana-jvm:2023.2-eap":                         true,
+		"jetbrains/qodana-python:2023.3.1":                        true,
+		"registry.example.com:5000/jetbrains/qodana-js:2023.2":    true,
+		"jetbrains/qodana-jvm:latest":                             true,
+		"jetbrains/qodana-jvm":                                    true,
+		"jetbrains/qodana-jvm@sha256:0123456789abcdef":            true,
+		"registry.example.com:5000/jetbrains/qodana-js":           true,
+		"jetbrains/qodana-jvm:2021.3":                             false,
+		"jetbrains/qodana-jvm-community:2023.1-eap":               false,
+		"jetbrains/qodana-go:2024.1":                              false,
+		"registry.example.com:5000/jetbrains/qodana-php:2022.3.4": false,
+	} {
+		problem := linterCompatibilityProblem(image, min, max)
+		assert.Equal(t, supported, problem == "", image)
+	}
+
+	release, ok := parseLinterRelease("v2023.3.2")
+	assert.True(t, ok)
+	assert.Equal(t, "2023.3", release.String())
+	_, ok = parseLinterRelease("dev")
+	assert.False(t, ok)
+}
+
+func TestReleaseAtLeast(t *testing.T) {
+	version := Prod.Version
+	t.Cleanup(func() { Prod.Version = version })
+	Prod.Version = "2023.2"
+	for linter, expected := range map[string]bool{
+		"jetbrains/qodana-jvm:2023.3":     true,
+		"jetbrains/qodana-jvm:2024.1-eap": true,
+		"jetbrains/qodana-jvm:latest":     true,
+		"jetbrains/qodana-jvm":            true,
+		"jetbrains/qodana-jvm:2023.2":     false,
+		"":                                false,
+	} {
+		assert.Equal(t, expected, (&QodanaOptions{Linter: linter}).releaseAtLeast(minConfigRelease), linter)
+	}
+	Prod.Version = "2023.3"
+	assert.True(t, (&QodanaOptions{}).releaseAtLeast(minConfigRelease))
+}
+
+func TestConfigCmdOption(t *testing.T) {
+	version := Prod.Version
+	t.Cleanup(func() { Prod.Version = version })
+	Prod.Version = "2023.3"
+	configPath := filepath.Join(t.TempDir(), "qodana.yaml")
+	assert.Equal(
+		t,
+		[]string{"--config", containerConfigPath},
+		GetCmdOptions(&QodanaOptions{Linter: "jetbrains/qodana-jvm:2023.3", ConfigPath: configPath}),
+	)
+	assert.Empty(t, GetCmdOptions(&QodanaOptions{Linter: "jetbrains/qodana-jvm:2023.2", ConfigPath: configPath}))
+	assert.Equal(t, []string{"--config", configPath}, GetCmdOptions(&QodanaOptions{Ide: QDJVM, ConfigPath: configPath}))
+}
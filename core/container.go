@@ -24,11 +24,17 @@ import (
 	"fmt"
 	"github.com/pterm/pterm"
 	"io"
+	"io/fs"
+	"net"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	cliconfig "github.com/docker/cli/cli/config"
 
@@ -36,6 +42,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -44,8 +51,14 @@ const (
 	QodanaSuccessExitCode = 0
 	// QodanaFailThresholdExitCode same as QodanaSuccessExitCode, but the threshold is set and exceeded.
 	QodanaFailThresholdExitCode = 255
+	// QodanaConfigErrorExitCode reports invalid options or configuration, the analysis is not started.
+	QodanaConfigErrorExitCode = 2
+	// QodanaDockerErrorExitCode reports that the container engine is not available or the image can't be pulled.
+	QodanaDockerErrorExitCode = 3
 	// QodanaOutOfMemoryExitCode reports an interrupted process, sometimes because of an OOM.
 	QodanaOutOfMemoryExitCode = 137
+	// QodanaInterruptedExitCode reports that the run was interrupted with Ctrl-C or SIGTERM, the container is stopped.
+	QodanaInterruptedExitCode = 130
 	// QodanaEapLicenseExpiredExitCode reports an expired license.
 	QodanaEapLicenseExpiredExitCode = 7
 	// QodanaTimeoutExitCodePlaceholder is not a real exit code (it is not obtained from IDE process! and not returned from CLI)
@@ -54,58 +67,181 @@ const (
 	// officialImagePrefix is the prefix of official Qodana images.
 	officialImagePrefix      = "jetbrains/qodana"
 	dockerSpecialCharsLength = 8
+	// containerProfileDir is the directory the custom profile file is mounted to inside the container.
+	containerProfileDir = "/data/profile"
+	// containerConfigPath is where the explicit configuration file is mounted to, outside the project mount,
+	// it is passed to the linter with --config instead of qodana.yaml from the project.
+	containerConfigPath = "/data/config/qodana.yaml"
+	// containerBaselinePath is where the baseline downloaded from a URL is mounted to.
+	containerBaselinePath = "/data/baseline/qodana.sarif.json"
+	// UserAuto is the --user value that runs the container as the current host user.
+	UserAuto = "auto"
+	// DefaultPullRetries is the default number of retries for transient image pull failures.
+	DefaultPullRetries = 3
+	// PullPolicyAlways pulls the linter image before every run.
+	PullPolicyAlways = "always"
+	// PullPolicyMissing pulls the linter image only if it is not present locally.
+	PullPolicyMissing = "missing"
+	// PullPolicyNever never pulls the linter image, the image must be present locally.
+	PullPolicyNever = "never"
 )
 
 var (
+	// DefaultPullJobs is the default number of images pulled at a time: the CPU count, but at most 4,
+	// as the pulls are limited by the network and the disk rather than the CPU.
+	DefaultPullJobs = min(runtime.NumCPU(), 4)
+
 	containerLogsOptions = types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
 		Timestamps: false,
 	}
-	containerName = "qodana-cli"
+	// startedContainers are the names of the containers created by runContainer, ContainerCleanup removes them.
+	startedContainers   []string
+	startedContainersMu sync.Mutex
+	// containerCleanup makes ContainerCleanup run once, e.g. if Ctrl-C is pressed twice.
+	containerCleanup sync.Once
+	// pullRetryDelay is the delay before the first pull retry, it doubles with every next attempt.
+	pullRetryDelay = 2 * time.Second
 )
 
 // runQodanaContainer runs the analysis in a Docker container from a Qodana image.
-func runQodanaContainer(ctx context.Context, options *QodanaOptions) int {
-	resetScanStages()
-	docker := getContainerClient()
+func runQodanaContainer(ctx context.Context, options *QodanaOptions) (int, error) {
+	var readOnlyWriteFailed atomic.Bool
+	docker, err := newContainerClient()
+	if err != nil {
+		return 0, err
+	}
 
 	fixDarwinCaches(options)
 
-	for i, stage := range scanStages {
-		scanStages[i] = PrimaryBold("[%d/%d] ", i+1, len(scanStages)+1) + primary(stage)
+	var eta string
+	if duration := lastScanDuration(options); duration > 0 {
+		eta = miscStyle.Sprintf(" (the previous analysis took %s)", duration)
+	}
+	options.scanStages = make([]string, len(scanStageNames))
+	for i, stage := range scanStageNames {
+		options.scanStages[i] = PrimaryBold("[%d/%d] ", i+1, len(scanStageNames)+1) + primary(stage) + eta
 	}
 
-	if !strings.HasPrefix(options.Linter, officialImagePrefix) {
+	if !isOfficialImage(options.Linter, options.Registry) {
 		WarningMessage("You are using an unofficial Qodana linter: %s\n", options.Linter)
 	}
-	if !(options.SkipPull) {
-		PullImage(docker, options.Linter)
+	pullPolicy := options.PullPolicy
+	if options.SkipPull {
+		pullPolicy = PullPolicyNever
+	}
+	if err := prepareImage(options, docker, options.Linter, pullPolicy, options.PullRetries); err != nil {
+		return 0, err
 	}
-	progress, _ := startQodanaSpinner(scanStages[0])
+	var progress *pterm.SpinnerPrinter
+	if options.parallelName == "" {
+		progress, _ = startQodanaSpinner(options.scanStages[0])
+	}
+	options.emitEvent(scanProgressEvent(0))
 
 	dockerConfig := getDockerOptions(options)
 	log.Debugf("docker command to run: %s", generateDebugDockerRunCommand(dockerConfig))
 
-	updateText(progress, scanStages[1])
+	options.updateScanStage(progress, 1)
 
 	runContainer(ctx, docker, dockerConfig)
-	go followLinter(docker, dockerConfig.Name, progress)
+	go followLinter(options, docker, dockerConfig.Name, progress, &readOnlyWriteFailed)
 
-	exitCode := getContainerExitCode(ctx, docker, dockerConfig.Name)
+	exitCode := getContainerExitCode(ctx, docker, dockerConfig.Name, options.GetAnalysisTimeout())
 
 	fixDarwinCaches(options)
+	if !options.NoChown {
+		normalizeResultsPermissions(options)
+	}
 
 	if progress != nil {
 		_ = progress.Stop()
 	}
-	return int(exitCode)
+	if exitCode == QodanaOutOfMemoryExitCode {
+		ErrorMessage(outOfMemoryMessage(options.Memory))
+	}
+	if options.MountCacheAsReadonly && readOnlyWriteFailed.Load() {
+		ErrorMessage(
+			"The linter could not write to the cache %s mounted as read-only, run without --mount-cache-as-readonly to update it",
+			options.CacheDir,
+		)
+	}
+	return int(exitCode), nil
+}
+
+// validateRegistry checks the --registry value: a registry host with an optional port and path, without a scheme.
+func validateRegistry(registry string) error {
+	if strings.Contains(registry, "://") || strings.ContainsAny(registry, " \t") || strings.Trim(registry, "/") == "" {
+		return fmt.Errorf("invalid registry %q, expected host[:port][/path], e.g. registry.example.com/mirror", registry)
+	}
+	return nil
+}
+
+// hasRegistry checks if the image name is already qualified with a registry host, e.g. registry.example.com/jetbrains/qodana-jvm.
+func hasRegistry(image string) bool {
+	host, _, found := strings.Cut(image, "/")
+	return found && (strings.ContainsAny(host, ".:") || host == "localhost")
+}
+
+// imageWithRegistry prefixes the image with the registry keeping the tag, images already qualified with a registry
+// are returned unchanged.
+func imageWithRegistry(image string, registry string) string {
+	registry = strings.Trim(registry, "/")
+	if registry == "" || hasRegistry(image) {
+		return image
+	}
+	return registry + "/" + image
+}
+
+// ImagesWithRegistry returns the images prefixed with the registry, see imageWithRegistry.
+func ImagesWithRegistry(images []string, registry string) ([]string, error) {
+	if err := validateRegistry(registry); err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(images))
+	for _, image := range images {
+		result = append(result, imageWithRegistry(image, registry))
+	}
+	return result, nil
+}
+
+// isOfficialImage checks if the image is an official Qodana image, possibly mirrored to the registry.
+func isOfficialImage(image string, registry string) bool {
+	if registry = strings.Trim(registry, "/"); registry != "" {
+		image = strings.TrimPrefix(image, registry+"/")
+	}
+	return strings.HasPrefix(image, officialImagePrefix)
+}
+
+// isReadOnlyWriteError reports whether the linter output line is a failed write to a read-only file system.
+func isReadOnlyWriteError(line string) bool {
+	return strings.Contains(line, "Read-only file system")
+}
+
+// normalizeResultsPermissions gives the results written by the container back to the invoking user,
+// as the container may run as root.
+func normalizeResultsPermissions(options *QodanaOptions) {
+	dirs := []string{options.ResultsDir}
+	if rel, err := filepath.Rel(options.ResultsDir, options.ReportDir); err != nil || strings.HasPrefix(rel, "..") {
+		dirs = append(dirs, options.ReportDir)
+	}
+	uid, gid := hostUser()
+	for _, dir := range dirs {
+		adjusted, err := normalizePermissions(dir, uid, gid)
+		if adjusted > 0 {
+			log.Infof("Adjusted ownership and permissions of %s in %s", pluralize(adjusted, "file", "files"), dir)
+		}
+		if err != nil {
+			WarningMessage("Could not give the results in %s back to the current user, run with --user %s or fix the permissions manually: %s\n", dir, UserAuto, err)
+		}
+	}
 }
 
 func fixDarwinCaches(options *QodanaOptions) {
 	if //goland:noinspection GoBoolExpressions
-	runtime.GOOS == "darwin" {
+	runtime.GOOS == "darwin" && !options.MountCacheAsReadonly {
 		err := removePortSocket(options.CacheDir)
 		if err != nil {
 			log.Warnf("Could not remove .port from %s: %s", options.CacheDir, err)
@@ -148,52 +284,222 @@ func checkRequiredToolInstalled(tool string) bool {
 	return err == nil
 }
 
-// PrepareContainerEnvSettings checks if the host is ready to run Qodana container images.
+// PrepareContainerEnvSettings checks if the host is ready to run Qodana container images and exits if it is not.
 func PrepareContainerEnvSettings() {
-	var tool string
-	if os.Getenv(qodanaCliUsePodman) == "" && checkRequiredToolInstalled("docker") {
-		tool = "docker"
-	} else if checkRequiredToolInstalled("podman") {
-		tool = "podman"
-	} else {
-		ErrorMessage(
-			"Docker (or podman) is not installed on the system or can't be found in PATH, refer to https://www.docker.com/get-started for installing it",
-		)
-		os.Exit(1)
+	if err := CheckContainerEngine(); err != nil {
+		exitWithError(err)
+	}
+}
+
+// CheckContainerEngine checks if the host is ready to run Qodana container images: ErrDockerNotFound is returned
+// if there is no container engine, an error matching ErrContainerEngine if it can't run containers.
+func CheckContainerEngine() error {
+	tool, err := findContainerEngine()
+	if err != nil {
+		return fmt.Errorf("%w, refer to https://www.docker.com/get-started for installing it", err)
+	}
+	if tool == "docker" {
+		docker, err := newContainerClient()
+		if err != nil {
+			return err
+		}
+		if err = pingContainerEngine(docker); err != nil {
+			return containerEngineNotRunning(tool, err)
+		}
 	}
 	cmd := exec.Command(tool, "ps")
+	log.Debugf("Checking the container engine: %v", cmd.Args)
 	if err := cmd.Run(); err != nil {
 		var exiterr *exec.ExitError
 		if errors.As(err, &exiterr) {
 			if strings.Contains(string(exiterr.Stderr), "permission denied") {
-				ErrorMessage(
-					"Qodana container can't be run by the current user. Please fix the container engine configuration.",
-				)
-				WarningMessage("https://docs.docker.com/engine/install/linux-postinstall/#manage-docker-as-a-non-root-user")
-				os.Exit(1)
-			} else {
-				ErrorMessage(
-					"'%s ps' exited with exit code %d, perhaps docker daemon is not running?",
-					tool,
-					exiterr.ExitCode(),
-				)
+				return containerEnginePermissionDenied()
+			}
+			return containerEngineError{
+				fmt.Errorf("'%s ps' exited with exit code %d, perhaps docker daemon is not running?", tool, exiterr.ExitCode()),
+				containerEngineHint(tool, runtime.GOOS),
 			}
-			os.Exit(1)
 		}
-		log.Fatal(err)
+		return containerEngineError{fmt.Errorf("Could not run '%s ps': %s", tool, err), ""}
+	}
+	return CheckContainerEngineMemory()
+}
+
+// findContainerEngine returns the container engine tool to use: docker, or podman if docker is not installed or
+// QODANA_CLI_USE_PODMAN is set.
+func findContainerEngine() (string, error) {
+	if os.Getenv(qodanaCliUsePodman) == "" && checkRequiredToolInstalled("docker") {
+		return "docker", nil
 	}
-	CheckContainerEngineMemory()
+	if checkRequiredToolInstalled("podman") {
+		return "podman", nil
+	}
+	return "", ErrDockerNotFound
+}
+
+// pingContainerEngine checks that the container engine daemon is running and responds.
+func pingContainerEngine(docker *client.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := docker.Ping(ctx)
+	return err
 }
 
-// PullImage pulls docker image and prints the process.
-func PullImage(client *client.Client, image string) {
+// containerEngineHint returns the platform-specific hint on how to start the container engine.
+func containerEngineHint(tool string, goos string) string {
+	if tool == "podman" {
+		if goos == "linux" {
+			return "Start the Podman service with 'systemctl --user start podman.socket' and try again"
+		}
+		return "Start the Podman machine with 'podman machine start' and try again"
+	}
+	switch goos {
+	case "windows", "darwin":
+		return "Start Docker Desktop, wait until it reports that the engine is running and try again"
+	default:
+		return "Start the Docker daemon with 'sudo systemctl start docker' and try again"
+	}
+}
+
+// containerEngineNotRunning returns the actionable error for the unavailable container engine.
+// A permission error means the daemon is running, but the current user can't access its socket.
+func containerEngineNotRunning(tool string, err error) error {
+	if errors.Is(err, os.ErrPermission) || strings.Contains(err.Error(), "permission denied") {
+		return containerEnginePermissionDenied()
+	}
+	return containerEngineError{
+		fmt.Errorf("Could not connect to the %s daemon, is it running? %s", tool, err),
+		containerEngineHint(tool, runtime.GOOS),
+	}
+}
+
+// containerEnginePermissionDenied returns the error for a container engine the current user has no access to.
+func containerEnginePermissionDenied() error {
+	return containerEngineError{
+		errors.New("Qodana container can't be run by the current user. Please fix the container engine configuration."),
+		"https://docs.docker.com/engine/install/linux-postinstall/#manage-docker-as-a-non-root-user",
+	}
+}
+
+// PullImage pulls docker image and prints the process, transient failures are retried up to the given number of times.
+func PullImage(client *client.Client, image string, retries int) {
+	if err := pullImageWithProgress(nil, client, image, retries); err != nil {
+		exitWithError(err)
+	}
+}
+
+// pullImageWithProgress pulls the image like PullImage and returns the error matching ErrImagePull,
+// the pull events are emitted according to the options.
+func pullImageWithProgress(options *QodanaOptions, client *client.Client, image string, retries int) error {
+	options.emitEvent(event{Event: eventPullStart, Image: image})
+	defer options.emitEvent(event{Event: eventPullDone, Image: image})
+	var err error
 	printProcess(
 		func(_ *pterm.SpinnerPrinter) {
-			pullImage(context.Background(), client, image)
+			err = pullImageWithRetries(context.Background(), client, image, retries)
 		},
 		fmt.Sprintf("Pulling the image %s", PrimaryBold(image)),
 		"pulling the latest version of linter",
 	)
+	return err
+}
+
+// PullImages pulls the given images with at most jobs pulls at a time, a failed pull doesn't stop the others,
+// and prints the summary. It returns the images that could not be pulled.
+func PullImages(client *client.Client, images []string, retries int, jobs int) []string {
+	return pullImages(images, jobs, func(image string) error {
+		return pullImageWithRetries(context.Background(), client, image, retries)
+	})
+}
+
+func pullImages(images []string, jobs int, pull func(image string) error) []string {
+	errs := make([]error, len(images))
+	printProcess(
+		func(spinner *pterm.SpinnerPrinter) {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			slots := make(chan struct{}, max(jobs, 1))
+			done := 0
+			for i, image := range images {
+				wg.Add(1)
+				slots <- struct{}{}
+				go func(i int, image string) {
+					defer wg.Done()
+					errs[i] = pull(image)
+					<-slots
+					mu.Lock()
+					defer mu.Unlock()
+					done++
+					updateText(spinner, fmt.Sprintf("[%d/%d] Pulling the images", done, len(images)))
+				}(i, image)
+			}
+			wg.Wait()
+		},
+		fmt.Sprintf("Pulling %s (%d at a time)", pluralize(len(images), "image", "images"), max(jobs, 1)),
+		"",
+	)
+	failed := make([]string, 0)
+	for i, image := range images {
+		if errs[i] != nil {
+			ErrorMessage("Could not pull %s: %s", image, errs[i])
+			failed = append(failed, image)
+		}
+	}
+	SuccessMessage("Pulled %d of %d images", len(images)-len(failed), len(images))
+	if len(failed) > 0 {
+		ErrorMessage("Failed to pull: %s", strings.Join(failed, ", "))
+	}
+	return failed
+}
+
+// PrepareImage makes sure the image is available locally according to the given pull policy.
+func PrepareImage(client *client.Client, image string, pullPolicy string, pullRetries int) {
+	if err := prepareImage(nil, client, image, pullPolicy, pullRetries); err != nil {
+		exitWithError(err)
+	}
+}
+
+// prepareImage makes sure the image is available locally like PrepareImage, the image problems are returned
+// as errors matching ErrImagePull, the pull events are emitted according to the options.
+func prepareImage(options *QodanaOptions, client *client.Client, image string, pullPolicy string, pullRetries int) error {
+	switch pullPolicy {
+	case PullPolicyAlways:
+		return pullImageWithProgress(options, client, image, pullRetries)
+	case PullPolicyMissing, "", PullPolicyNever:
+		present, err := isImagePresent(client, image)
+		if err != nil || present {
+			return err
+		}
+		if pullPolicy == PullPolicyNever {
+			return fmt.Errorf(
+				"%w %s: it is not present locally and pull policy is %s, load the image or use another --pull-policy",
+				ErrImagePull,
+				image,
+				PullPolicyNever,
+			)
+		}
+		return pullImageWithProgress(options, client, image, pullRetries)
+	default:
+		return configErrorf(
+			"Unknown pull policy %s, available values are: %s, %s, %s",
+			pullPolicy,
+			PullPolicyAlways,
+			PullPolicyMissing,
+			PullPolicyNever,
+		)
+	}
+}
+
+// isImagePresent checks whether the image is present locally.
+func isImagePresent(client *client.Client, image string) (bool, error) {
+	_, _, err := client.ImageInspectWithRaw(context.Background(), image)
+	if err == nil {
+		return true, nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return false, containerEngineError{fmt.Errorf("Couldn't inspect the image %s: %s", image, err), ""}
+	}
+	return false, nil
 }
 
 func isDockerUnauthorizedError(errMsg string) bool {
@@ -201,8 +507,8 @@ func isDockerUnauthorizedError(errMsg string) bool {
 	return strings.Contains(errMsg, "unauthorized") || strings.Contains(errMsg, "denied") || strings.Contains(errMsg, "forbidden")
 }
 
-// PullImage pulls docker image.
-func pullImage(ctx context.Context, client *client.Client, image string) {
+// pullImage pulls docker image, for the private registries the credentials from the docker config are used.
+func pullImage(ctx context.Context, client *client.Client, image string) error {
 	reader, err := client.ImagePull(ctx, image, types.ImagePullOptions{})
 	if err != nil && isDockerUnauthorizedError(err.Error()) {
 		cfg, err := cliconfig.Load("")
@@ -220,10 +526,10 @@ func pullImage(ctx context.Context, client *client.Client, image string) {
 		}
 		reader, err = client.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: encodedAuth})
 		if err != nil {
-			log.Fatal("can't pull image from the private registry", err)
+			return fmt.Errorf("%w from the private registry: %w", ErrImagePull, err)
 		}
 	} else if err != nil {
-		log.Fatal("can't pull image ", err)
+		return fmt.Errorf("%w: %w", ErrImagePull, err)
 	}
 	defer func(pull io.ReadCloser) {
 		err := pull.Close()
@@ -232,42 +538,83 @@ func pullImage(ctx context.Context, client *client.Client, image string) {
 		}
 	}(reader)
 	if _, err = io.Copy(io.Discard, reader); err != nil {
-		log.Fatal("couldn't read the image pull logs ", err)
+		return fmt.Errorf("%w, couldn't read the image pull logs: %w", ErrImagePull, err)
 	}
+	return nil
 }
 
-// ContainerCleanup cleans up Qodana containers.
+// isTransientPullError returns true for network and server-side errors that may go away on retry.
+// Missing images and authentication failures are not retried.
+func isTransientPullError(err error) bool {
+	if errdefs.IsNotFound(err) || errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) || isDockerUnauthorizedError(err.Error()) {
+		return false
+	}
+	if errdefs.IsUnavailable(err) || errdefs.IsSystem(err) || errdefs.IsDeadline(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	errMsg := lower(err.Error())
+	if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "manifest unknown") {
+		return false
+	}
+	for _, transient := range []string{
+		"timeout", "connection reset", "connection refused", "eof", "toomanyrequests", "too many requests",
+		"500 internal server error", "502 bad gateway", "503 service unavailable", "504 gateway timeout",
+	} {
+		if strings.Contains(errMsg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullImageWithRetries pulls docker image, transient failures are retried up to the given number of times with exponential backoff.
+func pullImageWithRetries(ctx context.Context, client *client.Client, image string, retries int) error {
+	delay := pullRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := pullImage(ctx, client, image)
+		if err == nil || attempt >= retries || !isTransientPullError(err) {
+			return err
+		}
+		WarningMessage("Pulling %s failed (attempt %d of %d): %s, retrying in %s", image, attempt+1, retries+1, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// ContainerCleanup stops and removes the running Qodana containers, e.g. when the run is interrupted.
+// It does nothing for the containers that have already exited and been removed, and runs only once.
 func ContainerCleanup() {
-	if containerName != "qodana-cli" { // if containerName is not set, it means that the container was not created!
-		docker := getContainerClient()
-		ctx := context.Background()
-		containers, err := docker.ContainerList(ctx, types.ContainerListOptions{})
-		if err != nil {
-			log.Fatal("couldn't get the running containers ", err)
+	containerCleanup.Do(func() {
+		startedContainersMu.Lock()
+		defer startedContainersMu.Unlock()
+		if len(startedContainers) == 0 {
+			return
 		}
-		for _, c := range containers {
-			if c.Names[0] == fmt.Sprintf("/%s", containerName) {
-				err = docker.ContainerStop(context.Background(), c.Names[0], nil)
-				if err != nil {
-					log.Fatal("couldn't stop the container ", err)
-				}
+		docker := getContainerClient()
+		for _, name := range startedContainers {
+			if err := removeContainer(docker, name); err != nil {
+				WarningMessage("Could not stop the container %s: %s", name, err)
 			}
 		}
-	}
+	})
 }
 
 // CheckContainerEngineMemory applicable only for Docker Desktop,
 // (has the default limit of 2GB which can be not enough when Gradle runs inside a container).
-func CheckContainerEngineMemory() {
+func CheckContainerEngineMemory() error {
 	docker := getContainerClient()
 	goos := runtime.GOOS
 	if //goland:noinspection GoBoolExpressions
 	goos != "windows" && goos != "darwin" {
-		return
+		return nil
 	}
 	info, err := docker.Info(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		return containerEngineNotRunning("docker", err)
 	}
 	var helpUrl string
 	switch goos {
@@ -286,12 +633,17 @@ func CheckContainerEngineMemory() {
 			helpUrl,
 		)
 	}
+	return nil
 }
 
 // getDockerOptions returns qodana docker container options.
 func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
-	cmdOpts := getIdeArgs(opts)
+	cmdOpts := GetCmdOptions(opts)
 	ExtractQodanaEnvironment(opts.setenv)
+	opts.setProxyEnv(opts.setenv)
+	if opts.NoStatistics {
+		opts.setenv(OptionEnv(noStatisticsOption), "true")
+	}
 	cachePath, err := filepath.Abs(opts.CacheDir)
 	if err != nil {
 		log.Fatal("couldn't get abs path for cache", err)
@@ -304,15 +656,19 @@ func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
 	if err != nil {
 		log.Fatal("couldn't get abs path for results", err)
 	}
-	containerName = os.Getenv(qodanaCliContainerName)
+	containerName := os.Getenv(qodanaCliContainerName)
 	if containerName == "" {
 		containerName = fmt.Sprintf("qodana-cli-%s", opts.id())
+	} else if opts.parallelName != "" {
+		// the linters run in parallel can't share the container name
+		containerName = fmt.Sprintf("%s-%s", containerName, opts.parallelName)
 	}
 	volumes := []mount.Mount{
 		{
-			Type:   mount.TypeBind,
-			Source: cachePath,
-			Target: "/data/cache",
+			Type:     mount.TypeBind,
+			Source:   cachePath,
+			Target:   "/data/cache",
+			ReadOnly: opts.MountCacheAsReadonly,
 		},
 		{
 			Type:   mount.TypeBind,
@@ -325,6 +681,35 @@ func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
 			Target: "/data/results",
 		},
 	}
+	if opts.ProfilePath != "" {
+		profilePath, err := filepath.Abs(opts.ProfilePath)
+		if err != nil {
+			log.Fatal("couldn't get abs path for profile", err)
+		}
+		volumes = append(volumes, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   profilePath,
+			Target:   containerProfilePath(profilePath),
+			ReadOnly: true,
+		})
+	}
+	if opts.ConfigPath != "" {
+		volumes = append(volumes, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   opts.ConfigPath,
+			Target:   containerConfigPath,
+			ReadOnly: true,
+		})
+	}
+	if opts.baselineUrl != "" {
+		volumes = append(volumes, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   opts.Baseline,
+			Target:   containerBaselinePath,
+			ReadOnly: true,
+		})
+	}
+	volumes = append(volumes, opts.scriptMounts()...)
 	for _, volume := range opts.Volumes {
 		source, target := extractDockerVolumes(volume)
 		if source != "" && target != "" {
@@ -339,7 +724,8 @@ func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
 	}
 	log.Debugf("image: %s", opts.Linter)
 	log.Debugf("container name: %s", containerName)
-	log.Debugf("user: %s", opts.User)
+	user := containerUser(opts.User)
+	log.Debugf("user: %s", user)
 	log.Debugf("volumes: %v", volumes)
 	log.Debugf("cmd: %v", cmdOpts)
 
@@ -357,6 +743,16 @@ func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
 			Mounts:     volumes,
 		}
 	}
+	dockerArgs, err := parseDockerArgs(opts.DockerArgs)
+	if err == nil {
+		err = dockerArgs.apply(hostConfig)
+	}
+	if err != nil {
+		log.Fatalf("Invalid --docker-arg: %s", err)
+	}
+	if err = opts.resourceLimits().apply(hostConfig); err != nil {
+		log.Fatal(err)
+	}
 
 	return &types.ContainerCreateConfig{
 		Name: containerName,
@@ -367,12 +763,22 @@ func getDockerOptions(opts *QodanaOptions) *types.ContainerCreateConfig {
 			AttachStdout: true,
 			AttachStderr: true,
 			Env:          opts.Env,
-			User:         opts.User,
+			User:         user,
 		},
 		HostConfig: hostConfig,
 	}
 }
 
+// containerProfilePath returns the path the given profile file is mounted to inside the container.
+func containerProfilePath(profilePath string) string {
+	return path.Join(containerProfileDir, filepath.Base(profilePath))
+}
+
+// DockerRunCommand returns the docker run command the analysis would be started with.
+func DockerRunCommand(opts *QodanaOptions) string {
+	return strings.TrimSpace(generateDebugDockerRunCommand(getDockerOptions(opts)))
+}
+
 func generateDebugDockerRunCommand(cfg *types.ContainerCreateConfig) string {
 	var cmdBuilder strings.Builder
 	cmdBuilder.WriteString("docker run ")
@@ -391,14 +797,16 @@ func generateDebugDockerRunCommand(cfg *types.ContainerCreateConfig) string {
 	if cfg.Config.User != "" {
 		cmdBuilder.WriteString(fmt.Sprintf("-u %s ", cfg.Config.User))
 	}
-	for _, env := range cfg.Config.Env {
-		if !strings.Contains(env, QodanaToken) || strings.Contains(env, QodanaLicense) || strings.Contains(env, QodanaLicenseOnlyToken) {
-			cmdBuilder.WriteString(fmt.Sprintf("-e %s ", env))
-		}
+	for _, env := range maskSecrets(cfg.Config.Env) {
+		cmdBuilder.WriteString(fmt.Sprintf("-e %s ", env))
 	}
 	if cfg.HostConfig != nil {
 		for _, m := range cfg.HostConfig.Mounts {
-			cmdBuilder.WriteString(fmt.Sprintf("-v %s:%s ", m.Source, m.Target))
+			if m.ReadOnly {
+				cmdBuilder.WriteString(fmt.Sprintf("-v %s:%s:ro ", m.Source, m.Target))
+			} else {
+				cmdBuilder.WriteString(fmt.Sprintf("-v %s:%s ", m.Source, m.Target))
+			}
 		}
 		for _, capAdd := range cfg.HostConfig.CapAdd {
 			cmdBuilder.WriteString(fmt.Sprintf("--cap-add %s ", capAdd))
@@ -406,9 +814,33 @@ func generateDebugDockerRunCommand(cfg *types.ContainerCreateConfig) string {
 		for _, secOpt := range cfg.HostConfig.SecurityOpt {
 			cmdBuilder.WriteString(fmt.Sprintf("--security-opt %s ", secOpt))
 		}
+		if cfg.HostConfig.NetworkMode != "" {
+			cmdBuilder.WriteString(fmt.Sprintf("--network %s ", cfg.HostConfig.NetworkMode))
+		}
+		for _, host := range cfg.HostConfig.ExtraHosts {
+			cmdBuilder.WriteString(fmt.Sprintf("--add-host %s ", host))
+		}
+		for _, dns := range cfg.HostConfig.DNS {
+			cmdBuilder.WriteString(fmt.Sprintf("--dns %s ", dns))
+		}
+		if cfg.HostConfig.Privileged {
+			cmdBuilder.WriteString("--privileged ")
+		}
+		if cfg.HostConfig.Memory != 0 {
+			cmdBuilder.WriteString(fmt.Sprintf("--memory %d ", cfg.HostConfig.Memory))
+		}
+		if cfg.HostConfig.MemorySwap != 0 {
+			cmdBuilder.WriteString(fmt.Sprintf("--memory-swap %d ", cfg.HostConfig.MemorySwap))
+		}
+		if cfg.HostConfig.ShmSize != 0 {
+			cmdBuilder.WriteString(fmt.Sprintf("--shm-size %d ", cfg.HostConfig.ShmSize))
+		}
+		if cfg.HostConfig.NanoCPUs != 0 {
+			cmdBuilder.WriteString(fmt.Sprintf("--cpus %g ", float64(cfg.HostConfig.NanoCPUs)/1e9))
+		}
 	}
 	cmdBuilder.WriteString(cfg.Config.Image + " ")
-	for _, arg := range cfg.Config.Cmd {
+	for _, arg := range maskSecrets(cfg.Config.Cmd) {
 		cmdBuilder.WriteString(fmt.Sprintf("%s ", arg))
 	}
 
@@ -416,7 +848,8 @@ func generateDebugDockerRunCommand(cfg *types.ContainerCreateConfig) string {
 }
 
 // getContainerExitCode returns the exit code of the docker container.
-func getContainerExitCode(ctx context.Context, client *client.Client, id string) int64 {
+// If timeout is reached, the container is stopped and removed, QodanaTimeoutExitCodePlaceholder is returned.
+func getContainerExitCode(ctx context.Context, client *client.Client, id string, timeout time.Duration) int64 {
 	statusCh, errCh := client.ContainerWait(ctx, id, container.WaitConditionNextExit)
 	select {
 	case err := <-errCh:
@@ -425,10 +858,29 @@ func getContainerExitCode(ctx context.Context, client *client.Client, id string)
 		}
 	case status := <-statusCh:
 		return status.StatusCode
+	case <-time.After(timeout):
+		if err := removeContainer(client, id); err != nil {
+			log.Fatal("couldn't stop the container ", err)
+		}
+		return QodanaTimeoutExitCodePlaceholder
 	}
 	return 0
 }
 
+// removeContainer stops the container and removes it if it was not auto-removed,
+// a container that has already exited or been removed is not an error.
+func removeContainer(client *client.Client, id string) error {
+	ctx := context.Background()
+	if err := client.ContainerStop(ctx, id, nil); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	err := client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+	if err != nil && !errdefs.IsNotFound(err) && !errdefs.IsConflict(err) {
+		return err
+	}
+	return nil
+}
+
 // runContainer runs the container.
 func runContainer(ctx context.Context, client *client.Client, opts *types.ContainerCreateConfig) {
 	createResp, err := client.ContainerCreate(
@@ -442,6 +894,9 @@ func runContainer(ctx context.Context, client *client.Client, opts *types.Contai
 	if err != nil {
 		log.Fatal("couldn't create the container ", err)
 	}
+	startedContainersMu.Lock()
+	startedContainers = append(startedContainers, opts.Name)
+	startedContainersMu.Unlock()
 	if err = client.ContainerStart(ctx, createResp.ID, types.ContainerStartOptions{}); err != nil {
 		log.Fatal("couldn't bootstrap the container ", err)
 	}
@@ -449,21 +904,90 @@ func runContainer(ctx context.Context, client *client.Client, opts *types.Contai
 
 // getContainerClient returns a docker client.
 func getContainerClient() *client.Client {
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newContainerClient()
 	if err != nil {
-		log.Fatal("couldn't create container client ", err)
+		exitWithError(err)
 	}
 	return docker
 }
 
+// newContainerClient returns a docker client configured from the environment, the error matches ErrContainerEngine.
+func newContainerClient() (*client.Client, error) {
+	docker, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return nil, containerEngineError{fmt.Errorf("Couldn't create container client: %w", err), ""}
+	}
+	return docker, nil
+}
+
 // extractDockerVolumes extracts the source and target of the volume to mount.
 func extractDockerVolumes(volume string) (string, string) {
 	split := strings.Split(volume, ":")
 	if len(split) == 2 {
 		return split[0], split[1]
 	} else if //goland:noinspection GoBoolExpressions
-	runtime.GOOS == "windows" {
+	runtime.GOOS == "windows" && len(split) == 3 {
 		return fmt.Sprintf("%s:%s", split[0], split[1]), split[2]
 	}
 	return "", ""
 }
+
+// validateVolume checks that the volume is in the host:container form.
+func validateVolume(volume string) error {
+	if source, target := extractDockerVolumes(volume); source == "" || target == "" {
+		return fmt.Errorf("invalid volume %q, expected host:container", volume)
+	}
+	return nil
+}
+
+// resolveYamlVolumes resolves the qodana.yaml volume sources against the project directory. Sources outside the project
+// are mounted only with --allow-yaml-volumes, so a qodana.yaml from the analyzed repository can't expose host files.
+// The sources inside the project must exist.
+func (o *QodanaOptions) resolveYamlVolumes(volumes []string) ([]string, error) {
+	resolved := make([]string, 0, len(volumes))
+	for _, volume := range volumes {
+		source, target := extractDockerVolumes(volume)
+		_, _, err := projectRelativePath(o.ProjectDir, source)
+		switch {
+		case errors.Is(err, errOutsideProject):
+			if !o.AllowYamlVolumes {
+				return nil, configErrorf(
+					"Volume %s from %s can't be mounted: %s, pass --allow-yaml-volumes to mount it",
+					volume,
+					o.YamlName,
+					err,
+				)
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			return nil, configErrorf("Volume %s from %s can't be mounted: %s doesn't exist in the project", volume, o.YamlName, source)
+		case err != nil:
+			return nil, configErrorf("Volume %s from %s can't be mounted: %s", volume, o.YamlName, err)
+		}
+		if !filepath.IsAbs(source) {
+			projectDir, err := filepath.Abs(o.ProjectDir)
+			if err != nil {
+				return nil, configErrorf("%s", err)
+			}
+			source = filepath.Join(projectDir, source)
+		}
+		resolved = append(resolved, source+":"+target)
+	}
+	return resolved, nil
+}
+
+// mergeVolumes appends the volumes that are not mounted to the same container path yet, so the existing ones win.
+func mergeVolumes(volumes []string, entries []string) []string {
+	targets := make(map[string]bool)
+	for _, v := range volumes {
+		_, target := extractDockerVolumes(v)
+		targets[target] = true
+	}
+	for _, v := range entries {
+		_, target := extractDockerVolumes(v)
+		if !targets[target] {
+			targets[target] = true
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2023/cloud"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -32,10 +33,15 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/owenrumney/go-sarif/v2/sarif"
+	"github.com/pterm/pterm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -85,6 +91,16 @@ func TestCliArgs(t *testing.T) {
 			opts: &QodanaOptions{ProjectDir: projectDir, CacheDir: cacheDir, ResultsDir: resultsDir, FixesStrategy: "cleanup", Ide: "/opt/idea/233"},
 			res:  []string{filepath.FromSlash("/opt/idea/bin/idea.sh"), "inspect", "qodana", "--cleanup", projectDir, resultsDir},
 		},
+		{
+			name: "--profile-path mounted for container runs",
+			opts: &QodanaOptions{ProjectDir: projectDir, CacheDir: cacheDir, ResultsDir: resultsDir, Linter: "jetbrains/qodana-jvm-community:latest", ProfilePath: filepath.Join(dir, "profiles", "custom.xml")},
+			res:  []string{filepath.FromSlash("/opt/idea/bin/idea.sh"), "inspect", "qodana", "--profile-path", "/data/profile/custom.xml", projectDir, resultsDir},
+		},
+		{
+			name: "--profile-path as is for local runs",
+			opts: &QodanaOptions{ProjectDir: projectDir, CacheDir: cacheDir, ResultsDir: resultsDir, Ide: Prod.Home, ProfilePath: filepath.Join(dir, "custom.xml")},
+			res:  []string{filepath.FromSlash("/opt/idea/bin/idea.sh"), "inspect", "qodana", "--profile-path", filepath.Join(dir, "custom.xml"), projectDir, resultsDir},
+		},
 		{
 			name: "--stub-profile ignored",
 			opts: &QodanaOptions{StubProfile: "ignored", ProjectDir: projectDir, CacheDir: cacheDir, ResultsDir: resultsDir, FixesStrategy: "cleanup", Ide: "/opt/idea/233"},
@@ -384,7 +400,7 @@ func TestScanFlags_Script(t *testing.T) {
 		"--script",
 		"custom-script:parameters",
 	}
-	actual := getIdeArgs(testOptions)
+	actual := GetCmdOptions(testOptions)
 	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("expected \"%s\" got \"%s\"", expected, actual)
 	}
@@ -446,7 +462,7 @@ func TestLegacyFixStrategies(t *testing.T) {
 				Prod.Version = "2023.2"
 			}
 
-			actual := getIdeArgs(tt.options)
+			actual := GetCmdOptions(tt.options)
 			if !reflect.DeepEqual(tt.expected, actual) {
 				t.Fatalf("expected \"%s\" got \"%s\"", tt.expected, actual)
 			}
@@ -556,7 +572,7 @@ func TestGetArgsThirdPartyLinters(t *testing.T) {
 				Prod.Code = tt.options.Ide
 			}
 
-			actual := getIdeArgs(tt.options)
+			actual := GetCmdOptions(tt.options)
 			if !reflect.DeepEqual(tt.expected, actual) {
 				t.Fatalf("expected \"%s\" got \"%s\"", tt.expected, actual)
 			}
@@ -1425,6 +1441,7 @@ func Test_Properties(t *testing.T) {
 		cliProperties []string
 		qodanaYaml    string
 		isContainer   bool
+		noStatistics  bool
 		expected      []string
 	}{
 		{
@@ -1480,6 +1497,14 @@ func Test_Properties(t *testing.T) {
 				"-Didea.application.info.value=0",
 			}, propertiesFixture(false, []string{})[3:]...),
 		},
+		{
+			name:          "statistics disabled with --no-statistics",
+			cliProperties: []string{"idea.headless.enable.statistics=true"},
+			qodanaYaml:    "",
+			isContainer:   false,
+			noStatistics:  true,
+			expected:      propertiesFixture(false, []string{}),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			err = os.WriteFile(filepath.Join(opts.ProjectDir, "qodana.yml"), []byte(tc.qodanaYaml), 0o600)
@@ -1487,6 +1512,7 @@ func Test_Properties(t *testing.T) {
 				t.Fatal(err)
 			}
 			opts.Property = tc.cliProperties
+			opts.NoStatistics = tc.noStatistics
 			qConfig := GetQodanaYaml(opts.ProjectDir)
 			if tc.isContainer {
 				err = os.Setenv(qodanaDockerEnv, "true")
@@ -1509,3 +1535,635 @@ func Test_Properties(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func Test_parseEnvFile(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	content := `# build settings
+GRADLE_OPTS=-Xmx2g
+
+export NPM_TOKEN="secret value"
+EMPTY=
+QUOTED='a=b'
+`
+	if err := os.WriteFile(envFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env, err := parseEnvFile(envFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GRADLE_OPTS=-Xmx2g", "NPM_TOKEN=secret value", "EMPTY=", "QUOTED=a=b"}, env)
+
+	assert.Equal(
+		t,
+		[]string{"NPM_TOKEN=explicit", "GRADLE_OPTS=-Xmx2g", "EMPTY=", "QUOTED=a=b"},
+		mergeEnv([]string{"NPM_TOKEN=explicit"}, env),
+	)
+
+	if err := os.WriteFile(envFile, []byte("A=1\nnot a variable\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = parseEnvFile(envFile)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), ".env:2:")
+	}
+}
+
+func Test_resolvePropertyFile(t *testing.T) {
+	propertyFile := filepath.Join(t.TempDir(), "qodana.properties")
+	content := `# performance tuning
+idea.max.intellisense.filesize = 5000
+! inspection settings
+qd.inspection.timeout=60
+
+idea.log.level=info
+`
+	if err := os.WriteFile(propertyFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options := &QodanaOptions{Property: []string{"idea.log.level=debug"}, PropertyFile: propertyFile}
+	assert.NoError(t, options.resolvePropertyFile())
+	assert.Equal(t, []string{"idea.log.level=debug", "idea.max.intellisense.filesize=5000", "qd.inspection.timeout=60"}, options.Property)
+
+	if err := os.WriteFile(propertyFile, []byte("a=1\n=2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := parsePropertyFile(propertyFile)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "qodana.properties:2:")
+	}
+}
+
+func Test_containerEngineHint(t *testing.T) {
+	assert.Contains(t, containerEngineHint("docker", "linux"), "sudo systemctl start docker")
+	assert.Contains(t, containerEngineHint("docker", "darwin"), "Docker Desktop")
+	assert.Contains(t, containerEngineHint("docker", "windows"), "Docker Desktop")
+	assert.Contains(t, containerEngineHint("podman", "linux"), "podman.socket")
+	assert.Contains(t, containerEngineHint("podman", "darwin"), "podman machine start")
+}
+
+func Test_removeContainer(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+	}{
+		{"running", http.StatusNoContent},
+		{"already removed", http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := make([]string, 0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				if tc.status == http.StatusNotFound {
+					_, _ = w.Write([]byte(`{"message":"No such container: qodana-cli-test"}`))
+				}
+			}))
+			defer server.Close()
+			docker, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.41"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.NoError(t, removeContainer(docker, "qodana-cli-test"))
+			assert.Equal(t, []string{"POST /v1.41/containers/qodana-cli-test/stop", "DELETE /v1.41/containers/qodana-cli-test"}, requests)
+		})
+	}
+}
+
+func Test_pingContainerEngine(t *testing.T) {
+	docker, err := client.NewClientWithOpts(client.WithHost("unix://" + filepath.Join(t.TempDir(), "docker.sock")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, pingContainerEngine(docker))
+}
+
+func Test_containerEngineNotRunning(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		hint string
+	}{
+		{
+			"not running",
+			errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"),
+			containerEngineHint("docker", runtime.GOOS),
+		},
+		{
+			"permission denied",
+			errors.New("permission denied while trying to connect to the Docker daemon socket at unix:///var/run/docker.sock"),
+			"https://docs.docker.com/engine/install/linux-postinstall/#manage-docker-as-a-non-root-user",
+		},
+		{
+			"os permission error",
+			fmt.Errorf("dial unix /var/run/docker.sock: %w", os.ErrPermission),
+			"https://docs.docker.com/engine/install/linux-postinstall/#manage-docker-as-a-non-root-user",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := containerEngineNotRunning("docker", tc.err)
+			assert.ErrorIs(t, err, ErrContainerEngine)
+			var engineErr containerEngineError
+			assert.True(t, errors.As(err, &engineErr))
+			assert.Equal(t, tc.hint, engineErr.hint)
+		})
+	}
+}
+
+func Test_isTransientPullError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("Get \"https://registry-1.docker.io/v2/\": net/http: TLS handshake timeout"), true},
+		{errors.New("read tcp 10.0.0.1:443: connection reset by peer"), true},
+		{errors.New("received unexpected HTTP status: 503 Service Unavailable"), true},
+		{errors.New("toomanyrequests: You have reached your pull rate limit"), true},
+		{errdefs.Unavailable(errors.New("daemon is busy")), true},
+		{errdefs.NotFound(errors.New("manifest for jetbrains/qodana-jvm:0.1 not found: manifest unknown")), false},
+		{errors.New("pull access denied for jetbrains/qodana-private"), false},
+		{errors.New("unauthorized: authentication required"), false},
+		{errors.New("invalid reference format"), false},
+	} {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			if got := isTransientPullError(tc.err); got != tc.expected {
+				t.Errorf("isTransientPullError() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_pullImages(t *testing.T) {
+	pulled := make([]string, 0)
+	failed := pullImages([]string{"jetbrains/qodana-jvm:latest", "jetbrains/qodana-go:latest", "jetbrains/qodana-php:latest"}, 1, func(image string) error {
+		if image == "jetbrains/qodana-go:latest" {
+			return errors.New("manifest unknown")
+		}
+		pulled = append(pulled, image)
+		return nil
+	})
+	assert.Equal(t, []string{"jetbrains/qodana-jvm:latest", "jetbrains/qodana-php:latest"}, pulled)
+	assert.Equal(t, []string{"jetbrains/qodana-go:latest"}, failed)
+}
+
+func Test_pullImagesJobs(t *testing.T) {
+	images := []string{"jetbrains/qodana-jvm:latest", "jetbrains/qodana-go:latest", "jetbrains/qodana-php:latest", "jetbrains/qodana-js:latest", "jetbrains/qodana-python:latest"}
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	failed := pullImages(images, 2, func(image string) error {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if image == "jetbrains/qodana-go:latest" || image == "jetbrains/qodana-python:latest" {
+			return errors.New("manifest unknown")
+		}
+		return nil
+	})
+	assert.Equal(t, 2, maxRunning)
+	assert.Equal(t, []string{"jetbrains/qodana-go:latest", "jetbrains/qodana-python:latest"}, failed)
+}
+
+func Test_listenReport(t *testing.T) {
+	listener, err := listenReport(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.NotZero(t, port)
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d", port), reportUrl(listener))
+
+	_, err = listenReport(port)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "--port 0")
+	}
+}
+
+func Test_waitForReport(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	assert.NoError(t, waitForReport(server.URL, time.Second, time.Millisecond))
+	assert.Equal(t, 3, requests)
+
+	listener, err := listenReport(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := reportUrl(listener)
+	_ = listener.Close()
+	err = waitForReport(url, 10*time.Millisecond, time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the report is not available after 10ms")
+	}
+}
+
+func Test_containerUser(t *testing.T) {
+	assert.Equal(t, "1001:1001", containerUser("1001:1001"))
+	assert.Equal(t, "root", containerUser("root"))
+	assert.Equal(t, GetDefaultUser(), containerUser(UserAuto))
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()), containerUser(UserAuto))
+	}
+}
+
+func Test_openUrl(t *testing.T) {
+	assert.NoError(t, openUrl("http://localhost:8080", OpenInNone))
+
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		t.Skip("the test opener is a shell script")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "opened")
+	opener := filepath.Join(dir, "opener.sh")
+	if err := os.WriteFile(opener, []byte("#!/bin/sh\necho \"$@\" > "+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, openUrl("http://localhost:8080", opener+" --new-window"))
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(out)
+		return err == nil && strings.TrimSpace(string(content)) == "--new-window http://localhost:8080"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Error(t, openUrl("http://localhost:8080", filepath.Join(dir, "missing-browser")))
+
+	spaced := filepath.Join(dir, "Program Files", "opener.sh")
+	if err := os.MkdirAll(filepath.Dir(spaced), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(opener, spaced); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(out)
+	assert.NoError(t, openUrl("http://localhost:8080", spaced))
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(out)
+		return err == nil && strings.TrimSpace(string(content)) == "http://localhost:8080"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func Test_splitCommand(t *testing.T) {
+	for command, expected := range map[string][]string{
+		"firefox":                 {"firefox"},
+		"  firefox  -new-window ": {"firefox", "-new-window"},
+		`"C:\Program Files\Google\Chrome\Application\chrome.exe" --new-window`: {`C:\Program Files\Google\Chrome\Application\chrome.exe`, "--new-window"},
+		`open -a 'Google Chrome'`: {"open", "-a", "Google Chrome"},
+		`browser --profile=""`:    {"browser", "--profile="},
+	} {
+		args, err := splitCommand(command)
+		assert.NoError(t, err, command)
+		assert.Equal(t, expected, args, command)
+	}
+	_, err := splitCommand(`"C:\Program Files\chrome.exe --new-window`)
+	assert.Error(t, err)
+}
+
+func Test_validateIdeHome(t *testing.T) {
+	ideHome := t.TempDir()
+	binDir := filepath.Join(ideHome, "bin")
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "darwin" {
+		binDir = filepath.Join(ideHome, "Contents", "MacOS")
+	}
+	if err := validateIdeHome(ideHome); err == nil {
+		t.Errorf("validateIdeHome() expected an error for a directory without an IDE launcher")
+	}
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, idea+getScriptSuffix()), []byte{}, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateIdeHome(ideHome); err != nil {
+		t.Errorf("validateIdeHome() = %v, want no error", err)
+	}
+	if err := validateIdeHome(filepath.Join(ideHome, "missing")); err == nil {
+		t.Errorf("validateIdeHome() expected an error for a missing directory")
+	}
+}
+
+func Test_isUpdateCheckDue(t *testing.T) {
+	lastCheckPath := filepath.Join(t.TempDir(), "last-update-check")
+	now := time.Date(2023, 11, 20, 12, 0, 0, 0, time.UTC)
+	if !isUpdateCheckDue(lastCheckPath, now) {
+		t.Errorf("isUpdateCheckDue() = false without a previous check, want true")
+	}
+	saveUpdateCheckTime(lastCheckPath, now)
+	for _, tc := range []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{"Checked an hour ago", now.Add(time.Hour), false},
+		{"Checked a day ago", now.Add(updateCheckInterval), true},
+		{"Clock moved back", now.Add(-time.Hour), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isUpdateCheckDue(lastCheckPath, tc.now); got != tc.expected {
+				t.Errorf("isUpdateCheckDue() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_isUpdateCheckDisabled(t *testing.T) {
+	for _, env := range []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL", "BUILD_NUMBER", "TF_BUILD", "CIRCLECI", "TEAMCITY_VERSION", "BITBUCKET_COMMIT", "SPACE_EXECUTION_NUMBER"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv(qodanaDockerEnv, "")
+	t.Setenv("QODANA_NO_UPDATE_CHECK", "")
+	if isUpdateCheckDisabled() {
+		t.Fatalf("isUpdateCheckDisabled() = true for a local run, want false")
+	}
+	t.Setenv("QODANA_NO_UPDATE_CHECK", "true")
+	if !isUpdateCheckDisabled() {
+		t.Errorf("isUpdateCheckDisabled() = false with QODANA_NO_UPDATE_CHECK, want true")
+	}
+	t.Setenv("QODANA_NO_UPDATE_CHECK", "")
+	t.Setenv("QODANA_NO_STATISTICS", "true")
+	if !isUpdateCheckDisabled() {
+		t.Errorf("isUpdateCheckDisabled() = false with QODANA_NO_STATISTICS, want true")
+	}
+	t.Setenv("QODANA_NO_STATISTICS", "")
+	t.Setenv("CI", "true")
+	if !isUpdateCheckDisabled() {
+		t.Errorf("isUpdateCheckDisabled() = false in CI, want true")
+	}
+}
+
+func TestIsNoStatisticsRequested(t *testing.T) {
+	assert.True(t, IsNoStatisticsRequested([]string{"scan", "--no-statistics"}))
+	assert.True(t, IsNoStatisticsRequested([]string{"--no-statistics=true", "-l", "jvm"}))
+	assert.False(t, IsNoStatisticsRequested([]string{"scan", "--no-statistics=false"}))
+	assert.False(t, IsNoStatisticsRequested([]string{"scan", "--no-statistics-please"}))
+}
+
+func Test_cleanResultsDir(t *testing.T) {
+	resultsDir := t.TempDir()
+	for _, file := range []string{QodanaSarifName, filepath.Join("log", "idea.log"), filepath.Join("report", "index.html")} {
+		path := filepath.Join(resultsDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, err := cleanResultsDir(resultsDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	entries, err := os.ReadDir(resultsDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	removed, err = cleanResultsDir(filepath.Join(resultsDir, "missing"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	projectDir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(projectDir, "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cleanResultsDir(projectDir)
+	assert.Error(t, err)
+	assert.FileExists(t, filepath.Join(projectDir, "main.go"))
+}
+
+func TestParseCmdOptions(t *testing.T) {
+	Prod.Version = "2023.2"
+	for _, tc := range []struct {
+		name string
+		opts *QodanaOptions
+	}{
+		{
+			name: "container run",
+			opts: &QodanaOptions{Linter: "jetbrains/qodana-jvm:2023.3", SaveReport: true, SourceDirectory: "src", DisableSanity: true, ProfileName: "separated words", RunPromo: "false", Script: "custom", Baseline: "qodana.sarif.json", BaselineIncludeAbsent: true, FailThreshold: "10", ApplyFixes: true, AnalysisId: "id", CoverageDir: "coverage", Property: []string{"foo.baz=bar", "foo.bar=baz"}},
+		},
+		{
+			name: "cdnet options",
+			opts: &QodanaOptions{Linter: "jetbrains/qodana-cdnet:2023.3", NoStatistics: true, Solution: "app.sln", Project: "app.csproj", Configuration: "Release", Platform: "x64", NoBuild: true},
+		},
+		{
+			name: "clang options",
+			opts: &QodanaOptions{Linter: "jetbrains/qodana-clang:2023.3", CompileCommands: "build/compile_commands.json", ClangArgs: "-std=c++17"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := ParseCmdOptions(GetCmdOptions(tc.opts))
+			actual.Linter = tc.opts.Linter
+			assert.Equal(t, tc.opts, actual)
+		})
+	}
+}
+
+func TestExportReport(t *testing.T) {
+	resultsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(resultsDir, "report"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "report", "index.html"), []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reportDir := filepath.Join(t.TempDir(), "html")
+	actual, err := ExportReport(&QodanaOptions{ResultsDir: resultsDir, ReportDir: reportDir})
+	assert.NoError(t, err)
+	assert.Equal(t, reportDir, actual)
+	assert.FileExists(t, filepath.Join(reportDir, "index.html"))
+
+	actual, err = ExportReport(&QodanaOptions{ResultsDir: resultsDir, ReportDir: filepath.Join(resultsDir, "report")})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(resultsDir, "report"), actual)
+
+	_, err = ExportReport(&QodanaOptions{ResultsDir: t.TempDir(), ReportDir: filepath.Join(t.TempDir(), "html")})
+	assert.Error(t, err, "there is neither a report nor a SARIF file")
+}
+
+func TestExportReportFromSarif(t *testing.T) {
+	resultsDir := t.TempDir()
+	writeTestSarif(t, resultsDir, []Result{
+		{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate", Message: "Unused <x>", Location: Location{File: "main.py", Line: 3, Column: 5}},
+		{RuleId: "PyUnresolvedReferences", Level: LevelError, Severity: "High", Message: "Unresolved reference"},
+	})
+	reportDir := filepath.Join(t.TempDir(), "html")
+	actual, err := ExportReport(&QodanaOptions{ResultsDir: resultsDir, ReportDir: reportDir})
+	assert.NoError(t, err)
+	assert.Equal(t, reportDir, actual)
+	content, err := os.ReadFile(filepath.Join(reportDir, "index.html"))
+	assert.NoError(t, err)
+	html := string(content)
+	assert.Contains(t, html, "Qodana found 1 error, 1 warning, 0 weak warnings")
+	assert.Contains(t, html, "main.py:3:5")
+	assert.Contains(t, html, "Unused &lt;x&gt;")
+	assert.Less(t, strings.Index(html, "PyUnresolvedReferences"), strings.Index(html, "PyUnusedLocal"), "the most severe first")
+}
+
+func TestSetLogLevel(t *testing.T) {
+	defer func() {
+		Quiet = false
+		log.SetLevel(log.ErrorLevel)
+	}()
+	for _, level := range LogLevels {
+		expected, _ := log.ParseLevel(level)
+		assert.NoError(t, SetLogLevel(level))
+		assert.Equal(t, expected, log.GetLevel())
+	}
+	assert.Error(t, SetLogLevel("loud"))
+
+	Quiet = true
+	assert.NoError(t, SetLogLevel("debug"))
+	assert.Equal(t, log.ErrorLevel, log.GetLevel())
+}
+
+func TestJsonLogFormat(t *testing.T) {
+	var out bytes.Buffer
+	Quiet = false
+	messageLogger.SetLevel(log.InfoLevel)
+	messageLogger.SetOutput(&out)
+	defer func() {
+		messageLogger.SetOutput(os.Stdout)
+		_ = SetLogFormat(LogFormatText)
+	}()
+	assert.NoError(t, SetLogFormat(LogFormatJSON))
+	assert.Error(t, SetLogFormat("xml"))
+
+	SuccessMessage("Analysis %s", "completed")
+	w := &linterLogWriter{stream: "stdout"}
+	_, _ = w.Write([]byte("first line\nsecond "))
+	_, _ = w.Write([]byte("line\r\nlast"))
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	var entry map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "Analysis completed", entry["message"])
+	assert.NotEmpty(t, entry["timestamp"])
+	for i, message := range []string{"first line", "second line", "last"} {
+		assert.NoError(t, json.Unmarshal([]byte(lines[i+1]), &entry))
+		assert.Equal(t, message, entry["message"])
+		assert.Equal(t, "linter", entry["source"])
+	}
+}
+
+func TestJsonLogFormatProblems(t *testing.T) {
+	var out bytes.Buffer
+	Quiet = false
+	messageLogger.SetLevel(log.InfoLevel)
+	messageLogger.SetOutput(&out)
+	defer func() {
+		messageLogger.SetOutput(os.Stdout)
+		_ = SetLogFormat(LogFormatText)
+	}()
+	assert.NoError(t, SetLogFormat(LogFormatJSON))
+
+	printGroupHeader("main.py", 1)
+	printProblem(".", 1, "PyUnusedLocal", "warning", "Unused variable", "main.py", 3, 5, 1, "x = 1")
+	assert.NoError(t, renderTable(pterm.TableData{{"Name", "Last modified"}, {"jvm", "2023-11-01 10:00"}}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	var problem map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &problem))
+	assert.Equal(t, "Unused variable", problem["message"])
+	assert.Equal(t, "PyUnusedLocal", problem["rule"])
+	assert.Equal(t, "warning", problem["severity"])
+	assert.Equal(t, "main.py", problem["file"])
+	assert.Equal(t, float64(3), problem["line"])
+	assert.Equal(t, float64(1), problem["index"])
+	var row map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	assert.Equal(t, "jvm", row["name"])
+	assert.Equal(t, "2023-11-01 10:00", row["last_modified"])
+}
+
+func TestMountCacheAsReadonly(t *testing.T) {
+	cacheDir := t.TempDir()
+	opts := &QodanaOptions{
+		Linter:     "jetbrains/qodana-jvm-community:latest",
+		ProjectDir: t.TempDir(),
+		ResultsDir: t.TempDir(),
+		CacheDir:   cacheDir,
+	}
+	assert.Contains(t, DockerRunCommand(opts), fmt.Sprintf("-v %s:/data/cache ", cacheDir))
+
+	opts.MountCacheAsReadonly = true
+	assert.Contains(t, DockerRunCommand(opts), fmt.Sprintf("-v %s:/data/cache:ro ", cacheDir))
+
+	assert.True(t, isReadOnlyWriteError("java.nio.file.FileSystemException: /data/cache/idea/x: Read-only file system"))
+	assert.False(t, isReadOnlyWriteError("The Project opening stage completed in 10 seconds"))
+}
+
+func Test_imageWithRegistry(t *testing.T) {
+	tests := []struct {
+		image    string
+		registry string
+		expected string
+	}{
+		{"jetbrains/qodana-jvm:2023.3", "registry.example.com", "registry.example.com/jetbrains/qodana-jvm:2023.3"},
+		{"jetbrains/qodana-jvm:2023.3", "registry.example.com/mirror/", "registry.example.com/mirror/jetbrains/qodana-jvm:2023.3"},
+		{"jetbrains/qodana-jvm:2023.3", "", "jetbrains/qodana-jvm:2023.3"},
+		{"other.example.com/jetbrains/qodana-jvm:2023.3", "registry.example.com", "other.example.com/jetbrains/qodana-jvm:2023.3"},
+		{"localhost:5000/jetbrains/qodana-jvm", "registry.example.com", "localhost:5000/jetbrains/qodana-jvm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image+" "+tt.registry, func(t *testing.T) {
+			assert.Equal(t, tt.expected, imageWithRegistry(tt.image, tt.registry))
+		})
+	}
+	assert.True(t, isOfficialImage("registry.example.com/jetbrains/qodana-jvm:2023.3", "registry.example.com"))
+	assert.False(t, isOfficialImage("registry.example.com/acme/linter:1", "registry.example.com"))
+	assert.NoError(t, validateRegistry("registry.example.com:5000/mirror"))
+	assert.Error(t, validateRegistry("https://registry.example.com"))
+	assert.Error(t, validateRegistry("/"))
+}
+
+func Test_scanDuration(t *testing.T) {
+	options := &QodanaOptions{ProjectDir: t.TempDir(), Linter: "jetbrains/qodana-jvm:latest"}
+	options.CacheDir = filepath.Join(t.TempDir(), "system", "linter", "cache")
+	if err := os.MkdirAll(options.GetLinterDir(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Duration(0), lastScanDuration(options))
+	saveScanDuration(options, 3*time.Minute+12*time.Second)
+	assert.Equal(t, 3*time.Minute+12*time.Second, lastScanDuration(options))
+
+	assert.False(t, isProgressShown(), "tests don't run on a terminal")
+}
+
+func TestExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		ignoreExitCode bool
+		strictErrors   bool
+		code           int
+		expected       int
+	}{
+		{"kept by default", false, false, QodanaFailThresholdExitCode, QodanaFailThresholdExitCode},
+		{"quality gate ignored", true, false, QodanaFailThresholdExitCode, QodanaSuccessExitCode},
+		{"failure ignored", true, false, QodanaDockerErrorExitCode, QodanaSuccessExitCode},
+		{"quality gate ignored with strict errors", true, true, QodanaFailThresholdExitCode, QodanaSuccessExitCode},
+		{"failure kept with strict errors", true, true, QodanaDockerErrorExitCode, QodanaDockerErrorExitCode},
+		{"strict errors alone", false, true, QodanaFailThresholdExitCode, QodanaFailThresholdExitCode},
+		{"config error ignored", true, false, QodanaConfigErrorExitCode, QodanaSuccessExitCode},
+		{"config error kept with strict errors", true, true, QodanaConfigErrorExitCode, QodanaConfigErrorExitCode},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &QodanaOptions{IgnoreExitCode: tt.ignoreExitCode, StrictErrors: tt.strictErrors}
+			assert.Equal(t, tt.expected, options.ExitCode(tt.code))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// sarifDiff is the difference between two SARIF reports.
+type sarifDiff struct {
+	Added     []Result `json:"added"`
+	Removed   []Result `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// key returns the key used to match the same problem in different reports:
+// rule id, file and fingerprint (or the message, if the fingerprint is missing).
+func (p Result) key() string {
+	id := p.Fingerprint
+	if id == "" {
+		id = p.Message
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s", p.RuleId, p.File, id)
+}
+
+// diffProblems compares two problem lists, problems occurring several times are matched one by one.
+func diffProblems(oldProblems []Result, newProblems []Result) sarifDiff {
+	diff := sarifDiff{Added: make([]Result, 0), Removed: make([]Result, 0)}
+	remaining := make(map[string][]Result)
+	for _, p := range oldProblems {
+		remaining[p.key()] = append(remaining[p.key()], p)
+	}
+	for _, p := range newProblems {
+		if matched := remaining[p.key()]; len(matched) > 0 {
+			remaining[p.key()] = matched[1:]
+			diff.Unchanged++
+		} else {
+			diff.Added = append(diff.Added, p)
+		}
+	}
+	for _, p := range oldProblems {
+		if matched := remaining[p.key()]; len(matched) > 0 {
+			remaining[p.key()] = matched[1:]
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+	return diff
+}
+
+// DiffSarif compares the old and the new SARIF reports.
+func DiffSarif(oldPath string, newPath string) (sarifDiff, error) {
+	oldProblems, err := readProblems(oldPath)
+	if err != nil {
+		return sarifDiff{}, err
+	}
+	newProblems, err := readProblems(newPath)
+	if err != nil {
+		return sarifDiff{}, err
+	}
+	return diffProblems(oldProblems, newProblems), nil
+}
+
+// SarifDiffToJSON returns the JSON representation of the SARIF reports difference.
+func SarifDiffToJSON(diff sarifDiff) (string, error) {
+	out, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return string(out), nil
+}
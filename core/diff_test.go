@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSarif(t *testing.T) {
+	fixed := Result{RuleId: "PyUnusedLocal", Severity: "High", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 5}, Fingerprint: "1"}
+	kept := Result{RuleId: "PyTypeChecker", Severity: "Moderate", Message: "Wrong type", Location: Location{File: "util.py", Line: 10, Column: 1}, Fingerprint: "2"}
+	moved := kept
+	moved.Line = 20
+	added := Result{RuleId: "PyTypeChecker", Severity: "Moderate", Message: "Wrong type", Location: Location{File: "util.py", Line: 30, Column: 1}, Fingerprint: "3"}
+
+	oldDir := filepath.Join(t.TempDir(), "old")
+	newDir := filepath.Join(t.TempDir(), "new")
+	for _, dir := range []string{oldDir, newDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldPath := writeTestSarif(t, oldDir, []Result{fixed, kept})
+	newPath := writeTestSarif(t, newDir, []Result{moved, added})
+
+	diff, err := DiffSarif(oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Result{added}, diff.Added)
+	assert.Equal(t, []Result{fixed}, diff.Removed)
+	assert.Equal(t, 1, diff.Unchanged)
+}
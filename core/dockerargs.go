@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/spf13/pflag"
+)
+
+// dockerArgs are the docker run options that can be passed with --docker-arg.
+// The container is created with the Docker API, so only the options listed here are supported.
+type dockerArgs struct {
+	network     string
+	addHosts    []string
+	dns         []string
+	capAdd      []string
+	securityOpt []string
+	privileged  bool
+	memory      string
+	memorySwap  string
+	shmSize     string
+	cpus        float64
+}
+
+// parseDockerArgs parses the --docker-arg values, each value can hold several whitespace-separated arguments.
+func parseDockerArgs(values []string) (*dockerArgs, error) {
+	args := &dockerArgs{}
+	flags := pflag.NewFlagSet("docker-arg", pflag.ContinueOnError)
+	flags.SetOutput(new(strings.Builder))
+	flags.StringVar(&args.network, "network", "", "")
+	flags.StringVar(&args.network, "net", "", "")
+	flags.StringArrayVar(&args.addHosts, "add-host", nil, "")
+	flags.StringArrayVar(&args.dns, "dns", nil, "")
+	flags.StringArrayVar(&args.capAdd, "cap-add", nil, "")
+	flags.StringArrayVar(&args.securityOpt, "security-opt", nil, "")
+	flags.BoolVar(&args.privileged, "privileged", false, "")
+	flags.StringVarP(&args.memory, "memory", "m", "", "")
+	flags.StringVar(&args.memorySwap, "memory-swap", "", "")
+	flags.StringVar(&args.shmSize, "shm-size", "", "")
+	flags.Float64Var(&args.cpus, "cpus", 0, "")
+
+	var fields []string
+	for _, value := range values {
+		fields = append(fields, strings.Fields(value)...)
+	}
+	if err := flags.Parse(fields); err != nil {
+		return nil, fmt.Errorf("%w, supported docker run options are --network, --add-host, --dns, --cap-add, --security-opt, --privileged, --memory, --memory-swap, --shm-size and --cpus", err)
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected docker argument %q, only options can be passed", flags.Arg(0))
+	}
+	if err := args.validate(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// validate checks the option values can be applied to the container.
+func (a *dockerArgs) validate() error {
+	return a.apply(&container.HostConfig{})
+}
+
+// apply sets the parsed options to the container host config.
+func (a *dockerArgs) apply(hostConfig *container.HostConfig) error {
+	if a.network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(a.network)
+	}
+	hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, a.addHosts...)
+	hostConfig.DNS = append(hostConfig.DNS, a.dns...)
+	hostConfig.CapAdd = append(hostConfig.CapAdd, a.capAdd...)
+	hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, a.securityOpt...)
+	hostConfig.Privileged = hostConfig.Privileged || a.privileged
+	var err error
+	if a.memory != "" {
+		if hostConfig.Memory, err = units.RAMInBytes(a.memory); err != nil {
+			return fmt.Errorf("invalid --memory: %w", err)
+		}
+	}
+	if a.memorySwap == "-1" {
+		hostConfig.MemorySwap = -1
+	} else if a.memorySwap != "" {
+		if hostConfig.MemorySwap, err = units.RAMInBytes(a.memorySwap); err != nil {
+			return fmt.Errorf("invalid --memory-swap: %w", err)
+		}
+	}
+	if a.shmSize != "" {
+		if hostConfig.ShmSize, err = units.RAMInBytes(a.shmSize); err != nil {
+			return fmt.Errorf("invalid --shm-size: %w", err)
+		}
+	}
+	if a.cpus < 0 {
+		return errors.New("invalid --cpus: the number of CPUs can't be negative")
+	}
+	if a.cpus > 0 {
+		hostConfig.NanoCPUs = int64(a.cpus * 1e9)
+	}
+	return nil
+}
+
+// resourceLimits returns the --memory and --cpus limits of the container, they take precedence over --docker-arg.
+func (o *QodanaOptions) resourceLimits() *dockerArgs {
+	return &dockerArgs{memory: o.Memory, cpus: o.Cpus}
+}
+
+// outOfMemoryMessage explains the QodanaOutOfMemoryExitCode exit of the container run with the given --memory limit.
+func outOfMemoryMessage(memory string) string {
+	limit, err := units.RAMInBytes(memory)
+	if memory == "" || err != nil {
+		return "The Qodana container was killed, likely because it ran out of memory: increase the memory available to the container engine or set a limit with --memory, e.g. --memory 8g"
+	}
+	return fmt.Sprintf(
+		"The Qodana container was killed, likely because it reached the --memory %s limit: run with a higher limit, e.g. --memory %s",
+		memory,
+		units.BytesSize(float64(2*limit)),
+	)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerArgs(t *testing.T) {
+	args, err := parseDockerArgs([]string{"--network host", "--add-host=db:10.0.0.2", "-m", "4g", "--cpus 1.5", "--cap-add NET_ADMIN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostConfig := &container.HostConfig{CapAdd: []string{"SYS_PTRACE"}}
+	assert.NoError(t, args.apply(hostConfig))
+	assert.Equal(t, container.NetworkMode("host"), hostConfig.NetworkMode)
+	assert.Equal(t, []string{"db:10.0.0.2"}, hostConfig.ExtraHosts)
+	assert.Equal(t, int64(4*1024*1024*1024), hostConfig.Memory)
+	assert.Equal(t, int64(1500000000), hostConfig.NanoCPUs)
+	assert.Equal(t, []string{"SYS_PTRACE", "NET_ADMIN"}, []string(hostConfig.CapAdd))
+
+	for _, invalid := range [][]string{
+		{"--rm"},
+		{"--network"},
+		{"host"},
+		{"--memory lots"},
+		{"--cpus -1"},
+	} {
+		_, err := parseDockerArgs(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDockerRunCommandWithDockerArgs(t *testing.T) {
+	options := &QodanaOptions{
+		ProjectDir: t.TempDir(),
+		ResultsDir: t.TempDir(),
+		CacheDir:   t.TempDir(),
+		Linter:     "jetbrains/qodana-jvm:latest",
+		DockerArgs: []string{"--network host --shm-size 1g"},
+	}
+	command := DockerRunCommand(options)
+	assert.Contains(t, command, "--network host ")
+	assert.Contains(t, command, "--shm-size 1073741824 ")
+}
+
+func TestDockerRunCommandWithResourceLimits(t *testing.T) {
+	options := &QodanaOptions{
+		ProjectDir: t.TempDir(),
+		ResultsDir: t.TempDir(),
+		CacheDir:   t.TempDir(),
+		Linter:     "jetbrains/qodana-jvm:latest",
+		DockerArgs: []string{"--memory 2g --cpus 1"},
+		Memory:     "8g",
+		Cpus:       2,
+	}
+	command := DockerRunCommand(options)
+	assert.Contains(t, command, "--memory 8589934592 ")
+	assert.Contains(t, command, "--cpus 2 ")
+
+	assert.Error(t, (&QodanaOptions{Memory: "a lot"}).resourceLimits().validate())
+	assert.Error(t, (&QodanaOptions{Cpus: -1}).resourceLimits().validate())
+}
+
+func TestOutOfMemoryMessage(t *testing.T) {
+	assert.Contains(t, outOfMemoryMessage(""), "increase the memory available to the container engine")
+	assert.Contains(t, outOfMemoryMessage("4g"), "reached the --memory 4g limit: run with a higher limit, e.g. --memory 8GiB")
+}
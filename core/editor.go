@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// problemAt returns the index-th problem (starting from 1) in the order printed by ReadSarif.
+func problemAt(sarifPath string, sortBy string, groupBy string, filter ProblemFilter, index int) (Result, error) {
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		return Result{}, err
+	}
+	results, _ := filter.apply(report.Results())
+	count := 0
+	for _, group := range printedProblems(results, sortBy, groupBy, 0) {
+		for _, r := range group.Problems {
+			count++
+			if count == index {
+				return r, nil
+			}
+		}
+	}
+	return Result{}, fmt.Errorf("problem #%d is not found, %s printed", index, pluralize(count, "problem is", "problems are"))
+}
+
+// editorCommand returns the command opening the file at the line and column with the given editor command line,
+// the way to pass the position depends on the editor.
+func editorCommand(editor string, path string, line int, column int) []string {
+	command := strings.Fields(editor)
+	if line <= 0 {
+		return append(command, path)
+	}
+	column = max(column, 1)
+	name := strings.TrimSuffix(filepath.Base(command[0]), ".exe")
+	switch name {
+	case "code", "code-insiders", "codium", "cursor":
+		return append(command, "--goto", fmt.Sprintf("%s:%d:%d", path, line, column))
+	case "subl", "zed", "hx", "helix":
+		return append(command, fmt.Sprintf("%s:%d:%d", path, line, column))
+	case "idea", "idea64", "goland", "pycharm", "webstorm", "phpstorm", "rider", "clion", "rubymine":
+		return append(command, "--line", strconv.Itoa(line), path)
+	default:
+		return append(command, "+"+strconv.Itoa(line), path)
+	}
+}
+
+// OpenProblem opens the file of the index-th problem printed by ReadSarif (starting from 1) at the problem line
+// in $VISUAL or $EDITOR, the problem path is resolved against the project directory.
+func OpenProblem(sarifPath string, projectDir string, sortBy string, groupBy string, filter ProblemFilter, index int) error {
+	problem, err := problemAt(sarifPath, sortBy, groupBy, filter, index)
+	if err != nil {
+		return err
+	}
+	if problem.File == "" {
+		return fmt.Errorf("problem #%d is not reported for a file", index)
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		return errors.New("no editor is configured, set the EDITOR environment variable, e.g. EDITOR=vim")
+	}
+	command := editorCommand(editor, filepath.Join(projectDir, problem.File), problem.Line, problem.Column)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
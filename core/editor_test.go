@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor   string
+		line     int
+		expected []string
+	}{
+		{"vim", 10, []string{"vim", "+10", "main.py"}},
+		{"code -w", 10, []string{"code", "-w", "--goto", "main.py:10:2"}},
+		{"/usr/local/bin/subl", 10, []string{"/usr/local/bin/subl", "main.py:10:2"}},
+		{"pycharm.exe", 10, []string{"pycharm.exe", "--line", "10", "main.py"}},
+		{"nano", 0, []string{"nano", "main.py"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			assert.Equal(t, tt.expected, editorCommand(tt.editor, "main.py", tt.line, 2))
+		})
+	}
+}
+
+func TestProblemAt(t *testing.T) {
+	unused := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 1}}
+	wrongType := Result{RuleId: "PyTypeChecker", Level: LevelError, Severity: "High", Message: "Wrong type", Location: Location{File: "util.py", Line: 40, Column: 5}}
+	unchanged := Result{RuleId: "PyTypeChecker", Level: LevelError, Severity: "High", Message: "Wrong type", Location: Location{File: "a.py", Line: 1, Column: 1}, BaselineState: baselineStateUnchanged}
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{unused, unchanged, wrongType})
+
+	problem, err := problemAt(sarifPath, SortBySeverity, "", ProblemFilter{}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "util.py", problem.File)
+	problem, err = problemAt(sarifPath, SortBySeverity, "", ProblemFilter{}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "main.py", problem.File)
+	problem, err = problemAt(sarifPath, SortBySeverity, "", ProblemFilter{Rules: []string{"PyUnusedLocal"}}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "main.py", problem.File)
+
+	_, err = problemAt(sarifPath, SortBySeverity, "", ProblemFilter{}, 3)
+	assert.EqualError(t, err, "problem #3 is not found, 2 problems are printed")
+}
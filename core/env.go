@@ -17,11 +17,13 @@
 package core
 
 import (
+	"bufio"
 	"fmt"
 	cienvironment "github.com/cucumber/ci-environment/go"
 	log "github.com/sirupsen/logrus"
 	"net/url"
 	"os"
+	"regexp"
 	"runtime"
 	"strings"
 )
@@ -53,8 +55,101 @@ const (
 	qodanaNugetPassword    = "QODANA_NUGET_PASSWORD"
 	qodanaNugetName        = "QODANA_NUGET_NAME"
 	qodanaRepoUrl          = "QODANA_REPO_URL"
+	// qodanaOptionEnvPrefix is the prefix of environment variables setting CLI options: QODANA_LINTER, QODANA_PROFILE_NAME, etc.
+	qodanaOptionEnvPrefix = "QODANA_"
 )
 
+// reservedOptionEnv contains the variables used by Qodana itself, they are never treated as CLI options.
+var reservedOptionEnv = []string{
+	qodanaEnv,
+	QodanaToken,
+	QodanaLicenseOnlyToken,
+	qodanaJobUrl,
+	qodanaRemoteUrl,
+	qodanaBranch,
+	qodanaRevision,
+	qodanaDockerEnv,
+	QodanaConfEnv,
+	QodanaToolEnv,
+	QodanaDistEnv,
+	QodanaLicense,
+	qodanaRepoUrl,
+}
+
+// envKeyPattern is the valid environment variable name.
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// parseEnvFile reads KEY=VALUE entries from a dotenv-style file: blank lines and lines starting with # are skipped,
+// the optional "export " prefix and quotes around the value are removed.
+func parseEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	env := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !found || !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE, got %q", path, lineNumber, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, scanner.Err()
+}
+
+// validateEnvEntry checks that the environment variable is in the KEY=VALUE form.
+func validateEnvEntry(env string) error {
+	if key, _, found := strings.Cut(env, "="); !found || !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid env %q, expected KEY=VALUE", env)
+	}
+	return nil
+}
+
+// mergeEnv appends the entries to env, keys that are already set in env are skipped.
+func mergeEnv(env []string, entries []string) []string {
+	keys := make(map[string]bool)
+	for _, e := range env {
+		key, _, _ := strings.Cut(e, "=")
+		keys[key] = true
+	}
+	for _, e := range entries {
+		key, _, _ := strings.Cut(e, "=")
+		if !keys[key] {
+			keys[key] = true
+			env = append(env, e)
+		}
+	}
+	return env
+}
+
+// OptionEnv returns the name of the environment variable for the given CLI option, e.g. QODANA_FAIL_THRESHOLD for fail-threshold.
+func OptionEnv(option string) string {
+	return qodanaOptionEnvPrefix + strings.ToUpper(strings.ReplaceAll(option, "-", "_"))
+}
+
+// LookupOptionEnv returns the value of the environment variable for the given CLI option if it is set.
+func LookupOptionEnv(option string) (string, bool) {
+	name := OptionEnv(option)
+	if Contains(reservedOptionEnv, name) {
+		return "", false
+	}
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
 // ExtractQodanaEnvironment extracts Qodana environment variables from the current environment.
 func ExtractQodanaEnvironment(setEnvironmentFunc func(string, string)) {
 	ci := cienvironment.DetectCIEnvironment()
@@ -170,7 +265,7 @@ func bootstrap(command string, project string) {
 
 		if res := RunCmd(project, executor, flag, command); res > 0 {
 			log.Printf("Provided bootstrap command finished with error: %d. Exiting...", res)
-			os.Exit(res)
+			Exit(res)
 		}
 	}
 }
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by core, so the code embedding it can tell the failure modes apart with errors.Is.
+var (
+	// ErrDockerNotFound reports that neither docker nor podman can be found in PATH.
+	ErrDockerNotFound = errors.New("docker (or podman) is not installed on the system or can't be found in PATH")
+	// ErrContainerEngine reports that the container engine is installed, but can't run the linter container.
+	ErrContainerEngine = errors.New("container engine is not available")
+	// ErrImagePull reports that the linter image can't be pulled.
+	ErrImagePull = errors.New("can't pull image")
+	// ErrThresholdExceeded reports that the analysis finished, but the found problems failed the quality gate.
+	ErrThresholdExceeded = errors.New("the number of problems exceeds the fail threshold")
+	// ErrIncompleteResults reports a SARIF file that is not complete JSON with the top-level runs array.
+	ErrIncompleteResults = errors.New("results appear incomplete; the scan may have failed")
+	// ErrInvalidConfig reports invalid options or configuration, the analysis is not started.
+	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrAnalysisFailed reports that the linter exited with a failure code, see AnalysisError for the code.
+	ErrAnalysisFailed = errors.New("analysis failed")
+)
+
+// configError keeps the message of the configuration problems and matches ErrInvalidConfig.
+type configError struct {
+	err error
+}
+
+func (e configError) Error() string {
+	return e.err.Error()
+}
+
+func (e configError) Unwrap() error {
+	return e.err
+}
+
+func (e configError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// containerEngineError keeps the message and the hint of the container engine problem and matches ErrContainerEngine.
+type containerEngineError struct {
+	err  error
+	hint string
+}
+
+func (e containerEngineError) Error() string {
+	return e.err.Error()
+}
+
+func (e containerEngineError) Unwrap() error {
+	return e.err
+}
+
+func (e containerEngineError) Is(target error) bool {
+	return target == ErrContainerEngine
+}
+
+// AnalysisError keeps the exit code of the failed linter run and matches ErrAnalysisFailed.
+type AnalysisError struct {
+	ExitCode int
+}
+
+func (e AnalysisError) Error() string {
+	return fmt.Sprintf("analysis failed with exit code %d", e.ExitCode)
+}
+
+func (e AnalysisError) Is(target error) bool {
+	return target == ErrAnalysisFailed
+}
+
+// configErrorf formats a configuration problem as an error matching ErrInvalidConfig.
+func configErrorf(format string, a ...any) error {
+	return configError{fmt.Errorf(format, a...)}
+}
+
+// ExitCodeError returns the error matching the exit code of RunAnalysis, nil for a successful run.
+// The linter failures are returned as AnalysisError, they never match ErrInvalidConfig.
+func ExitCodeError(exitCode int) error {
+	switch exitCode {
+	case QodanaSuccessExitCode:
+		return nil
+	case QodanaFailThresholdExitCode:
+		return ErrThresholdExceeded
+	default:
+		return AnalysisError{exitCode}
+	}
+}
+
+// errorExitCode returns the exit code of the CLI for the error returned by core.
+func errorExitCode(err error) int {
+	var analysisErr AnalysisError
+	switch {
+	case errors.Is(err, ErrInvalidConfig):
+		return QodanaConfigErrorExitCode
+	case errors.Is(err, ErrDockerNotFound), errors.Is(err, ErrContainerEngine), errors.Is(err, ErrImagePull):
+		return QodanaDockerErrorExitCode
+	case errors.As(err, &analysisErr):
+		return analysisErr.ExitCode
+	default:
+		return 1
+	}
+}
+
+// exitWithError prints the error (and the hint for the container engine problems) and exits with the matching code.
+func exitWithError(err error) {
+	printError(err)
+	Exit(errorExitCode(err))
+}
+
+// exitWithError is like the exitWithError function, but the exit code is changed according to ExitCode.
+func (o *QodanaOptions) exitWithError(err error) {
+	printError(err)
+	Exit(o.ExitCode(errorExitCode(err)))
+}
+
+// printError prints the error and the hint for the container engine problems.
+func printError(err error) {
+	ErrorMessage("%s", err)
+	var engineErr containerEngineError
+	if errors.As(err, &engineErr) && engineErr.hint != "" {
+		WarningMessage("%s", engineErr.hint)
+	}
+}
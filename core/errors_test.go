@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// scanOptions returns the options of a container scan of an empty project with the results and the cache in tmp.
+func scanOptions(t *testing.T) *QodanaOptions {
+	return &QodanaOptions{
+		Linter:     "jetbrains/qodana-python:2023.3",
+		ProjectDir: t.TempDir(),
+		YamlName:   "qodana.yaml",
+		ResultsDir: filepath.Join(t.TempDir(), "results"),
+		CacheDir:   filepath.Join(t.TempDir(), "cache"),
+		PullPolicy: PullPolicyMissing,
+	}
+}
+
+func TestScanInvalidConfigError(t *testing.T) {
+	options := scanOptions(t)
+	options.FailOnNew = true
+	err := Scan(context.Background(), options)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, "--fail-on-new requires --baseline or --baseline-dir", err.Error())
+	assert.Equal(t, QodanaConfigErrorExitCode, errorExitCode(err))
+}
+
+func TestScanDockerNotFoundError(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := Scan(context.Background(), scanOptions(t))
+	assert.ErrorIs(t, err, ErrDockerNotFound)
+	assert.Equal(t, QodanaDockerErrorExitCode, errorExitCode(err))
+}
+
+func TestScanContainerEngineError(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv(qodanaCliUsePodman, "")
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "docker.sock"))
+	err := Scan(context.Background(), scanOptions(t))
+	assert.ErrorIs(t, err, ErrContainerEngine)
+	assert.False(t, errors.Is(err, ErrDockerNotFound))
+	assert.Equal(t, QodanaDockerErrorExitCode, errorExitCode(err))
+}
+
+func TestPrepareImageError(t *testing.T) {
+	docker, err := client.NewClientWithOpts(client.WithHost("unix://" + filepath.Join(t.TempDir(), "docker.sock")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = prepareImage(nil, docker, "jetbrains/qodana-python:2023.3", PullPolicyAlways, 0)
+	assert.ErrorIs(t, err, ErrImagePull)
+	assert.Equal(t, QodanaDockerErrorExitCode, errorExitCode(err))
+
+	err = prepareImage(nil, docker, "jetbrains/qodana-python:2023.3", "sometimes", 0)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestValidateError(t *testing.T) {
+	options := &QodanaOptions{ProjectDir: t.TempDir(), Linter: "jetbrains/qodana-python:2023.2", Port: -1}
+	err := options.ValidateInput()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, "port -1 is out of range 0-65535", err.Error())
+
+	options.Port = 8080
+	assert.NoError(t, options.ValidateInput())
+	options.Linter = ""
+	assert.ErrorIs(t, options.Validate(), ErrInvalidConfig)
+}
+
+func TestExitCodeError(t *testing.T) {
+	assert.ErrorIs(t, ExitCodeError(QodanaFailThresholdExitCode), ErrThresholdExceeded)
+	assert.NoError(t, ExitCodeError(QodanaSuccessExitCode))
+	for _, exitCode := range []int{QodanaOutOfMemoryExitCode, 2} {
+		err := ExitCodeError(exitCode)
+		assert.ErrorIs(t, err, ErrAnalysisFailed)
+		var analysisErr AnalysisError
+		assert.True(t, errors.As(err, &analysisErr))
+		assert.Equal(t, exitCode, analysisErr.ExitCode)
+		assert.Equal(t, exitCode, errorExitCode(err))
+		for _, target := range []error{ErrDockerNotFound, ErrImagePull, ErrThresholdExceeded, ErrInvalidConfig} {
+			assert.False(t, errors.Is(err, target), "exit code %d matches %s", exitCode, target)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+)
+
+// The events written with --json-events.
+const (
+	eventPullStart    = "pull_start"
+	eventPullDone     = "pull_done"
+	eventScanStart    = "scan_start"
+	eventScanProgress = "scan_progress"
+	eventDone         = "done"
+)
+
+var (
+	// eventWriter is where the events are written to.
+	eventWriter io.Writer = os.Stderr
+	// eventWriterMu keeps the events of the linters run in parallel on separate lines.
+	eventWriterMu sync.Mutex
+)
+
+// event is a progress event written as one JSON line, only the fields relevant to the event are set:
+// pull_start and pull_done have image, scan_start has linter, scan_progress has stage with its step out of steps,
+// done has the number of problems and the exit code. The scan_progress and done events of a multi-linter scan have
+// the linter as well.
+type event struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Image     string    `json:"image,omitempty"`
+	Linter    string    `json:"linter,omitempty"`
+	Stage     string    `json:"stage,omitempty"`
+	Step      int       `json:"step,omitempty"`
+	Steps     int       `json:"steps,omitempty"`
+	Problems  *int      `json:"problems,omitempty"`
+	ExitCode  *int      `json:"exitCode,omitempty"`
+}
+
+// emitEvent writes the event if JsonEvents is set, nothing is written for nil options.
+func (o *QodanaOptions) emitEvent(e event) {
+	if o == nil || !o.JsonEvents {
+		return
+	}
+	e.Timestamp = time.Now().UTC()
+	out, err := json.Marshal(e)
+	if err != nil {
+		log.Debugf("Could not marshal the %s event: %s", e.Event, err)
+		return
+	}
+	eventWriterMu.Lock()
+	defer eventWriterMu.Unlock()
+	if _, err = eventWriter.Write(append(out, '\n')); err != nil {
+		log.Debugf("Could not write the %s event: %s", e.Event, err)
+	}
+}
+
+// scanProgressEvent returns the scan_progress event of the scan stage with the given index.
+func scanProgressEvent(index int) event {
+	return event{Event: eventScanProgress, Stage: scanStageNames[index], Step: index + 1, Steps: len(scanStageNames)}
+}
+
+// updateScanStage shows the scan stage with the given index in the spinner and emits the scan_progress event.
+func (o *QodanaOptions) updateScanStage(spinner *pterm.SpinnerPrinter, index int) {
+	updateText(spinner, o.scanStages[index])
+	e := scanProgressEvent(index)
+	if o.parallelName != "" {
+		e.Linter = o.analyzerName()
+	}
+	o.emitEvent(e)
+}
+
+// EmitDoneEvent emits the done event with the number of problems in the SARIF report, if any, and the exit code.
+func EmitDoneEvent(o *QodanaOptions, exitCode int) {
+	if !o.JsonEvents {
+		return
+	}
+	now := time.Now()
+	metrics, err := newScanMetrics(o, now, now, exitCode)
+	if err != nil {
+		log.Debugf("Could not count the problems: %s", err)
+	}
+	e := event{Event: eventDone, Problems: &metrics.TotalProblems, ExitCode: &exitCode}
+	if o.parallelName != "" {
+		e.Linter = o.analyzerName()
+	}
+	o.emitEvent(e)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonEvents(t *testing.T) {
+	out := new(bytes.Buffer)
+	eventWriter = out
+	t.Cleanup(func() {
+		eventWriter = os.Stderr
+	})
+	options := &QodanaOptions{JsonEvents: true}
+
+	resultsDir := t.TempDir()
+	writeTestSarif(t, resultsDir, []Result{
+		{RuleId: "A", Level: LevelError, Severity: "High", Message: "error"},
+		{RuleId: "B", Level: LevelWarning, Severity: "Moderate", Message: "warning"},
+	})
+	options.ResultsDir = resultsDir
+	options.emitEvent(event{Event: eventPullStart, Image: "jetbrains/qodana-jvm:latest"})
+	options.emitEvent(scanProgressEvent(4))
+	EmitDoneEvent(options, QodanaFailThresholdExitCode)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	expected := []map[string]interface{}{
+		{"event": "pull_start", "image": "jetbrains/qodana-jvm:latest"},
+		{"event": "scan_progress", "stage": "Analyzing the project", "step": float64(5), "steps": float64(6)},
+		{"event": "done", "problems": float64(2), "exitCode": float64(QodanaFailThresholdExitCode)},
+	}
+	for i, line := range lines {
+		var actual map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &actual); err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEmpty(t, actual["timestamp"])
+		delete(actual, "timestamp")
+		assert.Equal(t, expected[i], actual)
+	}
+
+	out.Reset()
+	options.JsonEvents = false
+	options.emitEvent(event{Event: eventPullDone})
+	var noOptions *QodanaOptions
+	noOptions.emitEvent(event{Event: eventPullDone})
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// excludeAllInspections is the qodana.yaml exclude name that disables all inspections for the paths.
+const excludeAllInspections = "All"
+
+// validateExcludes checks the --exclude glob patterns.
+func validateExcludes(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("empty --exclude pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesExclude checks if the project-relative slash-separated path is matched by the --exclude pattern.
+func matchesExclude(pattern string, rel string) bool {
+	name := rel
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		name = path.Base(rel)
+	}
+	ok, _ := path.Match(strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/"), name)
+	return ok
+}
+
+// matchExcludes returns the project-relative paths matched by each pattern. A pattern without a slash matches
+// a file or directory name at any depth (e.g. node_modules), other patterns match the whole relative path (e.g. src/gen/*).
+// The contents of a matched directory are not matched again.
+func matchExcludes(projectDir string, patterns []string) (map[string][]string, error) {
+	matches := make(map[string][]string, len(patterns))
+	err := filepath.WalkDir(projectDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(projectDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		matched := false
+		for _, pattern := range patterns {
+			if matchesExclude(pattern, rel) {
+				matches[pattern] = append(matches[pattern], rel)
+				matched = true
+			}
+		}
+		if matched && d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// writeExcludeConfig writes the configuration from configPath with the given paths excluded from all inspections
+// to outPath. The configuration is processed as a raw YAML document, so the options unknown to the CLI are kept.
+func writeExcludeConfig(configPath string, paths []string, outPath string) error {
+	config := make(map[string]interface{})
+	content, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		return err
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	if _, ok := config["version"]; !ok {
+		config["version"] = "1.0"
+	}
+	excludes, _ := config["exclude"].([]interface{})
+	added := false
+	for _, e := range excludes {
+		if exclude, ok := e.(map[string]interface{}); ok && exclude["name"] == excludeAllInspections {
+			existing, _ := exclude["paths"].([]interface{})
+			for _, p := range paths {
+				existing = append(existing, p)
+			}
+			exclude["paths"] = existing
+			added = true
+		}
+	}
+	if !added {
+		excludes = append(excludes, map[string]interface{}{"name": excludeAllInspections, "paths": paths})
+	}
+	config["exclude"] = excludes
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(outPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0o600)
+}
+
+// resolveExcludes excludes the paths matching the --exclude patterns and, with --files or --include-dir, the paths
+// other than the given files and directories from the analysis: the configuration with them added to the qodana.yaml
+// exclude section is generated in the linter directory and passed to the linter as --config, both for container
+// and native runs.
+func (o *QodanaOptions) resolveExcludes() error {
+	if len(o.Excludes) == 0 && len(o.Files) == 0 && len(o.IncludeDirs) == 0 {
+		return nil
+	}
+	if err := validateExcludes(o.Excludes); err != nil {
+		return configErrorf("%s", err)
+	}
+	matches, err := matchExcludes(o.ProjectDir, o.Excludes)
+	if err != nil {
+		return configErrorf("Could not apply --exclude: %s", err)
+	}
+	paths := make([]string, 0)
+	for _, pattern := range o.Excludes {
+		if len(matches[pattern]) == 0 {
+			WarningMessage("--exclude %s matches nothing in %s\n", pattern, o.ProjectDir)
+		}
+		paths = append(paths, matches[pattern]...)
+	}
+	if len(o.Files) > 0 || len(o.IncludeDirs) > 0 {
+		excludes, err := scopeExcludes(o.ProjectDir, append(append([]string{}, o.Files...), o.IncludeDirs...))
+		if err != nil {
+			return configErrorf("Could not apply --files and --include-dir: %s", err)
+		}
+		if len(o.Files) > 0 {
+			SuccessMessage("Analyzing %s", pluralize(len(o.Files), "file", "files"))
+		}
+		if len(o.IncludeDirs) > 0 {
+			SuccessMessage("Analyzing %s", strings.Join(o.IncludeDirs, ", "))
+		}
+		paths = append(paths, excludes...)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	configPath := filepath.Join(o.GetLinterDir(), "exclude", configName+".yaml")
+	if err = writeExcludeConfig(o.qodanaYamlPath(), paths, configPath); err != nil {
+		return configErrorf("Could not apply --exclude: %s", err)
+	}
+	o.ConfigPath = configPath
+	return nil
+}
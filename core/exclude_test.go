@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchExcludes(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, dir := range []string{"node_modules/lib", "web/node_modules", "src/gen", "src/main"} {
+		if err := os.MkdirAll(filepath.Join(projectDir, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"src/gen/api.js", "src/main/app.js", "src/main/app.min.js"} {
+		if err := os.WriteFile(filepath.Join(projectDir, file), []byte(""), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, err := matchExcludes(projectDir, []string{"node_modules", "src/gen/*", "*.min.js", "vendor"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node_modules", "web/node_modules"}, matches["node_modules"])
+	assert.Equal(t, []string{"src/gen/api.js"}, matches["src/gen/*"])
+	assert.Equal(t, []string{"src/main/app.min.js"}, matches["*.min.js"])
+	assert.Empty(t, matches["vendor"])
+
+	assert.NoError(t, validateExcludes([]string{"node_modules", "src/**/gen"}))
+	assert.Error(t, validateExcludes([]string{"src/[gen"}))
+	assert.Error(t, validateExcludes([]string{" "}))
+}
+
+func TestWriteExcludeConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "qodana.yaml")
+	content := "version: \"1.0\"\nlinter: jetbrains/qodana-js:latest\nexclude:\n  - name: All\n    paths:\n      - dist\n  - name: JSUnusedLocalSymbols\nunknownOption: kept\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "exclude", "qodana.yaml")
+	assert.NoError(t, writeExcludeConfig(configPath, []string{"node_modules"}, outPath))
+
+	q := LoadQodanaYaml(outPath)
+	assert.Equal(t, "jetbrains/qodana-js:latest", q.Linter)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"dist", "node_modules"}}, {Name: "JSUnusedLocalSymbols"}}, q.Excludes)
+	out, err := os.ReadFile(outPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "unknownOption: kept")
+
+	assert.NoError(t, writeExcludeConfig(filepath.Join(dir, "missing.yaml"), []string{"vendor"}, outPath))
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"vendor"}}}, LoadQodanaYaml(outPath).Excludes)
+}
+
+func TestResolveExcludesNative(t *testing.T) {
+	version := Prod.Version
+	t.Cleanup(func() { Prod.Version = version })
+	Prod.Version = "2023.3"
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "vendor"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yml"), []byte("version: \"1.0\"\nprofile:\n  name: qodana.recommended\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &QodanaOptions{
+		Ide:        QDJVM,
+		ProjectDir: projectDir,
+		YamlName:   "qodana.yml",
+		CacheDir:   filepath.Join(t.TempDir(), "cache"),
+		Excludes:   []string{"vendor"},
+	}
+	assert.NoError(t, opts.resolveExcludes())
+
+	assert.NotEqual(t, "", opts.ConfigPath)
+	assert.Equal(t, []string{"--config", opts.ConfigPath}, GetCmdOptions(opts))
+	q := LoadQodanaYaml(opts.ConfigPath)
+	assert.Equal(t, "qodana.recommended", q.Profile.Name)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"vendor"}}}, q.Excludes)
+}
+
+func TestResolveExcludesIncludeDirs(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, dir := range []string{"app", "lib", "services/api", "services/web"} {
+		if err := os.MkdirAll(filepath.Join(projectDir, filepath.FromSlash(dir)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &QodanaOptions{
+		Linter:          "jetbrains/qodana-jvm:2023.3",
+		ProjectDir:      projectDir,
+		YamlName:        "qodana.yaml",
+		CacheDir:        filepath.Join(t.TempDir(), "cache"),
+		SourceDirectory: "app",
+		IncludeDirs:     []string{"services/api"},
+	}
+	assert.NoError(t, opts.resolveIncludeDirs())
+	assert.Equal(t, "", opts.SourceDirectory)
+	assert.Equal(t, []string{"app", "services/api"}, opts.IncludeDirs)
+	assert.NoError(t, opts.resolveExcludes())
+
+	assert.Equal(t, []string{"--config", containerConfigPath}, GetCmdOptions(opts))
+	q := LoadQodanaYaml(opts.ConfigPath)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"lib", "services/web"}}}, q.Excludes)
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// OnExit registers the cleanup to run when the CLI exits with Exit, log.Fatal or is interrupted,
+// e.g. to remove the temporary files of the run.
+func OnExit(cleanup func()) {
+	log.RegisterExitHandler(cleanup)
+}
+
+// Exit runs the cleanups registered with OnExit and exits with the given code, use it instead of os.Exit.
+func Exit(code int) {
+	log.Exit(code)
+}
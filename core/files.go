@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// resolveFiles makes the --files paths relative to the project directory, the paths that are not files inside
+// the project are skipped with a warning. The run fails if no file is left.
+func (o *QodanaOptions) resolveFiles() error {
+	if o.Files == nil {
+		return nil
+	}
+	files := make([]string, 0, len(o.Files))
+	for _, file := range o.Files {
+		if strings.TrimSpace(file) == "" {
+			continue
+		}
+		relative, info, err := projectRelativePath(o.ProjectDir, file)
+		if err == nil && info.IsDir() {
+			err = errors.New("it is a directory")
+		}
+		if err != nil {
+			WarningMessage("Skipping --files %s: not a file inside the project (%s)\n", file, err)
+			continue
+		}
+		files = append(files, relative)
+	}
+	if len(files) == 0 {
+		return configErrorf("No files to analyze: --files lists no existing files inside the project %s", o.ProjectDir)
+	}
+	o.Files = files
+	return nil
+}
+
+// scopeExcludes returns the project-relative paths to exclude from the analysis so only the given files and
+// directories are analyzed: the files and directories next to the given paths and their parent directories.
+func scopeExcludes(projectDir string, paths []string) ([]string, error) {
+	kept := make(map[string]bool)
+	scope := make(map[string]bool)
+	for _, scoped := range paths {
+		scope[scoped] = true
+		for p := scoped; p != "." && p != "/"; p = path.Dir(p) {
+			kept[p] = true
+		}
+	}
+	excludes := make([]string, 0)
+	err := filepath.WalkDir(projectDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if scope[rel] && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if kept[rel] {
+			return nil
+		}
+		if d.Name() != ".git" {
+			excludes = append(excludes, rel)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return excludes, err
+}
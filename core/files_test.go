@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesScope(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, file := range []string{".git/config", "docs/readme.md", "src/a.py", "src/b.py", "src/sub/c.py", "setup.py"} {
+		path := filepath.Join(projectDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := QodanaOptions{
+		ProjectDir: projectDir,
+		Files:      []string{"src/a.py", filepath.Join(projectDir, "src", "sub", "c.py"), "missing.py", "docs", "../outside.py", ""},
+	}
+	assert.NoError(t, opts.resolveFiles())
+	assert.Equal(t, []string{"src/a.py", "src/sub/c.py"}, opts.Files)
+
+	excludes, err := scopeExcludes(projectDir, opts.Files)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs", "setup.py", "src/b.py"}, excludes)
+
+	excludes, err = scopeExcludes(projectDir, []string{"src/sub", "docs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"setup.py", "src/a.py", "src/b.py"}, excludes)
+}
+
+func TestFilesConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, file := range []string{"src/a.py", "src/b.py"} {
+		path := filepath.Join(projectDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("version: \"1.0\"\nfailThreshold: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &QodanaOptions{
+		Linter:     "jetbrains/qodana-python:2023.3",
+		ProjectDir: projectDir,
+		YamlName:   "qodana.yaml",
+		CacheDir:   filepath.Join(t.TempDir(), "cache"),
+		Files:      []string{"src/a.py"},
+	}
+	assert.NoError(t, opts.resolveFiles())
+	assert.NoError(t, opts.resolveExcludes())
+
+	assert.Equal(t, []string{"--config", containerConfigPath}, GetCmdOptions(opts))
+	q := LoadQodanaYaml(opts.ConfigPath)
+	assert.Equal(t, 3, *q.FailThreshold)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"qodana.yaml", "src/b.py"}}}, q.Excludes)
+	project, err := os.ReadFile(filepath.Join(projectDir, "qodana.yaml"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(project), "exclude")
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fileState is the file size and modification time used to detect the files changed by quick-fixes.
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// fixesRequested returns true if quick-fixes or cleanup are going to be applied to the project files.
+func (o *QodanaOptions) fixesRequested() bool {
+	if !o.fixesSupported() {
+		return false
+	}
+	if o.ApplyFixes || o.Cleanup {
+		return true
+	}
+	strategy := o.FixesStrategy
+	if strategy == "" {
+		strategy = LoadQodanaYaml(o.qodanaYamlPath()).FixesStrategy
+	}
+	switch strings.ToLower(strategy) {
+	case "apply", "cleanup":
+		return true
+	}
+	return false
+}
+
+// snapshotProject returns the state of the project files, the ignored directories and skipDirs are skipped.
+func snapshotProject(projectDir string, skipDirs []string) (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	err := filepath.WalkDir(projectDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != projectDir && (Contains(ignoredDirectories, d.Name()) || Contains(skipDirs, path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return err
+		}
+		snapshot[rel] = fileState{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return snapshot, err
+}
+
+// modifiedFiles returns the sorted files that were added, changed or removed between the snapshots.
+func modifiedFiles(before map[string]fileState, after map[string]fileState) []string {
+	files := make([]string, 0)
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || previous.Size != state.Size || !previous.ModTime.Equal(state.ModTime) {
+			files = append(files, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// runWithFixesReport runs the analysis and, if quick-fixes were requested, warns about the project files they changed.
+func runWithFixesReport(options *QodanaOptions, run func() int) int {
+	if !options.fixesRequested() {
+		return run()
+	}
+	skipDirs := make([]string, 0)
+	for _, dir := range []string{options.ResultsDir, options.CacheDir, options.ReportDir} {
+		if abs, err := filepath.Abs(dir); err == nil && dir != "" {
+			skipDirs = append(skipDirs, abs)
+		}
+	}
+	projectDir, err := filepath.Abs(options.ProjectDir)
+	if err != nil {
+		projectDir = options.ProjectDir
+	}
+	before, err := snapshotProject(projectDir, skipDirs)
+	if err != nil {
+		log.Warnf("Could not list the project files to report the applied fixes: %s", err)
+		return run()
+	}
+	exitCode := run()
+	after, err := snapshotProject(projectDir, skipDirs)
+	if err != nil {
+		log.Warnf("Could not list the project files to report the applied fixes: %s", err)
+		return exitCode
+	}
+	files := modifiedFiles(before, after)
+	if len(files) == 0 {
+		SuccessMessage("No project files were modified by quick-fixes")
+		return exitCode
+	}
+	log.Debugf("Files modified by quick-fixes: %v", files)
+	EmptyMessage()
+	WarningMessage(
+		"%s modified by quick-fixes in %s, review the changes before committing them",
+		PrimaryBold(pluralize(len(files), "file was", "files were")),
+		options.ProjectDir,
+	)
+	EmptyMessage()
+	return exitCode
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_modifiedFiles(t *testing.T) {
+	projectDir := t.TempDir()
+	resultsDir := filepath.Join(projectDir, "results")
+	for _, name := range []string{"a.java", "b.java", "c.java", filepath.Join(".git", "HEAD"), filepath.Join("results", "qodana.sarif.json")} {
+		path := filepath.Join(projectDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("class A {}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := snapshotProject(projectDir, []string{resultsDir})
+	assert.NoError(t, err)
+	assert.Len(t, before, 3)
+
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "a.java"), []byte("class A {}"), 0o644))
+	assert.NoError(t, os.Chtimes(filepath.Join(projectDir, "a.java"), later, later))
+	assert.NoError(t, os.Remove(filepath.Join(projectDir, "b.java")))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "d.java"), []byte("class D {}"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, ".git", "HEAD"), []byte("changed"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(resultsDir, "qodana.sarif.json"), []byte("changed"), 0o644))
+
+	after, err := snapshotProject(projectDir, []string{resultsDir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.java", "b.java", "d.java"}, modifiedFiles(before, after))
+}
+
+func TestQodanaOptions_fixesRequested(t *testing.T) {
+	projectDir := t.TempDir()
+	opts := &QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml", Linter: Image(QDJVM)}
+	assert.False(t, opts.fixesRequested())
+	opts.Cleanup = true
+	assert.True(t, opts.fixesRequested())
+
+	opts.Cleanup = false
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("fixesStrategy: apply\n"), 0o644))
+	assert.True(t, opts.fixesRequested())
+
+	opts.Linter = Image(QDNET)
+	assert.False(t, opts.fixesRequested(), "fixes are not supported for .NET")
+}
@@ -17,11 +17,16 @@
 package core
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
+	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -92,3 +97,125 @@ func gitRemoteUrl(cwd string) string {
 func gitBranch(cwd string) string {
 	return gitOutput(cwd, []string{"rev-parse", "--abbrev-ref", "HEAD"})[0]
 }
+
+// gitCommandOutput runs the git command in the given directory and returns its output, the error contains git stderr.
+func gitCommandOutput(cwd string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = cwd
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitChangedFiles returns the project files changed since the given commit (including uncommitted changes),
+// paths are relative to the given directory.
+func gitChangedFiles(cwd string, sha string) ([]string, error) {
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s is not a git repository: %w", cwd, err)
+	}
+	sha = strings.TrimPrefix(sha, "CI")
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--verify", "--quiet", sha+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("%s is not a valid commit in %s", sha, cwd)
+	}
+	out, err := gitCommandOutput(cwd, "diff", "--name-only", "--relative", sha, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return []string{}, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// gitMergeBase returns the commit where HEAD diverged from the given branch, e.g. origin/main.
+func gitMergeBase(cwd string, branch string) (string, error) {
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", cwd, err)
+	}
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+		return "", fmt.Errorf("HEAD does not point to a commit in %s", cwd)
+	}
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--verify", "--quiet", branch+"^{commit}"); err != nil {
+		if remote, name, found := strings.Cut(branch, "/"); found {
+			remotes, _ := gitCommandOutput(cwd, "remote")
+			if !Contains(strings.Split(remotes, "\n"), remote) {
+				return "", fmt.Errorf("branch %s is not found and remote %s is not configured in %s", branch, remote, cwd)
+			}
+			return "", fmt.Errorf("branch %s is not found, fetch it with 'git fetch %s %s'", branch, remote, name)
+		}
+		return "", fmt.Errorf("branch %s is not found in %s", branch, cwd)
+	}
+	if _, err := gitCommandOutput(cwd, "symbolic-ref", "--quiet", "HEAD"); err != nil {
+		log.Debugf("HEAD is detached in %s, comparing the checked out commit with %s", cwd, branch)
+	}
+	base, err := gitCommandOutput(cwd, "merge-base", "HEAD", branch)
+	if err != nil || base == "" {
+		return "", fmt.Errorf("HEAD and %s have no common commit, if the repository is a shallow clone fetch more history with 'git fetch --unshallow'", branch)
+	}
+	return base, nil
+}
+
+// repoCloneDir returns the directory the --repo is cloned to: the same for the same repository and branch,
+// so the cache and the results of the previous scans of the repository are reused.
+func (o *QodanaOptions) repoCloneDir() string {
+	return filepath.Join(o.getQodanaSystemDir(), "repos", getHash(o.Repo + "#" + o.RepoBranch)[:8])
+}
+
+// gitAuthEnv returns the environment passing the token to git as an HTTP authorization header,
+// so it is not saved to the clone configuration and not shown in the process arguments.
+func gitAuthEnv(token string) []string {
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}
+}
+
+// CloneRepo shallow-clones the --repo (the --branch or the default branch) and returns the clone directory.
+// Without --repo-token, the git credentials configured on the machine are used.
+func CloneRepo(o *QodanaOptions) (string, error) {
+	if o.RepoToken != "" && !strings.HasPrefix(o.Repo, "https://") && !strings.HasPrefix(o.Repo, "http://") {
+		return "", fmt.Errorf("--repo-token can be used only with http(s) repository URLs")
+	}
+	dir := o.repoCloneDir()
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return "", err
+	}
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if o.RepoBranch != "" {
+		args = append(args, "--branch", o.RepoBranch)
+	}
+	cmd := exec.Command("git", append(args, "--", o.Repo, dir)...)
+	cmd.Env = os.Environ()
+	if o.RepoToken != "" {
+		cmd.Env = append(cmd.Env, gitAuthEnv(o.RepoToken)...)
+	}
+	if !IsInteractive() {
+		cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	var err error
+	_ = spin(func(_ *pterm.SpinnerPrinter) {
+		err = cmd.Run()
+	}, fmt.Sprintf("Cloning %s", o.Repo))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitChangedFiles(t *testing.T) {
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		out, err := gitCommandOutput(repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	write := func(name string) {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("main.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	base := git("rev-parse", "HEAD")
+	write("src/util.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "util")
+
+	files, err := gitChangedFiles(repo, base)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"src/util.go"}, files)
+
+	files, err = gitChangedFiles(filepath.Join(repo, "src"), "CI"+base)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"util.go"}, files)
+
+	_, err = gitChangedFiles(repo, "unknown-ref")
+	assert.Error(t, err)
+
+	_, err = gitChangedFiles(t.TempDir(), base)
+	assert.Error(t, err)
+}
+
+func TestGitMergeBase(t *testing.T) {
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		out, err := gitCommandOutput(repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	write := func(name string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("main.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	base := git("rev-parse", "HEAD")
+	git("checkout", "-q", "-b", "feature")
+	write("feature.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "feature")
+	git("checkout", "-q", "main")
+	write("other.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "other")
+	git("checkout", "-q", "feature")
+
+	actual, err := gitMergeBase(repo, "main")
+	assert.NoError(t, err)
+	assert.Equal(t, base, actual)
+	files, err := gitChangedFiles(repo, actual)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"feature.go"}, files)
+
+	git("checkout", "-q", "--detach")
+	actual, err = gitMergeBase(repo, "main")
+	assert.NoError(t, err, "detached HEAD")
+	assert.Equal(t, base, actual)
+
+	_, err = gitMergeBase(repo, "origin/main")
+	assert.ErrorContains(t, err, "remote origin is not configured")
+	git("remote", "add", "origin", "https://example.com/repo.git")
+	_, err = gitMergeBase(repo, "origin/main")
+	assert.ErrorContains(t, err, "git fetch origin main")
+}
+
+func TestCloneRepo(t *testing.T) {
+	repo := t.TempDir()
+	git := func(args ...string) {
+		if _, err := gitCommandOutput(repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("checkout", "-q", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(repo, "feature.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "feature")
+	git("checkout", "-q", "-")
+
+	systemDir := t.TempDir()
+	options := &QodanaOptions{Repo: "file://" + filepath.ToSlash(repo), CacheDir: filepath.Join(systemDir, "linter", "cache")}
+	dir, err := CloneRepo(options)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(systemDir, "repos"), filepath.Dir(dir))
+	assert.FileExists(t, filepath.Join(dir, "main.go"))
+	assert.NoFileExists(t, filepath.Join(dir, "feature.go"))
+
+	options.RepoBranch = "feature"
+	featureDir, err := CloneRepo(options)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir, featureDir)
+	assert.FileExists(t, filepath.Join(featureDir, "feature.go"))
+	again, err := CloneRepo(options)
+	assert.NoError(t, err)
+	assert.Equal(t, featureDir, again, "the same repository and branch are cloned to the same directory")
+
+	options.RepoToken = "secret"
+	_, err = CloneRepo(options)
+	assert.ErrorContains(t, err, "only with http(s)")
+
+	options = &QodanaOptions{Repo: "file://" + filepath.ToSlash(filepath.Join(repo, "missing")), CacheDir: options.CacheDir}
+	_, err = CloneRepo(options)
+	assert.Error(t, err)
+	assert.NoDirExists(t, options.repoCloneDir())
+}
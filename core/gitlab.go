@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GitLabReportName is the default name of the GitLab Code Quality report.
+const GitLabReportName = "gl-code-quality-report.json"
+
+// gitlabIssue is a problem in the GitLab Code Quality report format.
+// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// gitlabSeverity maps the SARIF level to the GitLab Code Quality severity.
+func gitlabSeverity(level Level) string {
+	switch level {
+	case LevelError:
+		return "major"
+	case LevelNote, LevelNone:
+		return "info"
+	default:
+		return "minor"
+	}
+}
+
+// gitlabIssues converts the problems to GitLab Code Quality issues, problems absent in the current run are skipped.
+// Fingerprints are unique within the report: repeated problems get the occurrence number mixed in.
+func gitlabIssues(problems []Result) []gitlabIssue {
+	issues := make([]gitlabIssue, 0)
+	occurrences := map[string]int{}
+	for _, p := range problems {
+		if p.BaselineState == baselineStateAbsent {
+			continue
+		}
+		key := p.key()
+		occurrences[key]++
+		hash := md5.Sum([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+		line := p.Line
+		if line < 1 {
+			line = 1
+		}
+		issues = append(issues, gitlabIssue{
+			Description: p.Message,
+			CheckName:   p.RuleId,
+			Fingerprint: hex.EncodeToString(hash[:]),
+			Severity:    gitlabSeverity(p.Level),
+			Location:    gitlabLocation{Path: p.File, Lines: gitlabLines{Begin: line}},
+		})
+	}
+	return issues
+}
+
+// WriteGitLabReport converts the given SARIF file to the GitLab Code Quality report and writes it to reportPath.
+func WriteGitLabReport(sarifPath string, reportPath string) error {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(gitlabIssues(problems), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(reportPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath, out, 0o644)
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitlabIssues(t *testing.T) {
+	problems := []Result{
+		{RuleId: "PyUnusedLocal", Level: LevelError, Message: "Unused variable", Location: Location{File: "main.py", Line: 3}},
+		{RuleId: "PyUnusedLocal", Level: LevelError, Message: "Unused variable", Location: Location{File: "main.py", Line: 3}},
+		{RuleId: "PyTypeChecker", Message: "Wrong type", Location: Location{File: "util.py", Line: 10}},
+		{RuleId: "PyOld", Level: LevelNote, Message: "Old problem", Location: Location{File: "old.py", Line: 1}, BaselineState: baselineStateAbsent},
+		{RuleId: "ProjectLevel", Level: LevelNote, Message: "Project problem"},
+	}
+
+	issues := gitlabIssues(problems)
+	assert.Len(t, issues, 4)
+	assert.Equal(t, "major", issues[0].Severity)
+	assert.Equal(t, "minor", issues[2].Severity)
+	assert.Equal(t, "info", issues[3].Severity)
+	assert.Equal(t, gitlabLocation{Path: "util.py", Lines: gitlabLines{Begin: 10}}, issues[2].Location)
+	assert.Equal(t, 1, issues[3].Location.Lines.Begin)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	assert.Equal(t, issues[2].Fingerprint, gitlabIssues(problems[2:3])[0].Fingerprint)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/docker/docker/api/types/mount"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// containerBeforeScriptPath is where the --before-script file is mounted to inside the container.
+	containerBeforeScriptPath = "/data/scripts/before.sh"
+	// containerAfterScriptPath is where the --after-script file is mounted to inside the container.
+	containerAfterScriptPath = "/data/scripts/after.sh"
+)
+
+// resolveScripts makes the --before-script and --after-script paths absolute, so the same file is mounted to the container
+// and run by the native run from the project directory.
+func (o *QodanaOptions) resolveScripts() error {
+	for _, script := range []*string{&o.BeforeScript, &o.AfterScript} {
+		if *script == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*script)
+		if err != nil {
+			return configErrorf("Could not resolve the script %s: %s", *script, err)
+		}
+		*script = abs
+	}
+	return nil
+}
+
+// checkScriptsSupported stops the container run with --before-script or --after-script if the linter image
+// doesn't run them, instead of failing on the unknown option.
+func (o *QodanaOptions) checkScriptsSupported() error {
+	if o.Linter == "" || (o.BeforeScript == "" && o.AfterScript == "") || o.releaseAtLeast(minScriptRelease) {
+		return nil
+	}
+	return configErrorf("--before-script and --after-script require linter %s or newer, update the linter", minScriptRelease)
+}
+
+// validateScript checks that the script passed with the given flag is an existing file.
+func validateScript(flag string, script string) error {
+	info, err := os.Stat(script)
+	if err != nil {
+		return fmt.Errorf("--%s %s does not exist", flag, script)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("--%s %s is a directory, not a script", flag, script)
+	}
+	return nil
+}
+
+// scriptMounts returns the read-only mounts of the before and after scripts for a container run.
+func (o *QodanaOptions) scriptMounts() []mount.Mount {
+	var mounts []mount.Mount
+	for _, script := range []struct{ source, target string }{
+		{o.BeforeScript, containerBeforeScriptPath},
+		{o.AfterScript, containerAfterScriptPath},
+	} {
+		if script.source == "" {
+			continue
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   script.source,
+			Target:   script.target,
+			ReadOnly: true,
+		})
+	}
+	return mounts
+}
+
+// runScript runs the script with the shell in the project directory, its output is forwarded, and returns its exit code.
+func runScript(script string, project string) int {
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		return RunCmd(project, "cmd", "/c", script)
+	}
+	return RunCmd(project, "sh", script)
+}
+
+// runBeforeScript runs --before-script before the analysis, the run exits with its code if it fails.
+func (o *QodanaOptions) runBeforeScript() {
+	if o.BeforeScript == "" {
+		return
+	}
+	log.Printf("Running the before-script %s...", o.BeforeScript)
+	if res := runScript(o.BeforeScript, o.ProjectDir); res != 0 {
+		ErrorMessage("The before-script %s finished with exit code %d, the analysis is not started", o.BeforeScript, res)
+		Exit(res)
+	}
+}
+
+// runAfterScript runs --after-script after the analysis, its failure is reported, but doesn't change the exit code.
+func (o *QodanaOptions) runAfterScript() {
+	if o.AfterScript == "" {
+		return
+	}
+	log.Printf("Running the after-script %s...", o.AfterScript)
+	if res := runScript(o.AfterScript, o.ProjectDir); res != 0 {
+		WarningMessage("The after-script %s finished with exit code %d\n", o.AfterScript, res)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScript(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "before.sh")
+	if err := os.WriteFile(script, []byte("echo before\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, validateScript("before-script", script))
+	assert.EqualError(t, validateScript("before-script", dir), "--before-script "+dir+" is a directory, not a script")
+	missing := filepath.Join(dir, "missing.sh")
+	assert.EqualError(t, validateScript("after-script", missing), "--after-script "+missing+" does not exist")
+}
+
+func TestScriptMounts(t *testing.T) {
+	assert.Empty(t, (&QodanaOptions{}).scriptMounts())
+
+	before, err := filepath.Abs("before.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &QodanaOptions{BeforeScript: "before.sh", AfterScript: "/ci/after.sh"}
+	assert.NoError(t, opts.resolveScripts())
+	assert.Equal(t, before, opts.BeforeScript)
+	assert.Equal(t, []mount.Mount{
+		{Type: mount.TypeBind, Source: before, Target: containerBeforeScriptPath, ReadOnly: true},
+		{Type: mount.TypeBind, Source: "/ci/after.sh", Target: containerAfterScriptPath, ReadOnly: true},
+	}, opts.scriptMounts())
+
+	opts.Linter = "jetbrains/qodana-jvm:2023.3"
+	assert.Equal(t, []string{
+		"--before-script", containerBeforeScriptPath,
+		"--after-script", containerAfterScriptPath,
+	}, GetCmdOptions(opts))
+	assert.NoError(t, opts.checkScriptsSupported())
+
+	opts.Linter = "jetbrains/qodana-jvm:2023.2"
+	assert.Empty(t, GetCmdOptions(opts))
+	assert.ErrorIs(t, opts.checkScriptsSupported(), ErrInvalidConfig)
+}
+
+func TestRunScript(t *testing.T) {
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts are run with cmd on Windows")
+	}
+	project := t.TempDir()
+	script := filepath.Join(t.TempDir(), "before.sh")
+	if err := os.WriteFile(script, []byte("touch generated.txt\nexit 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, runScript(script, project))
+	assert.FileExists(t, filepath.Join(project, "generated.txt"))
+}
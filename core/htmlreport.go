@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// sarifReportTemplate is the minimal HTML report rendered from the SARIF report when the linter doesn't write one.
+var sarifReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Qodana report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Qodana report</h1>
+<p>{{.Summary}}</p>
+<table>
+<tr><th>Severity</th><th>Rule</th><th>Location</th><th>Message</th></tr>
+{{- range .Problems}}
+<tr><td>{{.Severity}}</td><td>{{.RuleId}}</td><td>{{if .File}}{{.File}}:{{.Line}}:{{.Column}}{{end}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// writeSarifReport renders the problems of the SARIF report as index.html in reportDir, the most severe first.
+func writeSarifReport(sarifPath string, reportDir string) error {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", sarifPath, err)
+	}
+	sortProblems(problems, SortBySeverity)
+	if err = os.MkdirAll(reportDir, os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(filepath.Join(reportDir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer func(out *os.File) {
+		_ = out.Close()
+	}(out)
+	return sarifReportTemplate.Execute(out, struct {
+		Summary  string
+		Problems []Result
+	}{getSummary(problems), problems})
+}
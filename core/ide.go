@@ -61,6 +61,7 @@ func getIdeExitCode(resultsDir string, c int) (res int) {
 func runQodanaLocal(opts *QodanaOptions) int {
 	args := getIdeRunCommand(opts)
 	res := getIdeExitCode(opts.ResultsDir, RunCmdWithTimeout("", opts.GetAnalysisTimeout(), QodanaTimeoutExitCodePlaceholder, args...))
+	opts.runAfterScript()
 	if res > QodanaSuccessExitCode && res != QodanaFailThresholdExitCode {
 		postAnalysis(opts)
 		return res
@@ -74,13 +75,18 @@ func runQodanaLocal(opts *QodanaOptions) int {
 
 func getIdeRunCommand(opts *QodanaOptions) []string {
 	args := []string{QuoteForWindows(Prod.IdeScript), "inspect", "qodana"}
-	args = append(args, getIdeArgs(opts)...)
+	args = append(args, GetCmdOptions(opts)...)
 	args = append(args, QuoteForWindows(opts.ProjectDir), QuoteForWindows(opts.ResultsDir))
 	return args
 }
 
-// getIdeArgs returns qodana command options.
-func getIdeArgs(opts *QodanaOptions) []string {
+// GetCmdOptions returns qodana command options in a stable order:
+// report and scope options (--save-report, --source-directory, --disable-sanity),
+// profile options (--profile-name, --profile-path, --run-promo), --script, --config, baseline options, --fail-threshold,
+// fixes options, third-party linter options and, for container runs only, --analysis-id, --coverage-dir, --property
+// and the mounted --before-script and --after-script.
+// ParseCmdOptions does the reverse.
+func GetCmdOptions(opts *QodanaOptions) []string {
 	arguments := make([]string, 0)
 	if opts.Linter != "" && opts.SaveReport {
 		arguments = append(arguments, "--save-report")
@@ -95,7 +101,11 @@ func getIdeArgs(opts *QodanaOptions) []string {
 		arguments = append(arguments, "--profile-name", quoteIfSpace(opts.ProfileName))
 	}
 	if opts.ProfilePath != "" {
-		arguments = append(arguments, "--profile-path", QuoteForWindows(opts.ProfilePath))
+		profilePath := opts.ProfilePath
+		if opts.Linter != "" {
+			profilePath = containerProfilePath(profilePath)
+		}
+		arguments = append(arguments, "--profile-path", QuoteForWindows(profilePath))
 	}
 	if opts.RunPromo != "" {
 		arguments = append(arguments, "--run-promo", opts.RunPromo)
@@ -103,13 +113,24 @@ func getIdeArgs(opts *QodanaOptions) []string {
 	if opts.Script != "" && opts.Script != "default" {
 		arguments = append(arguments, "--script", opts.Script)
 	}
+	if opts.ConfigPath != "" && opts.releaseAtLeast(minConfigRelease) {
+		configPath := opts.ConfigPath
+		if opts.Linter != "" {
+			configPath = containerConfigPath
+		}
+		arguments = append(arguments, "--config", QuoteForWindows(configPath))
+	}
 	if opts.Baseline != "" {
-		arguments = append(arguments, "--baseline", QuoteForWindows(opts.Baseline))
+		baseline := opts.Baseline
+		if opts.Linter != "" && opts.baselineUrl != "" {
+			baseline = containerBaselinePath
+		}
+		arguments = append(arguments, "--baseline", QuoteForWindows(baseline))
 	}
 	if opts.BaselineIncludeAbsent {
 		arguments = append(arguments, "--baseline-include-absent")
 	}
-	if opts.FailThreshold != "" {
+	if opts.FailThreshold != "" && !opts.FailOnNew {
 		arguments = append(arguments, "--fail-threshold", opts.FailThreshold)
 	}
 	if opts.GitReset && opts.Commit != "" && opts.Script == "default" {
@@ -188,11 +209,79 @@ func getIdeArgs(opts *QodanaOptions) []string {
 		for _, property := range opts.Property {
 			arguments = append(arguments, "--property="+property)
 		}
+
+		if opts.BeforeScript != "" && opts.releaseAtLeast(minScriptRelease) {
+			arguments = append(arguments, "--before-script", containerBeforeScriptPath)
+		}
+		if opts.AfterScript != "" && opts.releaseAtLeast(minScriptRelease) {
+			arguments = append(arguments, "--after-script", containerAfterScriptPath)
+		}
 	}
 
 	return arguments
 }
 
+// ParseCmdOptions reconstructs options from the command options produced by GetCmdOptions.
+// Unknown arguments are ignored.
+func ParseCmdOptions(args []string) *QodanaOptions {
+	opts := &QodanaOptions{}
+	values := map[string]*string{
+		"--source-directory": &opts.SourceDirectory,
+		"--profile-name":     &opts.ProfileName,
+		"--profile-path":     &opts.ProfilePath,
+		"--run-promo":        &opts.RunPromo,
+		"--script":           &opts.Script,
+		"--config":           &opts.ConfigPath,
+		"--baseline":         &opts.Baseline,
+		"--fail-threshold":   &opts.FailThreshold,
+		"--solution":         &opts.Solution,
+		"--project":          &opts.Project,
+		"--configuration":    &opts.Configuration,
+		"--platform":         &opts.Platform,
+		"--compile-commands": &opts.CompileCommands,
+		"--clang-args":       &opts.ClangArgs,
+		"--analysis-id":      &opts.AnalysisId,
+		"--coverage-dir":     &opts.CoverageDir,
+		"--before-script":    &opts.BeforeScript,
+		"--after-script":     &opts.AfterScript,
+	}
+	flags := map[string]*bool{
+		"--save-report":             &opts.SaveReport,
+		"--disable-sanity":          &opts.DisableSanity,
+		"--baseline-include-absent": &opts.BaselineIncludeAbsent,
+		"--apply-fixes":             &opts.ApplyFixes,
+		"--cleanup":                 &opts.Cleanup,
+		"--no-statistics":           &opts.NoStatistics,
+		"--no-build":                &opts.NoBuild,
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if property, ok := strings.CutPrefix(arg, "--property="); ok {
+			opts.Property = append(opts.Property, property)
+			continue
+		}
+		if flag, ok := flags[arg]; ok {
+			*flag = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		value := strings.Trim(args[i+1], "\"")
+		switch {
+		case arg == "--fixes-strategy":
+			opts.ApplyFixes = value == "apply"
+			opts.Cleanup = value == "cleanup"
+		case values[arg] != nil:
+			*values[arg] = value
+		default:
+			continue
+		}
+		i++
+	}
+	return opts
+}
+
 // postAnalysis post-analysis stage: wait for FUS stats to upload
 func postAnalysis(opts *QodanaOptions) {
 	syncIdeaCache(opts.ProjectDir, opts.CacheDir, true)
@@ -286,6 +375,26 @@ func findIde(dir string) string {
 	return ""
 }
 
+// validateIdeHome checks the given directory is an IDE installation that can run the analysis.
+func validateIdeHome(ideHome string) error {
+	info, err := os.Stat(ideHome)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", ideHome)
+	}
+	binDir := filepath.Join(ideHome, "bin")
+	if //goland:noinspection GoBoolExpressions
+	runtime.GOOS == "darwin" {
+		binDir = filepath.Join(ideHome, "Contents", "MacOS")
+	}
+	if findIde(binDir) == "" {
+		return fmt.Errorf("no supported IDE launcher found in %s", binDir)
+	}
+	return nil
+}
+
 // readIdeProductInfo returns IDE info from the given path.
 func readIdeProductInfo(ideDir string) map[string]interface{} {
 	if //goland:noinspection ALL
@@ -334,7 +443,11 @@ func prepareLocalIdeSettings(opts *QodanaOptions) {
 		opts.logDirPath(),
 		opts.ConfDirPath(),
 	)
-	Config = GetQodanaYaml(opts.ProjectDir)
+	if opts.ConfigPath != "" {
+		Config = *LoadQodanaYaml(opts.ConfigPath)
+	} else {
+		Config = GetQodanaYaml(opts.ProjectDir)
+	}
 	writeProperties(opts)
 
 	if IsContainer() {
@@ -343,6 +456,7 @@ func prepareLocalIdeSettings(opts *QodanaOptions) {
 	}
 
 	bootstrap(Config.Bootstrap, opts.ProjectDir)
+	opts.runBeforeScript()
 	installPlugins(Config.Plugins)
 }
 
@@ -421,12 +535,17 @@ func prepareDirectories(cacheDir string, logDir string, confDir string) {
 	}
 }
 
-// installPlugins runs plugin installer for every plugin id in qodana.yaml.
+// installPlugins runs plugin installer for every plugin id in qodana.yaml, an id listed twice is installed once.
 func installPlugins(plugins []Plugin) {
+	installed := make(map[string]bool)
 	for _, plugin := range plugins {
+		if installed[plugin.Id] {
+			continue
+		}
+		installed[plugin.Id] = true
 		log.Printf("Installing plugin %s", plugin.Id)
 		if res := RunCmd("", QuoteForWindows(Prod.IdeScript), "installPlugins", plugin.Id); res > 0 {
-			os.Exit(res)
+			Exit(res)
 		}
 	}
 }
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// imageProfilesScript prints the inspection profile files shipped with the linter image, one per line:
+// the profiles directories of the IDE distribution and of its jars are searched.
+const imageProfilesScript = `dist="${QODANA_DIST:-/opt/idea}"
+find "$dist" -path '*/profiles/*' -type f \( -name '*.xml' -o -name '*.yaml' \) 2>/dev/null
+find "$dist" -name '*.jar' 2>/dev/null | while read -r jar; do
+  unzip -Z1 "$jar" 2>/dev/null | grep -E '(^|/)profiles/[^/]+\.(xml|yaml)$'
+done`
+
+// imageProfilesTimeout limits the container run listing the profiles of the linter image.
+const imageProfilesTimeout = 2 * time.Minute
+
+// parseImageProfiles returns the sorted unique profile names of the files printed by imageProfilesScript.
+func parseImageProfiles(output string) []string {
+	unique := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		name := path.Base(strings.TrimSpace(line))
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".xml"), ".yaml")
+		if name != "" && name != "." && name != "/" {
+			unique[name] = true
+		}
+	}
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageProfilesCachePath returns the path of the profiles cached for the image with the given digest.
+func imageProfilesCachePath(digest string) string {
+	userCacheDir, _ := os.UserCacheDir()
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "profiles", strings.ReplaceAll(digest, ":", "-")+".json")
+}
+
+// cachedImageProfiles returns the profiles cached in cachePath, otherwise query is run and its result is cached.
+func cachedImageProfiles(cachePath string, query func() ([]string, error)) ([]string, error) {
+	var names []string
+	if content, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(content, &names) == nil && len(names) > 0 {
+		return names, nil
+	}
+	names, err := query()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no inspection profiles found in the image")
+	}
+	content, err := json.Marshal(names)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(cachePath), os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(cachePath, content, 0o644)
+	}
+	if err != nil {
+		WarningMessage("Could not cache the profiles of the image: %s", err)
+	}
+	return names, nil
+}
+
+// queryImageProfiles runs the image with imageProfilesScript and returns the names of the profiles it has found.
+func queryImageProfiles(docker *client.Client, image string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), imageProfilesTimeout)
+	defer cancel()
+	created, err := docker.ContainerCreate(
+		ctx,
+		&container.Config{Image: image, Entrypoint: []string{"sh", "-c", imageProfilesScript}},
+		nil,
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = docker.ContainerRemove(context.Background(), created.ID, types.ContainerRemoveOptions{Force: true})
+	}()
+	if err = docker.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return nil, err
+	}
+	statusCh, errCh := docker.ContainerWait(ctx, created.ID, container.WaitConditionNotRunning)
+	select {
+	case err = <-errCh:
+		return nil, err
+	case <-statusCh:
+	}
+	logs, err := docker.ContainerLogs(ctx, created.ID, types.ContainerLogsOptions{ShowStdout: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func(logs io.ReadCloser) {
+		_ = logs.Close()
+	}(logs)
+	var out bytes.Buffer
+	if _, err = stdcopy.StdCopy(&out, io.Discard, logs); err != nil {
+		return nil, err
+	}
+	return parseImageProfiles(out.String()), nil
+}
+
+// linterImageProfiles returns the profiles of the linter image (an alias is resolved), the image must be present
+// locally. The result is cached by the image digest, so the image is run once.
+func linterImageProfiles(linter string) ([]string, error) {
+	image, err := resolveLinter(linter)
+	if err != nil {
+		return nil, err
+	}
+	docker, err := newContainerClient()
+	if err != nil {
+		return nil, err
+	}
+	inspect, _, err := docker.ImageInspectWithRaw(context.Background(), image)
+	if err != nil {
+		return nil, fmt.Errorf("image %s is not available, pull it with qodana pull: %w", image, err)
+	}
+	return cachedImageProfiles(imageProfilesCachePath(inspect.ID), func() ([]string, error) {
+		return queryImageProfiles(docker, image)
+	})
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// linter struct represents a supported Qodana linter.
+type linter struct {
+	Code      string   `json:"code"`
+	Image     string   `json:"image"`
+	Languages []string `json:"languages"`
+	Selected  bool     `json:"selected"` // whether the linter would be auto-selected for the project
+}
+
+// codeLanguages returns the languages the linter with the given product code targets.
+func codeLanguages(code string) []string {
+	languages := make([]string, 0)
+	for language, codes := range langsProductCodes {
+		if Contains(codes, code) {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// GetLinters returns all supported linters, marking the one that would be auto-selected for projectDir.
+func GetLinters(projectDir string) []linter {
+	var selected string
+	if languages := detectLanguages(projectDir); len(languages) > 0 {
+		selected = SelectAnalyzer(projectDir, rankAnalyzers(languages), false, nil)
+	}
+	linters := make([]linter, 0, len(AllCodes))
+	for _, code := range AllCodes {
+		image := Image(code)
+		linters = append(linters, linter{
+			Code:      code,
+			Image:     image,
+			Languages: codeLanguages(code),
+			Selected:  selected != "" && (selected == image || selected == code),
+		})
+	}
+	return linters
+}
+
+// LintersToJSON returns the JSON representation of the list of linters.
+func LintersToJSON(linters []linter) (string, error) {
+	out, err := json.MarshalIndent(linters, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return string(out), nil
+}
+
+// linterAliases returns short linter aliases (e.g. jvm, python) mapped to product codes, derived from DockerImageMap.
+func linterAliases() map[string]string {
+	aliases := make(map[string]string, len(DockerImageMap))
+	for code, image := range DockerImageMap {
+		alias := strings.TrimSuffix(strings.TrimPrefix(image, officialImagePrefix+"-"), ":")
+		aliases[alias] = code
+	}
+	return aliases
+}
+
+// resolveLinter returns the image for the given linter alias or an unambiguous alias prefix.
+// Linter values that look like image names (containing '/' or ':') are returned unchanged.
+func resolveLinter(linter string) (string, error) {
+	if strings.ContainsAny(linter, "/:") {
+		return linter, nil
+	}
+	aliases := linterAliases()
+	valid := make([]string, 0, len(aliases))
+	for a := range aliases {
+		valid = append(valid, a)
+	}
+	sort.Strings(valid)
+
+	alias := strings.TrimPrefix(lower(linter), "qodana-")
+	if code, ok := aliases[alias]; ok {
+		return Image(code), nil
+	}
+	var matches []string
+	for _, a := range valid {
+		if strings.HasPrefix(a, alias) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown linter %s, use a full image name or one of the aliases: %s", linter, strings.Join(valid, ", "))
+	case 1:
+		return Image(aliases[matches[0]]), nil
+	default:
+		return "", fmt.Errorf("ambiguous linter %s matches %s, use one of the aliases: %s", linter, strings.Join(matches, ", "), strings.Join(valid, ", "))
+	}
+}
+
+// LinterCompletions returns the linter aliases starting with the given prefix, each with its image as the description,
+// for the shell completion of --linter.
+func LinterCompletions(prefix string) []string {
+	aliases := linterAliases()
+	completions := make([]string, 0, len(aliases))
+	for alias, code := range aliases {
+		if strings.HasPrefix(alias, prefix) {
+			completions = append(completions, alias+"\t"+Image(code))
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
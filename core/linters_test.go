@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestResolveLinter(t *testing.T) {
+	tests := []struct {
+		name     string
+		linter   string
+		expected string
+		wantErr  bool
+	}{
+		{"Full image name", "jetbrains/qodana-jvm-community:2021.3", "jetbrains/qodana-jvm-community:2021.3", false},
+		{"Custom registry image", "registry.jetbrains.team/p/sa/containers/qodana-php:latest", "registry.jetbrains.team/p/sa/containers/qodana-php:latest", false},
+		{"Exact alias", "jvm", Image(QDJVM), false},
+		{"Alias with dash", "python-community", Image(QDPYC), false},
+		{"Alias is case-insensitive", "PHP", Image(QDPHP), false},
+		{"Alias with qodana- prefix", "qodana-js", Image(QDJS), false},
+		{"Unambiguous prefix", "cd", Image(QDNETC), false},
+		{"Ambiguous prefix", "jvm-", "", true},
+		{"Unknown alias", "cobol", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLinter(tt.linter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveLinter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("resolveLinter() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LogFormatText is the default human-readable output.
+	LogFormatText = "text"
+	// LogFormatJSON prints every log line and message as a JSON object with timestamp, level and message fields.
+	LogFormatJSON = "json"
+)
+
+// LogLevels are the documented --log-level values, the other logrus levels are accepted too.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+var (
+	// Quiet flag to print only errors: success and warning messages, progress and linter output are skipped.
+	Quiet = false
+
+	// jsonOutput is set by --log-format json, messages are printed with messageLogger then.
+	jsonOutput = false
+
+	// messageLogger prints the CLI messages and the linter output as JSON objects to stdout.
+	messageLogger = &log.Logger{Out: os.Stdout, Formatter: jsonFormatter(), Hooks: make(log.LevelHooks), Level: log.InfoLevel}
+)
+
+// jsonFormatter returns the formatter of the JSON log lines.
+func jsonFormatter() *log.JSONFormatter {
+	return &log.JSONFormatter{
+		TimestampFormat: time.RFC3339Nano,
+		FieldMap: log.FieldMap{
+			log.FieldKeyTime:  "timestamp",
+			log.FieldKeyLevel: "level",
+			log.FieldKeyMsg:   "message",
+		},
+	}
+}
+
+// SetLogLevel applies the --log-level value, Quiet forces the error level.
+func SetLogLevel(level string) error {
+	if Quiet {
+		level = log.ErrorLevel.String()
+	}
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q, available levels are: %s", level, strings.Join(LogLevels, ", "))
+	}
+	log.SetLevel(logLevel)
+	if Quiet {
+		messageLogger.SetLevel(log.ErrorLevel)
+	}
+	return nil
+}
+
+// SetLogFormat applies the --log-format value: text or json.
+func SetLogFormat(format string) error {
+	switch format {
+	case LogFormatText, "":
+		jsonOutput = false
+		log.SetFormatter(&log.TextFormatter{})
+	case LogFormatJSON:
+		jsonOutput = true
+		log.SetFormatter(jsonFormatter())
+		DisableColor()
+	default:
+		return fmt.Errorf("invalid log format %q, available formats are: %s, %s", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// linterLogWriter forwards the process output line by line as JSON log objects.
+type linterLogWriter struct {
+	stream string
+	buf    []byte
+}
+
+func (w *linterLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line if it was not terminated by a newline.
+func (w *linterLogWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *linterLogWriter) log(line string) {
+	messageLogger.WithFields(log.Fields{"source": "linter", "stream": w.stream}).Info(strings.TrimRight(line, "\r"))
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMarkdownRows is the default maximum number of problems listed in the Markdown summary.
+const DefaultMarkdownRows = 50
+
+// severityOrder is the order of severity groups in the Markdown summary, unknown severities go last.
+var severityOrder = []string{"critical", "high", "error", "moderate", "warning", "low", "note", "info", "none"}
+
+// severityRank returns the position of the severity in severityOrder.
+func severityRank(severity string) int {
+	for i, s := range severityOrder {
+		if lower(severity) == s {
+			return i
+		}
+	}
+	return len(severityOrder)
+}
+
+// escapeMarkdownCell makes the text safe to put into a Markdown table cell.
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>").Replace(s)
+}
+
+// markdownLocation returns the file:line link to the problem location.
+func markdownLocation(p Result) string {
+	if p.File == "" {
+		return "—"
+	}
+	if p.Line > 0 {
+		return fmt.Sprintf("[%s:%d](%s#L%d)", p.File, p.Line, p.File, p.Line)
+	}
+	return fmt.Sprintf("[%s](%s)", p.File, p.File)
+}
+
+// problemsToMarkdown renders new problems as a collapsible Markdown summary grouped by severity,
+// at most maxRows problems are listed (no limit if maxRows is negative).
+func problemsToMarkdown(problems []Result, maxRows int) string {
+	newProblems := make([]Result, 0)
+	for _, p := range problems {
+		if isNewResult(p) {
+			newProblems = append(newProblems, p)
+		}
+	}
+	var b strings.Builder
+	if len(newProblems) == 0 {
+		b.WriteString("**Qodana found no new problems** :tada:\n")
+		return b.String()
+	}
+	sort.SliceStable(newProblems, func(i, j int) bool {
+		return severityRank(newProblems[i].Severity) < severityRank(newProblems[j].Severity)
+	})
+
+	counts := make([]string, 0)
+	groups := make([]string, 0)
+	groupCounts := make(map[string]int)
+	for _, p := range newProblems {
+		if groupCounts[p.Severity] == 0 {
+			groups = append(groups, p.Severity)
+		}
+		groupCounts[p.Severity]++
+	}
+	for _, severity := range groups {
+		counts = append(counts, fmt.Sprintf("%d %s", groupCounts[severity], severity))
+	}
+
+	b.WriteString("<details>\n")
+	b.WriteString(fmt.Sprintf(
+		"<summary>Qodana found %s: %s</summary>\n",
+		pluralize(len(newProblems), "new problem", "new problems"),
+		strings.Join(counts, ", "),
+	))
+
+	shown := 0
+	for _, severity := range groups {
+		if maxRows >= 0 && shown >= maxRows {
+			break
+		}
+		b.WriteString(fmt.Sprintf("\n#### %s (%d)\n\n", severity, groupCounts[severity]))
+		b.WriteString("| Rule | Location | Message |\n")
+		b.WriteString("|------|----------|---------|\n")
+		for _, p := range newProblems {
+			if p.Severity != severity {
+				continue
+			}
+			if maxRows >= 0 && shown >= maxRows {
+				break
+			}
+			b.WriteString(fmt.Sprintf(
+				"| `%s` | %s | %s |\n",
+				p.RuleId,
+				markdownLocation(p),
+				escapeMarkdownCell(p.Message),
+			))
+			shown++
+		}
+	}
+	if truncated := len(newProblems) - shown; truncated > 0 {
+		b.WriteString(fmt.Sprintf("\n_%s not shown, see the full Qodana report._\n", pluralize(truncated, "more problem is", "more problems are")))
+	}
+	b.WriteString("</details>\n")
+	return b.String()
+}
+
+// SarifToMarkdown returns the Markdown summary of the new problems found in the given SARIF file, suitable for a PR comment.
+// Only the problems matching the filter are summarized, the number of the problems hidden by the filter is returned too.
+func SarifToMarkdown(sarifPath string, maxRows int, filter ProblemFilter) (string, int, error) {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return "", 0, err
+	}
+	problems, hidden := filter.apply(problems)
+	return problemsToMarkdown(problems, maxRows), hidden, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemsToMarkdown(t *testing.T) {
+	problems := []Result{
+		{RuleId: "PyUnusedLocal", Severity: "Moderate", Message: "Unused variable", Location: Location{File: "main.py", Line: 3}},
+		{RuleId: "PyTypeChecker", Severity: "High", Message: "Wrong | type", Location: Location{File: "util.py", Line: 10}},
+		{RuleId: "PyOld", Severity: "Critical", Message: "Old problem", Location: Location{File: "old.py", Line: 1}, BaselineState: baselineStateUnchanged},
+		{RuleId: "ProjectLevel", Severity: "Moderate", Message: "Project problem"},
+	}
+
+	tests := []struct {
+		name     string
+		maxRows  int
+		expected string
+	}{
+		{
+			"All rows",
+			-1,
+			`<details>
+<summary>Qodana found 3 new problems: 1 High, 2 Moderate</summary>
+
+#### High (1)
+
+| Rule | Location | Message |
+|------|----------|---------|
+| ` + "`PyTypeChecker`" + ` | [util.py:10](util.py#L10) | Wrong \| type |
+
+#### Moderate (2)
+
+| Rule | Location | Message |
+|------|----------|---------|
+| ` + "`PyUnusedLocal`" + ` | [main.py:3](main.py#L3) | Unused variable |
+| ` + "`ProjectLevel`" + ` | — | Project problem |
+</details>
+`,
+		},
+		{
+			"Truncated",
+			1,
+			`<details>
+<summary>Qodana found 3 new problems: 1 High, 2 Moderate</summary>
+
+#### High (1)
+
+| Rule | Location | Message |
+|------|----------|---------|
+| ` + "`PyTypeChecker`" + ` | [util.py:10](util.py#L10) | Wrong \| type |
+
+_2 more problems are not shown, see the full Qodana report._
+</details>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, problemsToMarkdown(problems, tt.maxRows))
+		})
+	}
+
+	assert.Equal(t, "**Qodana found no new problems** :tada:\n", problemsToMarkdown(problems[2:3], -1))
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+)
+
+const maskedValue = "***"
+
+var (
+	// DisableMasking flag to print secrets as is in the logged commands
+	DisableMasking = false
+
+	// secretKeyParts are the key parts that mark the value as secret.
+	secretKeyParts = []string{"TOKEN", "SECRET", "PASSWORD"}
+)
+
+// isSecretKey returns true if the environment variable, property or flag name looks like it holds a secret.
+func isSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecret masks the value of a KEY=VALUE pair, --property=KEY=VALUE and -DKEY=VALUE are supported too.
+func maskSecret(arg string) string {
+	prefix := ""
+	for _, p := range []string{"--property=", "-D"} {
+		if strings.HasPrefix(arg, p) {
+			prefix = p
+			break
+		}
+	}
+	key, _, found := strings.Cut(strings.TrimPrefix(arg, prefix), "=")
+	if !found || !isSecretKey(key) {
+		return maskProxyEnv(arg)
+	}
+	return prefix + key + "=" + maskedValue
+}
+
+// maskSecrets returns a copy of the command arguments with secret values masked, so the command can be logged.
+// The values of flags like --token are masked as well. Nothing is masked if DisableMasking is set.
+func maskSecrets(args []string) []string {
+	masked := make([]string, len(args))
+	copy(masked, args)
+	if DisableMasking {
+		return masked
+	}
+	for i, arg := range masked {
+		if i > 0 && strings.HasPrefix(masked[i-1], "-") && !strings.Contains(masked[i-1], "=") && isSecretKey(masked[i-1]) {
+			masked[i] = maskedValue
+			continue
+		}
+		masked[i] = maskSecret(arg)
+	}
+	return masked
+}
+
+// masked returns a copy of the options with the tokens and secret environment variables and properties masked, so it can be logged.
+func (o *QodanaOptions) masked() QodanaOptions {
+	masked := *o
+	masked.Env = maskSecrets(o.Env)
+	masked.Property = maskSecrets(o.Property)
+	if DisableMasking {
+		return masked
+	}
+	for _, token := range []*string{&masked.LicenseToken, &masked.RepoToken} {
+		if *token != "" {
+			*token = maskedValue
+		}
+	}
+	if masked.ReportUploadUrl != "" {
+		masked.ReportUploadUrl = redactUploadUrl(masked.ReportUploadUrl)
+	}
+	return masked
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/owenrumney/go-sarif/v2/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+// mergeInputPath returns the SARIF report of the results directory, other paths are returned as is.
+func mergeInputPath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, QodanaSarifName)
+	}
+	return path
+}
+
+// MergeSarif combines the SARIF reports of the given files or results directories into one report with the runs
+// of all of them, the tool metadata of each run is kept. Results identical to the results of the previous runs
+// are dropped, the number of dropped results is returned.
+func MergeSarif(inputs []string, output string) (int, error) {
+	merged, err := sarif.New(sarif.Version210)
+	if err != nil {
+		return 0, err
+	}
+	outputPath, _ := filepath.Abs(output)
+	seen := make(map[Result]bool)
+	duplicates := 0
+	for _, input := range inputs {
+		path := mergeInputPath(input)
+		if inputPath, _ := filepath.Abs(path); inputPath == outputPath {
+			return 0, fmt.Errorf("the output %s is one of the merged reports", output)
+		}
+		s, err := sarif.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		checkSarifVersion(path, s)
+		for _, run := range s.Runs {
+			if run == nil {
+				continue
+			}
+			results := make([]*sarif.Result, 0, len(run.Results))
+			for _, result := range run.Results {
+				if result == nil {
+					continue
+				}
+				if key := newResult(result); seen[key] {
+					duplicates++
+				} else {
+					seen[key] = true
+					results = append(results, result)
+				}
+			}
+			run.Results = results
+			merged.AddRun(run)
+		}
+		log.Debugf("Merged %d runs of %s", len(s.Runs), path)
+	}
+	out, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return duplicates, os.WriteFile(output, out, 0o644)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSarif(t *testing.T) {
+	shared := Result{RuleId: "JSUnusedLocalSymbols", Severity: "High", Message: "Unused variable", Location: Location{File: "web/app.js", Line: 3, Column: 5}, Fingerprint: "1"}
+	jvm := Result{RuleId: "ConstantValue", Severity: "Moderate", Message: "Condition is always true", Location: Location{File: "src/Main.java", Line: 10, Column: 1}, Fingerprint: "2"}
+	js := Result{RuleId: "JSUnresolvedReference", Severity: "High", Message: "Unresolved variable", Location: Location{File: "web/app.js", Line: 7, Column: 2}, Fingerprint: "3"}
+
+	jvmDir := filepath.Join(t.TempDir(), "jvm")
+	jsDir := filepath.Join(t.TempDir(), "js")
+	for _, dir := range []string{jvmDir, jsDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestSarif(t, jvmDir, []Result{jvm, shared})
+	jsPath := writeTestSarif(t, jsDir, []Result{shared, js})
+	output := filepath.Join(t.TempDir(), QodanaSarifName)
+
+	duplicates, err := MergeSarif([]string{jvmDir, jsPath}, output)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, duplicates)
+	report, err := ParseSarif(output)
+	assert.NoError(t, err)
+	assert.Equal(t, []Run{
+		{Tool: "QDTEST", Results: []Result{jvm, shared}},
+		{Tool: "QDTEST", Results: []Result{js}},
+	}, report.Runs)
+
+	_, err = MergeSarif([]string{jvmDir, jsDir}, jsPath)
+	assert.Error(t, err, "the merged reports are not overwritten")
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QodanaMetricsName is the name of the run metrics file saved to the results directory.
+const QodanaMetricsName = "qodana-metrics.json"
+
+// scanMetrics is the run-level metadata of a scan saved as JSON for dashboards.
+type scanMetrics struct {
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Duration      float64        `json:"durationSeconds"`
+	Linter        string         `json:"linter"`
+	TotalProblems int            `json:"totalProblems"`
+	Problems      map[string]int `json:"problemsBySeverity"`
+	Suppressed    int            `json:"suppressedProblems"`
+	ExitCode      int            `json:"exitCode"`
+}
+
+// metricsPath returns the path to save the run metrics to, the results directory is used by default.
+func (o *QodanaOptions) metricsPath() string {
+	if o.MetricsFile != "" {
+		return o.MetricsFile
+	}
+	return filepath.Join(o.ResultsDir, QodanaMetricsName)
+}
+
+// analyzerName returns the linter image or, for native runs, the IDE the analysis is run with.
+func (o *QodanaOptions) analyzerName() string {
+	if o.Linter != "" {
+		return o.Linter
+	}
+	return o.Ide
+}
+
+// newScanMetrics collects the run metrics, the problems are counted if the SARIF report exists.
+// The problems absent in the current run compared to the baseline are not counted, the suppressed ones are counted separately.
+func newScanMetrics(o *QodanaOptions, start time.Time, end time.Time, exitCode int) (scanMetrics, error) {
+	metrics := scanMetrics{
+		Start:    start.UTC(),
+		End:      end.UTC(),
+		Duration: end.Sub(start).Round(time.Millisecond).Seconds(),
+		Linter:   o.analyzerName(),
+		Problems: map[string]int{},
+		ExitCode: exitCode,
+	}
+	sarifPath := filepath.Join(o.ResultsDir, QodanaSarifName)
+	if _, err := os.Stat(sarifPath); errors.Is(err, os.ErrNotExist) {
+		return metrics, nil
+	}
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		return metrics, err
+	}
+	metrics.Suppressed = report.Suppressed()
+	for _, p := range report.Results() {
+		if p.BaselineState == baselineStateAbsent {
+			continue
+		}
+		metrics.TotalProblems++
+		metrics.Problems[p.Severity]++
+	}
+	return metrics, nil
+}
+
+// WriteScanMetrics saves the scan start and end time, duration, linter, problem counts by severity and exit code
+// as JSON and returns the path of the written file.
+func WriteScanMetrics(o *QodanaOptions, start time.Time, end time.Time, exitCode int) (string, error) {
+	metrics, err := newScanMetrics(o, start, end, exitCode)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := o.metricsPath()
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, out, 0o644)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteScanMetrics(t *testing.T) {
+	resultsDir := t.TempDir()
+	writeTestSarif(t, resultsDir, []Result{
+		{RuleId: "A", Level: LevelError, Severity: "High", Message: "a"},
+		{RuleId: "B", Level: LevelWarning, Severity: "Moderate", Message: "b", BaselineState: baselineStateUnchanged},
+		{RuleId: "C", Level: LevelWarning, Severity: "Moderate", Message: "c"},
+		{RuleId: "D", Level: LevelNote, Severity: "Low", Message: "d", BaselineState: baselineStateAbsent},
+	})
+	start := time.Date(2023, 5, 4, 10, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+	options := &QodanaOptions{ResultsDir: resultsDir, Linter: "jetbrains/qodana-jvm:2023.2"}
+
+	path, err := WriteScanMetrics(options, start, end, QodanaFailThresholdExitCode)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(resultsDir, QodanaMetricsName), path)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var metrics scanMetrics
+	assert.NoError(t, json.Unmarshal(content, &metrics))
+	assert.Equal(t, scanMetrics{
+		Start:         start,
+		End:           end,
+		Duration:      90,
+		Linter:        "jetbrains/qodana-jvm:2023.2",
+		TotalProblems: 3,
+		Problems:      map[string]int{"High": 1, "Moderate": 2},
+		ExitCode:      QodanaFailThresholdExitCode,
+	}, metrics)
+
+	options = &QodanaOptions{ResultsDir: t.TempDir(), Ide: "QDNET", MetricsFile: filepath.Join(t.TempDir(), "metrics", "run.json")}
+	path, err = WriteScanMetrics(options, start, end, QodanaOutOfMemoryExitCode)
+	assert.NoError(t, err)
+	assert.Equal(t, options.MetricsFile, path)
+	metrics, err = newScanMetrics(options, start, end, QodanaOutOfMemoryExitCode)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, metrics.TotalProblems)
+	assert.Equal(t, "QDNET", metrics.Linter)
+}
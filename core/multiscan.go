@@ -0,0 +1,281 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultScanJobs is the default number of linters a multi-linter scan runs at a time: a linter takes several CPUs
+// and a few gigabytes of memory, so a run is started per four CPUs.
+var DefaultScanJobs = max(runtime.NumCPU()/4, 1)
+
+// linterScan is the outcome of one linter of a multi-linter scan.
+type linterScan struct {
+	Linter     string
+	ResultsDir string
+	Problems   int
+	ExitCode   int
+	Err        error
+}
+
+// ScanLinters runs the analysis with each of the given linters, at most jobs at a time, and prints the summary of
+// the runs. The images are pulled beforehand, jobs at a time as well. It returns the worst exit code of the runs.
+func ScanLinters(ctx context.Context, options *QodanaOptions, linters []string, jobs int) int {
+	runs, err := options.linterRuns(linters)
+	if err != nil {
+		options.exitWithError(err)
+	}
+	for _, run := range runs {
+		OnExit(run.CleanupTmpResults)
+		defer run.CleanupTmpResults()
+	}
+	if err = CheckContainerEngine(); err != nil {
+		options.exitWithError(err)
+	}
+	docker, err := newContainerClient()
+	if err != nil {
+		options.exitWithError(err)
+	}
+	images, err := options.imagesToPull(runs, func(image string) (bool, error) {
+		return isImagePresent(docker, image)
+	})
+	if err != nil {
+		options.exitWithError(err)
+	}
+	pullFailed := map[string]bool{}
+	if len(images) > 0 {
+		for _, image := range PullImages(docker, images, options.PullRetries, jobs) {
+			pullFailed[image] = true
+		}
+	}
+	scans := scanLinters(runs, jobs, func(run *QodanaOptions) linterScan {
+		if pullFailed[run.Linter] {
+			return linterScan{
+				Linter:     run.Linter,
+				ResultsDir: run.ResultsDir,
+				ExitCode:   QodanaDockerErrorExitCode,
+				Err:        fmt.Errorf("%w %s", ErrImagePull, run.Linter),
+			}
+		}
+		return scanLinter(ctx, run)
+	})
+	printLinterScans(scans)
+	codes := make([]int, len(scans))
+	for i, scan := range scans {
+		codes[i] = scan.ExitCode
+	}
+	return worstExitCode(codes)
+}
+
+// linterRuns resolves the options of every linter of a multi-linter scan, the configuration problems are returned
+// as errors matching ErrInvalidConfig.
+func (o *QodanaOptions) linterRuns(linters []string) ([]*QodanaOptions, error) {
+	runs := make([]*QodanaOptions, 0, len(linters))
+	names := map[string]string{}
+	for _, linter := range linters {
+		run, err := o.forLinter(linter)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[run.parallelName]; ok {
+			return nil, configErrorf("Linters %s and %s can't be scanned together: they share the results directory name %s", other, run.Linter, run.parallelName)
+		}
+		names[run.parallelName] = run.Linter
+		if err = run.ResolveAnalyzerSettings(); err != nil {
+			return nil, fmt.Errorf("%s: %w", run.Linter, err)
+		}
+		if err = run.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", run.Linter, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// forLinter returns a copy of the options to run the given linter along with the other linters of a multi-linter
+// scan: the results, cache and report directories passed explicitly get a subdirectory named after the linter.
+func (o *QodanaOptions) forLinter(linter string) (*QodanaOptions, error) {
+	resolved, err := resolveLinter(linter)
+	if err != nil {
+		return nil, configErrorf("%s", err)
+	}
+	run := *o
+	run.Linter = resolved
+	run._id = ""
+	run.parallelName = linterDirName(resolved)
+	// the resolution appends to the slices, the runs must not share them
+	for _, values := range []*[]string{
+		&run.IncludeDirs,
+		&run.Excludes,
+		&run.Files,
+		&run.Property,
+		&run.Env,
+		&run.Volumes,
+		&run.DockerArgs,
+		&run.ReportUploadHeaders,
+	} {
+		*values = append([]string(nil), *values...)
+	}
+	for _, dir := range []*string{&run.ResultsDir, &run.CacheDir, &run.ReportDir} {
+		if *dir != "" {
+			*dir = filepath.Join(*dir, run.parallelName)
+		}
+	}
+	return &run, nil
+}
+
+// linterDirName returns the image name without the registry, the namespace and the tag, e.g. qodana-jvm.
+func linterDirName(image string) string {
+	name := path.Base(image)
+	if i := strings.IndexAny(name, ":@"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// imagesToPull returns the images of the runs to pull before the analyses according to the pull policy, the runs are
+// then set to use the present images. With the never policy nothing is pulled, the runs report the missing images.
+func (o *QodanaOptions) imagesToPull(runs []*QodanaOptions, isPresent func(image string) (bool, error)) ([]string, error) {
+	pullPolicy := o.PullPolicy
+	if o.SkipPull {
+		pullPolicy = PullPolicyNever
+	}
+	images := make([]string, 0, len(runs))
+	for _, run := range runs {
+		switch pullPolicy {
+		case PullPolicyAlways:
+			images = append(images, run.Linter)
+		case PullPolicyMissing, "":
+			present, err := isPresent(run.Linter)
+			if err != nil {
+				return nil, err
+			}
+			if !present {
+				images = append(images, run.Linter)
+			}
+		case PullPolicyNever:
+		default:
+			return nil, configErrorf(
+				"Unknown pull policy %s, available values are: %s, %s, %s",
+				pullPolicy,
+				PullPolicyAlways,
+				PullPolicyMissing,
+				PullPolicyNever,
+			)
+		}
+		run.SkipPull = false
+		run.PullPolicy = PullPolicyNever
+	}
+	return images, nil
+}
+
+// scanLinters runs scan for every run, at most jobs at a time, the outcomes are returned in the order of the runs.
+func scanLinters(runs []*QodanaOptions, jobs int, scan func(run *QodanaOptions) linterScan) []linterScan {
+	jobs = max(jobs, 1)
+	SuccessMessage("Scanning with %s (%d at a time)", pluralize(len(runs), "linter", "linters"), jobs)
+	scans := make([]linterScan, len(runs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	slots := make(chan struct{}, jobs)
+	done := 0
+	for i, run := range runs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, run *QodanaOptions) {
+			defer wg.Done()
+			scans[i] = scan(run)
+			<-slots
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			SuccessMessage("[%d/%d] %s finished with exit code %d", done, len(runs), run.Linter, scans[i].ExitCode)
+		}(i, run)
+	}
+	wg.Wait()
+	return scans
+}
+
+// scanLinter runs the analysis of one linter of a multi-linter scan, checks its quality gate and saves its metrics.
+func scanLinter(ctx context.Context, run *QodanaOptions) linterScan {
+	start := time.Now()
+	scan := linterScan{Linter: run.Linter, ResultsDir: run.ResultsDir}
+	exitCode, err := Analyze(ctx, run)
+	if err != nil {
+		scan.ExitCode = errorExitCode(err)
+		scan.Err = err
+		return scan
+	}
+	sarifPath := filepath.Join(run.ResultsDir, QodanaSarifName)
+	exitCode = SuppressionsExitCode(exitCode, sarifPath, run)
+	if run.FailOnNew {
+		exitCode = FailOnNewExitCode(exitCode, sarifPath, run)
+	} else {
+		exitCode = ThresholdExitCode(exitCode, sarifPath, run)
+	}
+	exitCode = MaxProblemsExitCode(exitCode, sarifPath, run)
+	switch exitCode {
+	case QodanaSuccessExitCode, QodanaFailThresholdExitCode:
+	case QodanaTimeoutExitCodePlaceholder:
+		exitCode = run.AnalysisTimeoutExitCode
+		scan.Err = fmt.Errorf("analysis reached timeout %s", run.GetAnalysisTimeout())
+	default:
+		scan.Err = AnalysisError{exitCode}
+	}
+	scan.ExitCode = exitCode
+	metrics, err := newScanMetrics(run, start, time.Now(), exitCode)
+	if err != nil {
+		log.Debugf("Could not count the problems of %s: %s", run.Linter, err)
+	}
+	scan.Problems = metrics.TotalProblems
+	EmitDoneEvent(run, exitCode)
+	if _, err = WriteScanMetrics(run, start, time.Now(), exitCode); err != nil {
+		WarningMessage("Could not save the run metrics of %s: %s", run.Linter, err)
+	}
+	return scan
+}
+
+// worstExitCode returns the exit code of a multi-linter scan: a failed run outweighs an exceeded quality gate, which
+// outweighs a success, the highest code wins among the failed runs.
+func worstExitCode(codes []int) int {
+	rank := func(code int) int {
+		switch code {
+		case QodanaSuccessExitCode:
+			return 0
+		case QodanaFailThresholdExitCode:
+			return 1
+		default:
+			return 2
+		}
+	}
+	worst := QodanaSuccessExitCode
+	for _, code := range codes {
+		if rank(code) > rank(worst) || (rank(code) == rank(worst) && code > worst) {
+			worst = code
+		}
+	}
+	return worst
+}
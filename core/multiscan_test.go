@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorstExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		codes    []int
+		expected int
+	}{
+		{nil, QodanaSuccessExitCode},
+		{[]int{0, 0}, QodanaSuccessExitCode},
+		{[]int{0, 255}, QodanaFailThresholdExitCode},
+		{[]int{255, 1, 0}, 1},
+		{[]int{3, 255, 1}, 3},
+		{[]int{137, 255, 3}, 137},
+	} {
+		assert.Equal(t, tc.expected, worstExitCode(tc.codes), "%v", tc.codes)
+	}
+}
+
+func TestLinterDirName(t *testing.T) {
+	assert.Equal(t, "qodana-jvm", linterDirName("jetbrains/qodana-jvm:2023.3"))
+	assert.Equal(t, "qodana-python", linterDirName("registry.example.com:5000/jetbrains/qodana-python"))
+	assert.Equal(t, "qodana-go", linterDirName("jetbrains/qodana-go@sha256:0123"))
+}
+
+func TestForLinter(t *testing.T) {
+	results := filepath.Join(t.TempDir(), "results")
+	opts := &QodanaOptions{ResultsDir: results, Excludes: make([]string, 1, 8), _id: "shared"}
+	opts.Excludes[0] = "node_modules"
+
+	jvm, err := opts.forLinter("jetbrains/qodana-jvm:2023.3")
+	assert.NoError(t, err)
+	python, err := opts.forLinter("jetbrains/qodana-python:2023.3")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(results, "qodana-jvm"), jvm.ResultsDir)
+	assert.Equal(t, filepath.Join(results, "qodana-python"), python.ResultsDir)
+	assert.Empty(t, jvm.CacheDir)
+	assert.NotEqual(t, jvm.id(), python.id())
+
+	jvm.Excludes = append(jvm.Excludes, "build")
+	python.Excludes = append(python.Excludes, "venv")
+	assert.Equal(t, []string{"node_modules", "build"}, jvm.Excludes)
+	assert.Equal(t, []string{"node_modules", "venv"}, python.Excludes)
+	assert.Equal(t, results, opts.ResultsDir)
+
+	_, err = opts.forLinter("unknown")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestLinterRunsNameClash(t *testing.T) {
+	opts := &QodanaOptions{ProjectDir: t.TempDir()}
+	_, err := opts.linterRuns([]string{"jetbrains/qodana-jvm:2023.2", "jetbrains/qodana-jvm:2023.3"})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestImagesToPull(t *testing.T) {
+	present := map[string]bool{"jetbrains/qodana-jvm:2023.3": true}
+	isPresent := func(image string) (bool, error) { return present[image], nil }
+	newRuns := func() []*QodanaOptions {
+		return []*QodanaOptions{{Linter: "jetbrains/qodana-jvm:2023.3"}, {Linter: "jetbrains/qodana-python:2023.3"}}
+	}
+	for _, tc := range []struct {
+		options  QodanaOptions
+		expected []string
+	}{
+		{QodanaOptions{PullPolicy: PullPolicyMissing}, []string{"jetbrains/qodana-python:2023.3"}},
+		{QodanaOptions{PullPolicy: PullPolicyAlways}, []string{"jetbrains/qodana-jvm:2023.3", "jetbrains/qodana-python:2023.3"}},
+		{QodanaOptions{PullPolicy: PullPolicyNever}, []string{}},
+		{QodanaOptions{SkipPull: true}, []string{}},
+	} {
+		runs := newRuns()
+		images, err := tc.options.imagesToPull(runs, isPresent)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, images)
+		for _, run := range runs {
+			assert.Equal(t, PullPolicyNever, run.PullPolicy)
+		}
+	}
+
+	_, err := (&QodanaOptions{PullPolicy: "sometimes"}).imagesToPull(newRuns(), isPresent)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = (&QodanaOptions{}).imagesToPull(newRuns(), func(string) (bool, error) { return false, errors.New("engine is down") })
+	assert.Error(t, err)
+}
+
+func TestScanLintersJobs(t *testing.T) {
+	runs := make([]*QodanaOptions, 0)
+	for _, linter := range []string{"jvm", "python", "go", "php", "js"} {
+		runs = append(runs, &QodanaOptions{Linter: "jetbrains/qodana-" + linter + ":2023.3"})
+	}
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	scans := scanLinters(runs, 2, func(run *QodanaOptions) linterScan {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if run.Linter == "jetbrains/qodana-go:2023.3" {
+			return linterScan{Linter: run.Linter, ExitCode: 1, Err: AnalysisError{1}}
+		}
+		return linterScan{Linter: run.Linter}
+	})
+	assert.Equal(t, 2, maxRunning)
+	assert.Len(t, scans, len(runs))
+	for i, scan := range scans {
+		assert.Equal(t, runs[i].Linter, scan.Linter)
+	}
+	assert.ErrorIs(t, scans[2].Err, ErrAnalysisFailed)
+}
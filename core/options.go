@@ -17,11 +17,13 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -31,41 +33,110 @@ type QodanaOptions struct {
 	ResultsDir              string
 	CacheDir                string
 	ProjectDir              string
+	Repo                    string
+	RepoBranch              string
+	RepoToken               string
+	LicenseToken            string
 	ReportDir               string
 	CoverageDir             string
 	Linter                  string
 	Ide                     string
 	SourceDirectory         string
+	IncludeDirs             []string
+	Excludes                []string
+	Files                   []string
 	DisableSanity           bool
 	ProfileName             string
 	ProfilePath             string
+	InspectRule             string
 	RunPromo                string
 	StubProfile             string // note: deprecated option
 	Baseline                string
+	BaselineDir             string
 	BaselineIncludeAbsent   bool
+	FailOnNew               bool
+	BaselineGenerate        string
+	BaselineWriteOnPass     bool
+	SarifOut                string
+	MaxFiles                int
+	Force                   bool
 	SaveReport              bool
 	ShowReport              bool
 	Port                    int
+	OpenIn                  string
+	WaitForReport           bool
 	Property                []string
+	PropertyFile            string
+	Suppressions            string
 	Script                  string
+	BeforeScript            string
+	AfterScript             string
 	FailThreshold           string
+	FailThresholdError      int
+	FailThresholdWarning    int
+	FailThresholdNote       int
+	MaxProblems             int
 	Commit                  string
+	ChangesSinceBranch      string
+	Since                   string
 	AnalysisId              string
 	Env                     []string
+	EnvFile                 string
 	Volumes                 []string
+	AllowYamlVolumes        bool
+	DockerArgs              []string
+	Memory                  string
+	Cpus                    float64
 	User                    string
 	PrintProblems           bool
+	SortBy                  string
+	GroupBy                 string
+	NoSummary               bool
+	TeamCity                bool
+	GitHubAnnotations       bool
+	GitLabReport            string
+	MetricsFile             string
+	JsonEvents              bool
+	ReportUploadUrl         string
+	ReportUploadHeaders     []string
 	SkipPull                bool
+	PullPolicy              string
+	PullRetries             int
+	Registry                string
+	StrictCompat            bool
 	ClearCache              bool
+	CacheReport             bool
+	CacheMaxAge             int
+	CacheKey                string
+	MountCacheAsReadonly    bool
+	NoChown                 bool
+	DryRun                  bool
+	IgnoreExitCode          bool
+	StrictErrors            bool
+	CleanResults            bool
+	TmpResults              bool
+	KeepTmp                 bool
 	YamlName                string
+	ConfigPath              string
+	NoAutoLinter            bool
+	HttpProxy               string
+	HttpsProxy              string
+	NoProxy                 string
 	GitReset                bool
 	FullHistory             bool
 	ApplyFixes              bool
 	Cleanup                 bool
 	FixesStrategy           string // note: deprecated option
 	_id                     string
-	NoStatistics            bool   // thirdparty common option
-	Solution                string // cdnet specific options
+	baselineUrl             string // the URL the Baseline was downloaded from
+	cacheKey                string // the resolved CacheKey the CacheDir is namespaced with
+	tmpResultsTarget        string // the --results-dir the SARIF report is copied to from the temporary results directory
+	tmpResultsDir           string // the temporary results directory to remove after the scan
+	suppressions            []suppression
+	scanStages              []string // the container analysis stages shown in the progress
+	parallelName            string   // the linter name the output is prefixed with when several linters run in parallel
+	NoStatistics            bool     // thirdparty common option
+	Solution                string   // cdnet specific options
 	Project                 string
 	Configuration           string
 	Platform                string
@@ -76,32 +147,574 @@ type QodanaOptions struct {
 	AnalysisTimeoutExitCode int
 }
 
+// FetchAnalyzerSettings resolves the options with ResolveAnalyzerSettings and exits if the configuration is invalid.
 func (o *QodanaOptions) FetchAnalyzerSettings() {
-	if o.Linter == "" && o.Ide == "" {
-		qodanaYaml := LoadQodanaYaml(o.ProjectDir, o.YamlName)
-		if qodanaYaml.Linter == "" && qodanaYaml.Ide == "" {
-			WarningMessage(
-				"No valid `linter:` or `ide:` field found in %s. Have you run %s? Running that for you...",
-				PrimaryBold(o.YamlName),
-				PrimaryBold("qodana init"),
-			)
-			analyzer := GetAnalyzer(o.ProjectDir, o.YamlName)
-			if IsNativeAnalyzer(analyzer) {
-				o.Ide = analyzer
-			} else {
-				o.Linter = analyzer
-			}
-			EmptyMessage()
-		} else {
-			o.Linter = qodanaYaml.Linter
+	if err := o.ResolveAnalyzerSettings(); err != nil {
+		o.exitWithError(err)
+	}
+}
+
+// ResolveAnalyzerSettings completes the options with qodana.yaml and the defaults and checks them before the analysis,
+// configuration problems are returned as errors matching ErrInvalidConfig.
+func (o *QodanaOptions) ResolveAnalyzerSettings() error {
+	o.ConfigureProxy()
+	for _, resolve := range []func() error{o.resolveConfigPath, o.checkQodanaYaml, o.validateYamlScopes, o.resolveAnalyzer, o.resolveScripts} {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+	if o.Linter != "" {
+		linter, err := resolveLinter(o.Linter)
+		if err != nil {
+			return configErrorf("%s", err)
+		}
+		o.Linter = linter
+	}
+	for _, resolve := range []func() error{o.resolveRegistry, o.resolveBaseline} {
+		if err := resolve(); err != nil {
+			return err
 		}
-		if o.Ide == "" {
-			o.Ide = qodanaYaml.Ide
+	}
+	if o.FailOnNew && o.Baseline == "" && o.BaselineDir == "" {
+		return configErrorf("--fail-on-new requires --baseline or --baseline-dir")
+	}
+	for _, resolve := range []func() error{o.validateBaselineWriteOnPass, o.resolveInspectRule, o.resolveProfilePath} {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+	if o.ProfileName != "" {
+		// the linter image is queried only for the names unknown without it
+		warning := checkProfileName(o.ProfileName, ProfileNames(o.ProjectDir, ""))
+		if warning != "" && o.Linter != "" {
+			warning = checkProfileName(o.ProfileName, ProfileNames(o.ProjectDir, o.Linter))
+		}
+		if warning != "" {
+			WarningMessage("%s\n", warning)
 		}
 	}
+	if err := o.resolveIncludeDirs(); err != nil {
+		return err
+	}
+	if err := o.resolveEnvFile(); err != nil {
+		return err
+	}
+	if err := o.resolveYamlContainerSettings(); err != nil {
+		return err
+	}
+	o.resolveYamlFailThreshold()
+	for _, resolve := range []func() error{o.validateReportUpload, o.resolvePropertyFile, o.resolveSuppressions} {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+	if err := validateProperties(o.Property); err != nil {
+		return configErrorf("%s", err)
+	}
+	if err := ValidateProblemsOrder(o.SortBy, o.GroupBy); err != nil {
+		return configErrorf("%s", err)
+	}
+	if err := o.resolveTmpResults(); err != nil {
+		return err
+	}
 	o.ResultsDir = o.resultsDirPath()
 	o.ReportDir = o.reportDirPath()
 	o.CacheDir = o.cacheDirPath()
+	for _, resolve := range []func() error{
+		o.checkProjectSize,
+		o.resolveCacheKey,
+		o.resolveBaselineUrl,
+		o.resolveYamlIncludes,
+		o.resolveFiles,
+		o.resolveExcludes,
+	} {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAnalyzer takes the linter or the IDE from qodana.yaml if none is passed, or picks the one recommended
+// for the project.
+func (o *QodanaOptions) resolveAnalyzer() error {
+	if o.Linter != "" || o.Ide != "" {
+		return nil
+	}
+	qodanaYaml := LoadQodanaYaml(o.qodanaYamlPath())
+	if qodanaYaml.Linter == "" && qodanaYaml.Ide == "" {
+		if o.NoAutoLinter {
+			return configErrorf("No valid `linter:` or `ide:` field found in %s, run qodana init or pass --linter", o.YamlName)
+		}
+		if err := o.autoSelectLinter(); err != nil {
+			return err
+		}
+	} else {
+		o.Linter = qodanaYaml.Linter
+	}
+	if o.Ide == "" {
+		o.Ide = qodanaYaml.Ide
+	}
+	return nil
+}
+
+// resolveBaselineUrl downloads the baseline given as an http(s) URL and uses the downloaded copy.
+func (o *QodanaOptions) resolveBaselineUrl() error {
+	if !isBaselineUrl(o.Baseline) {
+		return nil
+	}
+	baselinePath, err := downloadBaseline(o.Baseline, filepath.Join(o.getQodanaSystemDir(), "baselines"))
+	if err != nil {
+		return configErrorf("Could not download the baseline: %s", err)
+	}
+	o.baselineUrl = o.Baseline
+	o.Baseline = baselinePath
+	return nil
+}
+
+// ValidateInput checks the options as given by the user before ResolveAnalyzerSettings, so misconfigurations are
+// reported before the baseline is downloaded or the project is walked. All found problems are returned joined.
+func (o *QodanaOptions) ValidateInput() error {
+	var errs []error
+	if info, err := os.Stat(o.ProjectDir); err != nil {
+		errs = append(errs, fmt.Errorf("project directory %s does not exist", o.ProjectDir))
+	} else if !info.IsDir() {
+		errs = append(errs, fmt.Errorf("project directory %s is not a directory", o.ProjectDir))
+	}
+	if o.ResultsDir != "" {
+		if err := checkWritableDir(o.ResultsDir); err != nil {
+			errs = append(errs, fmt.Errorf("results directory %s is not writable: %w", o.ResultsDir, err))
+		}
+	}
+	if o.Port < 0 || o.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d is out of range 0-65535", o.Port))
+	}
+	if o.FailThreshold != "" {
+		if threshold, err := strconv.Atoi(o.FailThreshold); err != nil || threshold < 0 {
+			errs = append(errs, fmt.Errorf("fail threshold %q is not a non-negative integer", o.FailThreshold))
+		}
+	}
+	if _, err := parseDockerArgs(o.DockerArgs); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --docker-arg: %w", err))
+	}
+	if err := o.resourceLimits().validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.MaxProblems < 0 {
+		errs = append(errs, fmt.Errorf("max problems %d is not a non-negative integer", o.MaxProblems))
+	}
+	if o.SarifOut != "" {
+		if err := validateSarifOut(o.SarifOut); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.Since != "" {
+		if _, err := parseSince(o.Since, time.Now()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.BeforeScript != "" {
+		if err := validateScript("before-script", o.BeforeScript); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.AfterScript != "" {
+		if err := validateScript("after-script", o.AfterScript); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.Repo == "" && (o.RepoBranch != "" || o.RepoToken != "") {
+		errs = append(errs, errors.New("--branch and --repo-token can be used only with --repo"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return configError{errors.Join(errs...)}
+}
+
+// Validate checks the options resolved by ResolveAnalyzerSettings before the analysis is started, the options
+// given by the user are checked earlier with ValidateInput.
+func (o *QodanaOptions) Validate() error {
+	if o.Linter == "" && o.Ide == "" {
+		return configErrorf("linter is not set, use --linter, --ide or configure it in qodana.yaml")
+	}
+	return nil
+}
+
+// checkWritableDir checks that files can be created in dir, or in its closest existing parent if dir doesn't exist yet.
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	file, err := os.CreateTemp(dir, ".qodana-write-check-*")
+	if err != nil {
+		return err
+	}
+	_ = file.Close()
+	return os.Remove(file.Name())
+}
+
+// levelThresholds returns the configured fail thresholds per level, negative thresholds are not checked.
+func (o *QodanaOptions) levelThresholds() map[Level]int {
+	thresholds := make(map[Level]int)
+	for level, threshold := range map[Level]int{
+		LevelError:   o.FailThresholdError,
+		LevelWarning: o.FailThresholdWarning,
+		LevelNote:    o.FailThresholdNote,
+	} {
+		if threshold >= 0 {
+			thresholds[level] = threshold
+		}
+	}
+	return thresholds
+}
+
+// resolveBaseline sets Baseline to the most recent SARIF report from BaselineDir, an explicit Baseline takes precedence.
+// Without both, the baseline declared in qodana.yaml is used.
+func (o *QodanaOptions) resolveBaseline() error {
+	if o.Baseline == "" && o.BaselineDir == "" && o.BaselineGenerate == "" {
+		if err := o.resolveYamlBaseline(); err != nil {
+			return err
+		}
+	}
+	if o.BaselineDir == "" {
+		return nil
+	}
+	if o.Baseline != "" {
+		WarningMessage("Both --baseline and --baseline-dir are set, using %s", o.Baseline)
+		return nil
+	}
+	baselineDir := o.BaselineDir
+	if !filepath.IsAbs(baselineDir) {
+		baselineDir = filepath.Join(o.ProjectDir, baselineDir)
+	}
+	name, err := findLatestSarif(baselineDir)
+	if err != nil {
+		return configErrorf("Could not find a baseline in %s: %s", o.BaselineDir, err)
+	}
+	o.Baseline = filepath.Join(o.BaselineDir, name)
+	SuccessMessage("Using the latest baseline %s", o.Baseline)
+	return nil
+}
+
+// resolveYamlBaseline sets Baseline to the baseline: path from qodana.yaml, relative to the project directory.
+func (o *QodanaOptions) resolveYamlBaseline() error {
+	baseline := LoadQodanaYaml(o.qodanaYamlPath()).Baseline
+	if baseline == "" {
+		return nil
+	}
+	o.Baseline = baseline
+	if !isBaselineUrl(baseline) {
+		if _, err := os.Stat(o.baselinePath()); err != nil {
+			return configErrorf("The baseline %s from %s does not exist: %s", baseline, o.qodanaYamlPath(), err)
+		}
+	}
+	log.Infof("Using the baseline %s from %s", baseline, o.qodanaYamlPath())
+	return nil
+}
+
+// resolveRegistry pulls and runs the linter image from the --registry mirror.
+func (o *QodanaOptions) resolveRegistry() error {
+	if o.Registry == "" {
+		return nil
+	}
+	if err := validateRegistry(o.Registry); err != nil {
+		return configErrorf("%s", err)
+	}
+	if o.Linter == "" {
+		return nil
+	}
+	if linter := imageWithRegistry(o.Linter, o.Registry); linter != o.Linter {
+		log.Debugf("Using %s from the registry %s", linter, o.Registry)
+		o.Linter = linter
+	} else {
+		log.Debugf("The image %s is already qualified with a registry, --registry %s is not applied", o.Linter, o.Registry)
+	}
+	return nil
+}
+
+// validateProperties checks that every property is in the key=value form.
+func validateProperties(properties []string) error {
+	for _, property := range properties {
+		key, _, found := strings.Cut(property, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid --property %q, expected property.name=value", property)
+		}
+	}
+	return nil
+}
+
+// resolvePropertyFile adds the properties from PropertyFile to Property, explicit --property values take precedence.
+func (o *QodanaOptions) resolvePropertyFile() error {
+	if o.PropertyFile == "" {
+		return nil
+	}
+	properties, err := parsePropertyFile(o.PropertyFile)
+	if err != nil {
+		return configErrorf("Invalid --property-file: %s", err)
+	}
+	o.Property = mergeEnv(o.Property, properties)
+	return nil
+}
+
+// resolveEnvFile adds the variables from EnvFile to Env, explicit --env values take precedence.
+func (o *QodanaOptions) resolveEnvFile() error {
+	if o.EnvFile == "" {
+		return nil
+	}
+	env, err := parseEnvFile(o.EnvFile)
+	if err != nil {
+		return configErrorf("Invalid --env-file: %s", err)
+	}
+	o.Env = mergeEnv(o.Env, env)
+	return nil
+}
+
+// resolveChangesSinceBranch sets Commit to the merge base with ChangesSinceBranch, so only the changes made on
+// the current branch are analyzed.
+func (o *QodanaOptions) resolveChangesSinceBranch() error {
+	if o.ChangesSinceBranch == "" {
+		return nil
+	}
+	if !isInstalled("git") {
+		return configErrorf("git is required to analyze the changes since %s", o.ChangesSinceBranch)
+	}
+	base, err := gitMergeBase(o.ProjectDir, o.ChangesSinceBranch)
+	if err != nil {
+		return configErrorf("Could not find the changes since %s: %s", o.ChangesSinceBranch, err)
+	}
+	SuccessMessage("Analyzing changes since %s (merge base %s)", o.ChangesSinceBranch, base)
+	o.Commit = base
+	return nil
+}
+
+// autoSelectLinter picks the linter recommended for the detected project languages when none is configured.
+func (o *QodanaOptions) autoSelectLinter() error {
+	code, err := GetLinterForProject(o.ProjectDir)
+	if err != nil {
+		return configErrorf("Could not select the linter: %s. Run qodana init or pass --linter", err)
+	}
+	analyzer := Image(code)
+	if IsNativeAnalyzer(code) && IsNativeRequired(o.ProjectDir, code) {
+		analyzer = code
+		o.Ide = code
+	} else {
+		o.Linter = analyzer
+	}
+	WarningMessage(
+		"No valid `linter:` or `ide:` field found in %s, using %s detected for the project. Run %s to save the choice",
+		PrimaryBold(o.YamlName),
+		PrimaryBold(analyzer),
+		PrimaryBold("qodana init"),
+	)
+	EmptyMessage()
+	return nil
+}
+
+// validateReportUpload checks the report upload URL and headers before the analysis is started.
+func (o *QodanaOptions) validateReportUpload() error {
+	if o.ReportUploadUrl == "" {
+		if len(o.ReportUploadHeaders) > 0 {
+			return configErrorf("--report-upload-header requires --report-upload-url")
+		}
+		return nil
+	}
+	if err := validateUploadUrl(o.ReportUploadUrl); err != nil {
+		return configErrorf("Invalid --report-upload-url: %s", err)
+	}
+	if _, err := parseUploadHeaders(o.ReportUploadHeaders); err != nil {
+		return configErrorf("Invalid --report-upload-header: %s", err)
+	}
+	return nil
+}
+
+// resolveYamlContainerSettings adds the volumes and env from qodana.yaml, --volume and --env values take precedence.
+func (o *QodanaOptions) resolveYamlContainerSettings() error {
+	qodanaYaml := LoadQodanaYaml(o.qodanaYamlPath())
+	o.Env = mergeEnv(o.Env, qodanaYaml.Env)
+	volumes, err := o.resolveYamlVolumes(qodanaYaml.Volumes)
+	if err != nil {
+		return err
+	}
+	o.Volumes = mergeVolumes(o.Volumes, volumes)
+	return nil
+}
+
+// logRequestedPlugins prints the plugins listed in qodana.yaml for container runs, the linter in the container
+// installs them before the analysis.
+func (o *QodanaOptions) logRequestedPlugins() {
+	if o.Linter == "" {
+		return
+	}
+	if plugins := LoadQodanaYaml(o.qodanaYamlPath()).Plugins; len(plugins) > 0 {
+		SuccessMessage("Plugins requested in qodana.yaml are installed before the analysis: %s", strings.Join(getPluginIds(plugins), ", "))
+	}
+}
+
+// resolveYamlFailThreshold takes the fail threshold from qodana.yaml if --fail-threshold is not passed.
+func (o *QodanaOptions) resolveYamlFailThreshold() {
+	if o.FailThreshold != "" {
+		return
+	}
+	if threshold := LoadQodanaYaml(o.qodanaYamlPath()).FailThreshold; threshold != nil {
+		o.FailThreshold = strconv.Itoa(*threshold)
+	}
+}
+
+// resolveIncludeDirs makes IncludeDirs relative to the project directory, directories outside the project are rejected
+// as they are not visible inside the container. The linter takes a single --source-directory, so with --include-dir
+// the source directory joins the include dirs, and resolveExcludes excludes everything else.
+func (o *QodanaOptions) resolveIncludeDirs() error {
+	if len(o.IncludeDirs) > 0 && o.SourceDirectory != "" {
+		o.IncludeDirs = append([]string{o.SourceDirectory}, o.IncludeDirs...)
+		o.SourceDirectory = ""
+	}
+	for i, dir := range o.IncludeDirs {
+		relative, err := projectRelativeDir(o.ProjectDir, dir)
+		if err != nil {
+			return configErrorf("Invalid --include-dir %s: %s", dir, err)
+		}
+		o.IncludeDirs[i] = relative
+	}
+	return nil
+}
+
+// projectRelativeDir returns the slash-separated path of the existing directory dir relative to the project directory.
+// A relative dir is resolved against the project directory.
+func projectRelativeDir(projectDir string, dir string) (string, error) {
+	relative, info, err := projectRelativePath(projectDir, dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return relative, nil
+}
+
+// errOutsideProject is returned by projectRelativePath for the paths outside of the project directory.
+var errOutsideProject = errors.New("the path is outside of the project directory")
+
+// projectRelativePath returns the slash-separated path of the existing file or directory relative to the project
+// directory and its file info. A relative path is resolved against the project directory.
+func projectRelativePath(projectDir string, p string) (string, os.FileInfo, error) {
+	projectPath, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", nil, err
+	}
+	absPath := p
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(projectPath, absPath)
+	}
+	relative, err := filepath.Rel(projectPath, filepath.Clean(absPath))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(os.PathSeparator)) {
+		return "", nil, fmt.Errorf("%w %s", errOutsideProject, projectPath)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.ToSlash(relative), info, nil
+}
+
+// qodanaYamlPath returns the path of the configuration file: the explicit --config file or qodana.yaml from the project.
+func (o *QodanaOptions) qodanaYamlPath() string {
+	if o.ConfigPath != "" {
+		return o.ConfigPath
+	}
+	return filepath.Join(o.ProjectDir, o.YamlName)
+}
+
+// resolveConfigPath makes ConfigPath absolute and checks the configuration file is readable.
+// The explicit configuration takes precedence over qodana.yaml from the project.
+func (o *QodanaOptions) resolveConfigPath() error {
+	if o.ConfigPath == "" {
+		return nil
+	}
+	configPath, err := filepath.Abs(o.ConfigPath)
+	if err == nil {
+		err = checkFileReadable(configPath)
+	}
+	if err != nil {
+		return configErrorf("Configuration %s is not a readable file: %s", o.ConfigPath, err)
+	}
+	projectYamlPath, err := filepath.Abs(filepath.Join(o.ProjectDir, o.YamlName))
+	if err == nil && projectYamlPath != configPath {
+		if _, err := os.Stat(projectYamlPath); err == nil {
+			WarningMessage("Using %s instead of %s from the project", configPath, o.YamlName)
+		}
+	}
+	o.ConfigPath = configPath
+	return nil
+}
+
+// resolveYamlIncludes merges the files included by the configuration into a generated one passed to the linter
+// as --config (mounted outside the project for container runs), as the linter reads only the given file.
+func (o *QodanaOptions) resolveYamlIncludes() error {
+	configPath := o.qodanaYamlPath()
+	mapping, err := readYamlMapping(configPath)
+	if err != nil || len(extractYamlIncludeFiles(mapping)) == 0 {
+		return nil
+	}
+	mergedPath := filepath.Join(o.GetLinterDir(), "include", configName+".yaml")
+	if err = writeMergedConfig(configPath, mergedPath); err != nil {
+		return configErrorf("Could not merge the files included by %s: %s", configPath, err)
+	}
+	o.ConfigPath = mergedPath
+	return nil
+}
+
+// resolveProfilePath makes ProfilePath absolute and checks the profile file is readable, so the run fails before the analysis starts.
+// A relative path is looked up in the current directory first, then in the project directory.
+func (o *QodanaOptions) resolveProfilePath() error {
+	if o.ProfilePath == "" {
+		return nil
+	}
+	profilePath := o.ProfilePath
+	if !filepath.IsAbs(profilePath) {
+		if _, err := os.Stat(profilePath); err != nil {
+			profilePath = filepath.Join(o.ProjectDir, profilePath)
+		}
+	}
+	profilePath, err := filepath.Abs(profilePath)
+	if err == nil {
+		err = checkFileReadable(profilePath)
+	}
+	if err != nil {
+		return configErrorf("Profile %s is not a readable file: %s", o.ProfilePath, err)
+	}
+	o.ProfilePath = profilePath
+	return nil
+}
+
+// checkFileReadable returns an error if the given path is not a regular file that can be read.
+func checkFileReadable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
 }
 
 // setenv sets the Qodana container environment variables if such variable was not set before.
@@ -147,12 +760,13 @@ func (o *QodanaOptions) id() string {
 	if o._id == "" {
 		var analyzer string
 		if o.Linter != "" {
-			analyzer = o.Linter
+			// the mirrored image shares the directories with the original one
+			analyzer = strings.TrimPrefix(o.Linter, strings.Trim(o.Registry, "/")+"/")
 		} else if o.Ide != "" {
 			analyzer = o.Ide
 		}
 		if analyzer == "" {
-			qYaml := LoadQodanaYaml(o.ProjectDir, o.YamlName)
+			qYaml := LoadQodanaYaml(o.qodanaYamlPath())
 			if qYaml.Ide != "" {
 				analyzer = qYaml.Linter
 			} else if qYaml.Linter != "" {
@@ -172,7 +786,11 @@ func (o *QodanaOptions) id() string {
 
 func (o *QodanaOptions) getQodanaSystemDir() string {
 	if o.CacheDir != "" {
-		return filepath.Dir(filepath.Dir(o.CacheDir))
+		cacheDir := o.CacheDir
+		if o.cacheKey != "" {
+			cacheDir = filepath.Dir(cacheDir)
+		}
+		return filepath.Dir(filepath.Dir(cacheDir))
 	}
 
 	userCacheDir, _ := os.UserCacheDir()
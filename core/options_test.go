@@ -1,7 +1,12 @@
 package core
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestQodanaOptions_guessProduct(t *testing.T) {
@@ -33,3 +38,308 @@ func TestQodanaOptions_guessProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestQodanaOptions_autoSelectLinter(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "main.py"), []byte("print('hello')\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml"}
+	opts.autoSelectLinter()
+	assert.Equal(t, Image(QDPYC), opts.Linter)
+	assert.Empty(t, opts.Ide)
+	assert.NoFileExists(t, filepath.Join(projectDir, "qodana.yaml"), "the choice is not saved")
+}
+
+func TestQodanaOptions_ConfigPathOverridesProjectYaml(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("linter: jetbrains/qodana-jvm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "custom.yaml")
+	if err := os.WriteFile(configPath, []byte("linter: jetbrains/qodana-python"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml"}
+	if got := LoadQodanaYaml(opts.qodanaYamlPath()).Linter; got != "jetbrains/qodana-jvm" {
+		t.Errorf("project qodana.yaml linter = %v, want %v", got, "jetbrains/qodana-jvm")
+	}
+	opts.ConfigPath = configPath
+	assert.NoError(t, opts.resolveConfigPath())
+	if got := LoadQodanaYaml(opts.qodanaYamlPath()).Linter; got != "jetbrains/qodana-python" {
+		t.Errorf("--config linter = %v, want %v", got, "jetbrains/qodana-python")
+	}
+}
+
+func TestQodanaOptions_resolveYamlIncludes(t *testing.T) {
+	version := Prod.Version
+	t.Cleanup(func() { Prod.Version = version })
+	Prod.Version = "2023.3"
+	projectDir := t.TempDir()
+	files := map[string]string{
+		"qodana.yml":  "include:\n  - common.yaml\nfailThreshold: 1\n",
+		"common.yaml": "profile:\n  name: qodana.recommended\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		linter   string
+		ide      string
+		expected func(configPath string) []string
+	}{
+		{linter: "jetbrains/qodana-jvm:2023.3", expected: func(string) []string { return []string{"--config", containerConfigPath} }},
+		{ide: QDJVM, expected: func(configPath string) []string { return []string{"--config", configPath} }},
+	} {
+		opts := &QodanaOptions{
+			Linter:     tc.linter,
+			Ide:        tc.ide,
+			ProjectDir: projectDir,
+			YamlName:   "qodana.yml",
+			CacheDir:   filepath.Join(t.TempDir(), "cache"),
+		}
+		assert.NoError(t, opts.resolveYamlIncludes())
+		assert.Equal(t, tc.expected(opts.ConfigPath), GetCmdOptions(opts))
+		merged := LoadQodanaYaml(opts.ConfigPath)
+		assert.Equal(t, "qodana.recommended", merged.Profile.Name)
+		assert.Equal(t, 1, *merged.FailThreshold)
+	}
+}
+
+func TestQodanaOptions_resolveYamlContainerSettings(t *testing.T) {
+	projectDir := t.TempDir()
+	content := `volumes:
+  - /yaml/cache:/data/cache
+  - /yaml/m2:/root/.m2
+env:
+  - FOO=yaml
+  - BAR=yaml`
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := QodanaOptions{
+		ProjectDir: projectDir,
+		YamlName:   "qodana.yaml",
+		Volumes:    []string{"/flag/cache:/data/cache"},
+		Env:        []string{"FOO=flag"},
+	}
+	err := opts.resolveYamlContainerSettings()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "/yaml/cache:/data/cache")
+
+	opts.AllowYamlVolumes = true
+	assert.NoError(t, opts.resolveYamlContainerSettings())
+	assert.Equal(t, []string{"/flag/cache:/data/cache", "/yaml/m2:/root/.m2"}, opts.Volumes)
+	assert.Equal(t, []string{"FOO=flag", "BAR=yaml"}, opts.Env)
+}
+
+func TestQodanaOptions_resolveYamlVolumes(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, ".m2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml"}
+	volumes, err := opts.resolveYamlVolumes([]string{".m2:/root/.m2", filepath.Join(projectDir, ".m2") + ":/data/m2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(projectDir, ".m2") + ":/root/.m2",
+		filepath.Join(projectDir, ".m2") + ":/data/m2",
+	}, volumes)
+
+	for _, volume := range []string{"../outside:/data/outside", "/etc:/data/etc"} {
+		_, err = opts.resolveYamlVolumes([]string{volume})
+		assert.ErrorIs(t, err, ErrInvalidConfig, volume)
+		assert.Contains(t, err.Error(), "--allow-yaml-volumes", volume)
+	}
+
+	_, err = opts.resolveYamlVolumes([]string{"missing:/data/missing"})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, "Volume missing:/data/missing from qodana.yaml can't be mounted: missing doesn't exist in the project", err.Error())
+
+	opts.AllowYamlVolumes = true
+	volumes, err = opts.resolveYamlVolumes([]string{"/etc:/data/etc"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/etc:/data/etc"}, volumes)
+	_, err = opts.resolveYamlVolumes([]string{"missing:/data/missing"})
+	assert.ErrorIs(t, err, ErrInvalidConfig, "--allow-yaml-volumes doesn't allow missing project sources")
+	assert.NotContains(t, err.Error(), "--allow-yaml-volumes")
+}
+
+func TestQodanaOptions_resolveYamlBaseline(t *testing.T) {
+	projectDir := t.TempDir()
+	baseline := filepath.Join(".qodana", "baseline.sarif.json")
+	if err := os.MkdirAll(filepath.Join(projectDir, ".qodana"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, baseline), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("baseline: "+filepath.ToSlash(baseline)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml"}
+	assert.NoError(t, opts.resolveBaseline())
+	assert.Equal(t, filepath.ToSlash(baseline), opts.Baseline)
+	assert.Equal(t, filepath.Join(projectDir, baseline), opts.baselinePath())
+
+	opts = QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml", Baseline: "other.sarif.json"}
+	assert.NoError(t, opts.resolveBaseline())
+	assert.Equal(t, "other.sarif.json", opts.Baseline, "--baseline takes precedence")
+
+	opts = QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml", BaselineGenerate: "new.sarif.json"}
+	assert.NoError(t, opts.resolveBaseline())
+	assert.Equal(t, "", opts.Baseline, "not compared with a baseline while generating one")
+}
+
+func TestQodanaOptions_resolveYamlFailThreshold(t *testing.T) {
+	projectDir := t.TempDir()
+	opts := QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml"}
+	opts.resolveYamlFailThreshold()
+	assert.Equal(t, "", opts.FailThreshold, "no threshold by default")
+
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("failThreshold: 0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts.resolveYamlFailThreshold()
+	assert.Equal(t, "0", opts.FailThreshold)
+
+	opts = QodanaOptions{ProjectDir: projectDir, YamlName: "qodana.yaml", FailThreshold: "10"}
+	opts.resolveYamlFailThreshold()
+	assert.Equal(t, "10", opts.FailThreshold, "--fail-threshold takes precedence")
+}
+
+func TestQodanaOptions_resolveProfilePath(t *testing.T) {
+	projectDir := t.TempDir()
+	profilePath := filepath.Join(projectDir, ".qodana", "profile.xml")
+	if err := os.MkdirAll(filepath.Dir(profilePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(profilePath, []byte("<profile/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := QodanaOptions{ProjectDir: projectDir, ProfilePath: filepath.Join(".qodana", "profile.xml")}
+	assert.NoError(t, opts.resolveProfilePath())
+	if opts.ProfilePath != profilePath {
+		t.Errorf("QodanaOptions.resolveProfilePath() = %v, want %v", opts.ProfilePath, profilePath)
+	}
+
+	if err := checkFileReadable(filepath.Dir(profilePath)); err == nil {
+		t.Errorf("checkFileReadable() expected an error for a directory")
+	}
+	if err := checkFileReadable(filepath.Join(projectDir, "missing.xml")); err == nil {
+		t.Errorf("checkFileReadable() expected an error for a missing file")
+	}
+}
+
+func TestProjectRelativeDir(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, dir := range []string{filepath.Join("services", "api"), "lib"} {
+		if err := os.MkdirAll(filepath.Join(projectDir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+		wantErr  bool
+	}{
+		{"Relative dir", filepath.Join("services", "api"), "services/api", false},
+		{"Absolute dir inside project", filepath.Join(projectDir, "lib"), "lib", false},
+		{"Dir outside project", "..", "", true},
+		{"Absolute dir outside project", os.TempDir(), "", true},
+		{"Missing dir", "missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := projectRelativeDir(projectDir, tt.dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("projectRelativeDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("projectRelativeDir() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties []string
+		wantErr    bool
+	}{
+		{"No properties", []string{}, false},
+		{"Single property", []string{"idea.log.level=debug"}, false},
+		{"Several properties with lists and empty values", []string{"foo.bar=a,b,c", "foo.baz="}, false},
+		{"Missing value separator", []string{"foo.bar=baz", "idea.log.level"}, true},
+		{"Missing key", []string{"=value"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateProperties(tt.properties); (err != nil) != tt.wantErr {
+				t.Errorf("validateProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQodanaOptions_Validate(t *testing.T) {
+	projectDir := t.TempDir()
+	file := filepath.Join(projectDir, "file.txt")
+	if err := os.WriteFile(file, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	valid := func() *QodanaOptions {
+		return &QodanaOptions{
+			ProjectDir:    projectDir,
+			ResultsDir:    filepath.Join(t.TempDir(), "results", "nested"),
+			Port:          8080,
+			FailThreshold: "10",
+			Linter:        "jetbrains/qodana-jvm:latest",
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(o *QodanaOptions)
+		error  string
+	}{
+		{"valid", func(o *QodanaOptions) {}, ""},
+		{"native", func(o *QodanaOptions) { o.Linter, o.Ide = "", "QDNET" }, ""},
+		{"missing project", func(o *QodanaOptions) { o.ProjectDir = filepath.Join(projectDir, "missing") }, "does not exist"},
+		{"project is a file", func(o *QodanaOptions) { o.ProjectDir = file }, "is not a directory"},
+		{"results under a file", func(o *QodanaOptions) { o.ResultsDir = filepath.Join(file, "results") }, "is not writable"},
+		{"negative port", func(o *QodanaOptions) { o.Port = -1 }, "out of range"},
+		{"too big port", func(o *QodanaOptions) { o.Port = 70000 }, "out of range"},
+		{"negative threshold", func(o *QodanaOptions) { o.FailThreshold = "-1" }, "not a non-negative integer"},
+		{"not a number threshold", func(o *QodanaOptions) { o.FailThreshold = "ten" }, "not a non-negative integer"},
+		{"negative max problems", func(o *QodanaOptions) { o.MaxProblems = -1 }, "not a non-negative integer"},
+		{"no linter", func(o *QodanaOptions) { o.Linter = "" }, "linter is not set"},
+		{"branch without repo", func(o *QodanaOptions) { o.RepoBranch = "main" }, "can be used only with --repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid()
+			tt.modify(o)
+			err := errors.Join(o.ValidateInput(), o.Validate())
+			if tt.error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.error)
+			}
+		})
+	}
+
+	o := valid()
+	o.Port, o.MaxProblems, o.Linter = -1, -1, ""
+	assert.ErrorContains(t, o.ValidateInput(), "out of range")
+	assert.ErrorContains(t, o.ValidateInput(), "max problems -1")
+	assert.NoError(t, (&QodanaOptions{ProjectDir: projectDir}).ValidateInput(), "the linter is resolved later")
+	assert.ErrorContains(t, o.Validate(), "linter is not set")
+}
@@ -19,8 +19,10 @@ package core
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/liamg/clinch/terminal"
 	"github.com/mattn/go-isatty"
@@ -45,11 +47,32 @@ func IsInteractive() bool {
 	return !IsContainer() && os.Getenv("NONINTERACTIVE") == "" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()))
 }
 
+// colorDisabled is set when the output is not colored (e.g. with NO_COLOR), the progress spinner is not shown then.
+var colorDisabled bool
+
+// IsColorSupported checks if the output can be colored: it is a terminal and NO_COLOR (http://no-color.org) is not set.
+func IsColorSupported() bool {
+	return IsInteractive() && os.Getenv("NO_COLOR") == ""
+}
+
 // DisableColor disables colors in the output.
 func DisableColor() {
+	colorDisabled = true
 	pterm.DisableColor()
 }
 
+// EnableColor enables colors in the output, e.g. with --color when the output is not a terminal.
+func EnableColor() {
+	colorDisabled = false
+	pterm.EnableColor()
+}
+
+// isProgressShown checks if the progress spinner can be shown: on a terminal outside CI,
+// without --quiet, JSON logs and disabled colors.
+func isProgressShown() bool {
+	return IsInteractive() && !isCI() && !Quiet && !jsonOutput && !colorDisabled
+}
+
 // styles and different declarations intended to be used only inside this file
 var (
 	noLineWidth             = 7
@@ -103,19 +126,36 @@ func PrimaryBold(text string, a ...interface{}) string {
 
 // EmptyMessage is a message that is used when there is no message to show.
 func EmptyMessage() {
+	if Quiet || jsonOutput {
+		return
+	}
 	pterm.Println()
 }
 
 // SuccessMessage prints a success message with the icon.
 func SuccessMessage(message string, a ...interface{}) {
+	if Quiet {
+		return
+	}
 	message = fmt.Sprintf(message, a...)
+	if jsonOutput {
+		messageLogger.Info(message)
+		return
+	}
 	icon := pterm.Green("✓ ")
 	pterm.Println(icon, primary(message))
 }
 
 // WarningMessage prints a warning message with the icon.
 func WarningMessage(message string, a ...interface{}) {
+	if Quiet {
+		return
+	}
 	message = fmt.Sprintf(message, a...)
+	if jsonOutput {
+		messageLogger.Warn(strings.TrimSpace(message))
+		return
+	}
 	icon := warningStyle.Sprint("\n! ")
 	pterm.Println(icon, primary(message))
 }
@@ -123,12 +163,23 @@ func WarningMessage(message string, a ...interface{}) {
 // ErrorMessage prints an error message with the icon.
 func ErrorMessage(message string, a ...interface{}) {
 	message = fmt.Sprintf(message, a...)
+	if jsonOutput {
+		messageLogger.Error(message)
+		return
+	}
 	icon := errorStyle.Sprint("✗ ")
 	pterm.Println(icon, errorStyle.Sprint(message))
 }
 
 // printLinterLog prints the linter logs with color, when needed.
 func printLinterLog(line string) {
+	if Quiet {
+		return
+	}
+	if jsonOutput {
+		messageLogger.WithField("source", "linter").Info(line)
+		return
+	}
 	if strings.Contains(line, " / /") ||
 		strings.Contains(line, "_              _") ||
 		strings.Contains(line, "\\/__") ||
@@ -139,7 +190,7 @@ func printLinterLog(line string) {
 	}
 }
 
-// printProcess prints the message for processing phase. TODO: Add ETA based on previous runs
+// printProcess prints the message for processing phase.
 func printProcess(f func(spinner *pterm.SpinnerPrinter), start string, finished string) {
 	if err := spin(f, start); err != nil {
 		log.Fatal("\nProblem occurred:", err.Error())
@@ -152,7 +203,9 @@ func printProcess(f func(spinner *pterm.SpinnerPrinter), start string, finished
 // spin creates spinner and runs the given function. Also, spin is a spider in Dutch.
 func spin(fun func(spinner *pterm.SpinnerPrinter), message string) error {
 	spinner, _ := startQodanaSpinner(message)
-	if spinner == nil {
+	if spinner == nil && jsonOutput {
+		messageLogger.Info(message)
+	} else if spinner == nil && !Quiet {
 		fmt.Println(primary(message + "..."))
 	}
 	fun(spinner)
@@ -164,9 +217,11 @@ func spin(fun func(spinner *pterm.SpinnerPrinter), message string) error {
 
 // startQodanaSpinner starts a new spinner with the given message.
 func startQodanaSpinner(message string) (*pterm.SpinnerPrinter, error) {
-	if IsInteractive() {
+	if isProgressShown() {
 		QodanaSpinner.Sequence = spinnerSequence
 		QodanaSpinner.MessageStyle = primaryStyle
+		QodanaSpinner.ShowTimer = true
+		QodanaSpinner.TimerRoundingFactor = time.Second
 		return QodanaSpinner.WithStyle(pterm.NewStyle(pterm.FgGray)).WithRemoveWhenDone(true).Start(message + "...")
 	}
 	return nil, nil
@@ -181,22 +236,56 @@ func updateText(spinner *pterm.SpinnerPrinter, message string) {
 
 // PrintFile prints the given file content with lines like printProblem.
 func PrintFile(file string) {
-	printHeader("", "", file)
 	content, err := os.ReadFile(file)
 	if err != nil {
 		log.Fatalf("failed to read file %s: %s", file, err)
 	}
+	if jsonOutput {
+		messageLogger.WithField("file", file).Info(string(content))
+		return
+	}
+	printHeader("", "", 0, file)
 	printLines(string(content), 1, 0, true)
 }
 
-// printProblem prints problem with source code or without it.
-func printProblem(ruleId string, level string, message string, path string, line int, column int, contextLine int, context string) {
-	printHeader(level, ruleId, "")
-	printPath(path, line, column)
-	printLines(context, contextLine, line, false)
+// logProblem prints the problem as a JSON log object with --log-format json, the index is omitted if it is 0.
+func logProblem(index int, ruleId string, level string, message string, path string, line int, column int) {
+	fields := log.Fields{"rule": ruleId, "severity": level}
+	if index > 0 {
+		fields["index"] = index
+	}
+	if path != "" {
+		fields["file"] = path
+		fields["line"] = line
+		fields["column"] = column
+	}
+	messageLogger.WithFields(fields).Info(message)
+}
+
+// printProblem prints problem with source code or without it, the index is used to open it with qodana view --open-file.
+// The path is relative to projectDir.
+func printProblem(projectDir string, index int, ruleId string, level string, message string, path string, line int, column int, contextLine int, context string) {
+	if jsonOutput {
+		logProblem(index, ruleId, level, message, path, line, column)
+		return
+	}
+	printHeader(level, ruleId, index, "")
+	printPath(projectDir, path, line, column)
+	if context != "" {
+		printLines(context, contextLine, line, false)
+	}
 	fmt.Print(message + "\n")
 }
 
+// printGroupHeader prints the file or rule the following problems are grouped by, the JSON output has no groups.
+func printGroupHeader(key string, count int) {
+	if jsonOutput {
+		return
+	}
+	fmt.Printf("%s %s\n", PrimaryBold(key), miscStyle.Sprintf("(%s)", pluralize(count, "problem", "problems")))
+	fmt.Println(strings.Repeat(tableSep, getTerminalWidth()))
+}
+
 // getTerminalWidth returns the width of the terminal.
 func getTerminalWidth() int {
 	width, _ := terminal.Size()
@@ -206,10 +295,14 @@ func getTerminalWidth() int {
 	return width
 }
 
-// printHeader prints the header of the problem/file.
-func printHeader(level string, ruleId string, file string) {
+// printHeader prints the header of the problem/file, the index is printed after the rule id if it is positive.
+func printHeader(level string, ruleId string, index int, file string) {
 	width := getTerminalWidth()
-	fmt.Printf("%s %s\n", PrimaryBold(strings.ToUpper(level)), primary(ruleId))
+	if index > 0 {
+		fmt.Printf("%s %s %s\n", PrimaryBold(strings.ToUpper(level)), primary(ruleId), miscStyle.Sprintf("#%d", index))
+	} else {
+		fmt.Printf("%s %s\n", PrimaryBold(strings.ToUpper(level)), primary(ruleId))
+	}
 	fmt.Println(strings.Repeat(tableSep, width))
 	if file != "" {
 		fmt.Printf("%5s  %s %s\n", "", tableSepMid, PrimaryBold(file))
@@ -217,16 +310,36 @@ func printHeader(level string, ruleId string, file string) {
 	}
 }
 
-// printPath prints the path of the problem.
-func printPath(path string, line int, column int) {
+// printPath prints the path of the problem, the path is relative to projectDir.
+func printPath(projectDir string, path string, line int, column int) {
 	if path != "" && line > 0 && column > 0 {
-		fmt.Printf(" %s:%d:%d\n", path, line, column)
+		fmt.Printf(" %s\n", fileLink(projectDir, path, fmt.Sprintf("%s:%d:%d", path, line, column)))
 		fmt.Printf("%s%s\n", tableUp, strings.Repeat(tableSep, getTerminalWidth()-noLineWidth-1))
 	} else {
 		fmt.Println(strings.Repeat(tableSep, getTerminalWidth()))
 	}
 }
 
+// fileLink returns the text as a terminal hyperlink to the file (OSC 8), so the file can be opened with a click.
+// A relative path is resolved against projectDir. The text is returned as is if the output is not a terminal.
+func fileLink(projectDir string, path string, text string) string {
+	if !IsInteractive() {
+		return text
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return text
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs // a Windows path, e.g. C:/project
+	}
+	return fmt.Sprintf("\x1b]8;;file://%s\x1b\\%s\x1b]8;;\x1b\\", abs, text)
+}
+
 // printLines prints the lines of the problem.
 func printLines(content string, contextLine int, line int, skipHighlight bool) {
 	lines := strings.Split(content, "\n")
@@ -250,6 +363,33 @@ func printLines(content string, contextLine int, line int, skipHighlight bool) {
 	fmt.Printf("%s%s\n", tableDown, strings.Repeat(tableSep, getTerminalWidth()-noLineWidth-1))
 }
 
+// renderTable prints the table with the header row, with --log-format json every row is printed as a JSON log object
+// with the header names as fields instead.
+func renderTable(data pterm.TableData) error {
+	if !jsonOutput {
+		table := pterm.DefaultTable.WithData(data)
+		table.HeaderRowSeparator = ""
+		table.Separator = " "
+		table.Boxed = true
+		return table.Render()
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	header := data[0]
+	for _, row := range data[1:] {
+		fields := log.Fields{}
+		for i, cell := range row {
+			if i < len(header) {
+				key := strings.ReplaceAll(strings.ToLower(pterm.RemoveColorFromString(header[i])), " ", "_")
+				fields[key] = cell
+			}
+		}
+		messageLogger.WithFields(fields).Info(strings.Join(row, " "))
+	}
+	return nil
+}
+
 // PrintContributorsTable prints the contributors table and helpful messages.
 func PrintContributorsTable(contributors []contributor, days int, dirs int) {
 	count := len(contributors)
@@ -268,11 +408,7 @@ func PrintContributorsTable(contributors []contributor, days int, dirs int) {
 		})
 	}
 
-	table := pterm.DefaultTable.WithData(contributorsTableData)
-	table.HeaderRowSeparator = ""
-	table.Separator = " "
-	table.Boxed = true
-	err := table.Render()
+	err := renderTable(contributorsTableData)
 	if err != nil {
 		return
 	}
@@ -283,6 +419,9 @@ func PrintContributorsTable(contributors []contributor, days int, dirs int) {
 		PrimaryBold(strconv.Itoa(days)),
 		PrimaryBold(strconv.Itoa(dirs)),
 	)
+	if jsonOutput {
+		return
+	}
 	fmt.Print(getPlanMessage("Community", 0, count))
 	fmt.Print(getPlanMessage("Ultimate", 6, count))
 	fmt.Print(getPlanMessage("Ultimate Plus*", 9, count))
@@ -296,6 +435,120 @@ func PrintContributorsTable(contributors []contributor, days int, dirs int) {
 	EmptyMessage()
 }
 
+// PrintLintersTable prints the supported linters table, the auto-selected linter is marked with an asterisk.
+func PrintLintersTable(linters []linter) {
+	lintersTableData := pterm.TableData{
+		[]string{
+			PrimaryBold("Code"),
+			PrimaryBold("Image"),
+			PrimaryBold("Languages"),
+		},
+	}
+	selected := ""
+	for _, l := range linters {
+		code := l.Code
+		if l.Selected {
+			code += "*"
+			selected = l.Image
+		}
+		lintersTableData = append(lintersTableData, []string{
+			code,
+			l.Image,
+			strings.Join(l.Languages, ", "),
+		})
+	}
+
+	err := renderTable(lintersTableData)
+	if err != nil {
+		return
+	}
+	if selected != "" {
+		EmptyMessage()
+		SuccessMessage("* %s would be selected for the project", PrimaryBold(selected))
+	}
+}
+
+// PrintProfilesTable prints the inspection profiles available for --profile-name.
+func PrintProfilesTable(profiles []profile) {
+	profilesTableData := pterm.TableData{
+		[]string{
+			PrimaryBold("Name"),
+			PrimaryBold("Source"),
+		},
+	}
+	for _, p := range profiles {
+		profilesTableData = append(profilesTableData, []string{p.Name, p.Source})
+	}
+
+	err := renderTable(profilesTableData)
+	if err != nil {
+		return
+	}
+	EmptyMessage()
+	SuccessMessage("Single inspections can be run with %s<inspection id>", PrimaryBold(singleInspectionProfilePrefix))
+}
+
+// printLinterScans prints the summary of a multi-linter scan and the errors of the failed runs.
+func printLinterScans(scans []linterScan) {
+	scansTableData := pterm.TableData{
+		[]string{
+			PrimaryBold("Linter"),
+			PrimaryBold("Problems"),
+			PrimaryBold("Exit code"),
+			PrimaryBold("Results"),
+		},
+	}
+	failed := 0
+	for _, s := range scans {
+		problems := strconv.Itoa(s.Problems)
+		if s.Err != nil {
+			problems = "-"
+			failed++
+		}
+		scansTableData = append(scansTableData, []string{s.Linter, problems, strconv.Itoa(s.ExitCode), s.ResultsDir})
+	}
+
+	EmptyMessage()
+	if err := renderTable(scansTableData); err != nil {
+		return
+	}
+	for _, s := range scans {
+		if s.Err != nil {
+			printError(fmt.Errorf("%s: %w", s.Linter, s.Err))
+		}
+	}
+	if failed > 0 {
+		ErrorMessage("%d of %s failed", failed, pluralize(len(scans), "linter", "linters"))
+	} else {
+		SuccessMessage("%s finished", pluralize(len(scans), "linter", "linters"))
+	}
+}
+
+// PrintSarifDiff prints the added/removed/unchanged problem counts and the list of added problems.
+func PrintSarifDiff(diff sarifDiff) {
+	for _, p := range diff.Added {
+		if jsonOutput {
+			logProblem(0, p.RuleId, p.Severity, p.Message, p.File, p.Line, p.Column)
+			continue
+		}
+		printHeader(p.Severity, p.RuleId, 0, "")
+		printPath(".", p.File, p.Line, p.Column)
+		fmt.Print(p.Message + "\n")
+	}
+	EmptyMessage()
+	if len(diff.Added) == 0 {
+		SuccessMessage("No new problems found")
+	} else {
+		ErrorMessage("%d new problem(s) found", len(diff.Added))
+	}
+	SuccessMessage(
+		"Added: %s, removed: %s, unchanged: %s",
+		PrimaryBold(strconv.Itoa(len(diff.Added))),
+		PrimaryBold(strconv.Itoa(len(diff.Removed))),
+		PrimaryBold(strconv.Itoa(diff.Unchanged)),
+	)
+}
+
 // getPlanMessage returns a message with the cost of the plan.
 func getPlanMessage(plan string, cost int, contributors int) string {
 	var costMessage string
@@ -0,0 +1,76 @@
+//go:build !windows
+
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// hostUser returns the user and group the results should belong to: the user who invoked sudo, if any,
+// otherwise the current user.
+func hostUser() (int, int) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid == 0 {
+		sudoUid, uidErr := strconv.Atoi(os.Getenv("SUDO_UID"))
+		sudoGid, gidErr := strconv.Atoi(os.Getenv("SUDO_GID"))
+		if uidErr == nil && gidErr == nil {
+			return sudoUid, sudoGid
+		}
+	}
+	return uid, gid
+}
+
+// normalizePermissions makes the files in dir owned by the given user and readable and writable by them,
+// directories also get the search permission. Returns the number of adjusted files.
+func normalizePermissions(dir string, uid int, gid int) (int, error) {
+	adjusted, failed := 0, 0
+	var firstErr error
+	fail := func(err error) {
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fail(err)
+			return nil
+		}
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		changed := false
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && (int(stat.Uid) != uid || int(stat.Gid) != gid) {
+			if err = os.Lchown(path, uid, gid); err != nil {
+				fail(err)
+				return nil
+			}
+			changed = true
+		}
+		required := fs.FileMode(0o600)
+		if d.IsDir() {
+			required = 0o700
+		}
+		if info.Mode().Perm()&required != required {
+			if err = os.Chmod(path, info.Mode().Perm()|required); err != nil {
+				fail(err)
+				return nil
+			}
+			changed = true
+		}
+		if changed {
+			adjusted++
+		}
+		return nil
+	})
+	if firstErr != nil {
+		return adjusted, fmt.Errorf("failed to adjust %d files: %w", failed, firstErr)
+	}
+	return adjusted, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePermissions(t *testing.T) {
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not adjusted on Windows")
+	}
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report")
+	if err := os.MkdirAll(report, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]os.FileMode{
+		filepath.Join(dir, QodanaSarifName):  0o200,
+		filepath.Join(dir, "log.txt"):        0o644,
+		filepath.Join(report, "result.json"): 0o400,
+	}
+	for path, mode := range files {
+		if err := os.WriteFile(path, []byte("{}"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(report, 0o500); err != nil {
+		t.Fatal(err)
+	}
+
+	adjusted, err := normalizePermissions(dir, os.Getuid(), os.Getgid())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, adjusted)
+	for path, mode := range map[string]os.FileMode{
+		filepath.Join(dir, QodanaSarifName):  0o600,
+		filepath.Join(dir, "log.txt"):        0o644,
+		filepath.Join(report, "result.json"): 0o600,
+		report:                               0o700,
+	} {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), path)
+	}
+
+	adjusted, err = normalizePermissions(dir, os.Getuid(), os.Getgid())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, adjusted)
+}
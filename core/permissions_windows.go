@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package core
+
+// hostUser is not used on Windows, the results are accessible by the invoking user.
+func hostUser() (int, int) {
+	return -1, -1
+}
+
+// normalizePermissions is a no-op on Windows.
+//
+//goland:noinspection GoUnusedParameter
+func normalizePermissions(dir string, uid int, gid int) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SortBySeverity = "severity"
+	SortByFile     = "file"
+	SortByRule     = "rule"
+	GroupByFile    = "file"
+	GroupByRule    = "rule"
+)
+
+// ValidateProblemsOrder checks the --sort-by and --group-by values, empty values are the defaults.
+func ValidateProblemsOrder(sortBy string, groupBy string) error {
+	switch sortBy {
+	case "", SortBySeverity, SortByFile, SortByRule:
+	default:
+		return fmt.Errorf("invalid --sort-by %q, expected one of: %s, %s, %s", sortBy, SortBySeverity, SortByFile, SortByRule)
+	}
+	switch groupBy {
+	case "", GroupByFile, GroupByRule:
+	default:
+		return fmt.Errorf("invalid --group-by %q, expected one of: %s, %s", groupBy, GroupByFile, GroupByRule)
+	}
+	return nil
+}
+
+// ProblemFilter selects the problems by severity or level and by rule, the filters are combined with AND.
+// Empty lists match all problems.
+type ProblemFilter struct {
+	Severities []string
+	Rules      []string
+}
+
+// Validate checks the filter severities are known Qodana severities or SARIF levels.
+func (f ProblemFilter) Validate() error {
+	for _, severity := range f.Severities {
+		if severityRank(severity) == len(severityOrder) {
+			return fmt.Errorf("invalid --severity %q, expected one of: %s", severity, strings.Join(severityOrder, ", "))
+		}
+	}
+	return nil
+}
+
+// matches returns true if the problem has one of the filter severities (or levels) and rules.
+func (f ProblemFilter) matches(p Result) bool {
+	if len(f.Rules) > 0 && !Contains(f.Rules, p.RuleId) {
+		return false
+	}
+	if len(f.Severities) == 0 {
+		return true
+	}
+	for _, severity := range f.Severities {
+		if strings.EqualFold(severity, p.Severity) || strings.EqualFold(severity, string(p.Level)) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the problems matching the filter and the number of the hidden ones.
+func (f ProblemFilter) apply(problems []Result) ([]Result, int) {
+	matched := make([]Result, 0, len(problems))
+	for _, p := range problems {
+		if f.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	return matched, len(problems) - len(matched)
+}
+
+// compareLocations orders problems by file, line and column.
+func compareLocations(a Result, b Result) int {
+	if c := strings.Compare(a.File, b.File); c != 0 {
+		return c
+	}
+	if a.Line != b.Line {
+		return a.Line - b.Line
+	}
+	return a.Column - b.Column
+}
+
+// sortProblems sorts the problems in place by severity (the most severe first, default), file or rule.
+// Ties are ordered by the location.
+func sortProblems(problems []Result, sortBy string) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		switch sortBy {
+		case SortByFile:
+		case SortByRule:
+			if a.RuleId != b.RuleId {
+				return a.RuleId < b.RuleId
+			}
+		default:
+			if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+				return ra < rb
+			}
+		}
+		return compareLocations(a, b) < 0
+	})
+}
+
+// limitProblems returns at most limit problems in the sortBy order, non-positive limit keeps all problems.
+func limitProblems(problems []Result, sortBy string, limit int) []Result {
+	if limit <= 0 || len(problems) <= limit {
+		return problems
+	}
+	sorted := make([]Result, len(problems))
+	copy(sorted, problems)
+	sortProblems(sorted, sortBy)
+	return sorted[:limit]
+}
+
+// problemGroup is a group of problems reported for the same file or rule.
+type problemGroup struct {
+	Key      string
+	Problems []Result
+}
+
+// groupProblems sorts the problems and groups them by file or rule, the groups are ordered by the key.
+// Without groupBy all problems are returned as one group with an empty key.
+func groupProblems(problems []Result, sortBy string, groupBy string) []problemGroup {
+	sorted := make([]Result, len(problems))
+	copy(sorted, problems)
+	sortProblems(sorted, sortBy)
+	if groupBy == "" {
+		return []problemGroup{{Problems: sorted}}
+	}
+	byKey := make(map[string]*problemGroup)
+	keys := make([]string, 0)
+	for _, p := range sorted {
+		key := p.File
+		if groupBy == GroupByRule {
+			key = p.RuleId
+		}
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &problemGroup{Key: key}
+			keys = append(keys, key)
+		}
+		byKey[key].Problems = append(byKey[key].Problems, p)
+	}
+	sort.Strings(keys)
+	groups := make([]problemGroup, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, *byKey[key])
+	}
+	return groups
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupProblems(t *testing.T) {
+	low := Result{RuleId: "PyUnusedLocal", Severity: "Low", Location: Location{File: "a.py", Line: 3}}
+	critical := Result{RuleId: "PyTypeChecker", Severity: "Critical", Location: Location{File: "b.py", Line: 10}}
+	high := Result{RuleId: "PyTypeChecker", Severity: "High", Location: Location{File: "a.py", Line: 20}}
+	moderate := Result{RuleId: "PyUnusedLocal", Severity: "Moderate", Location: Location{File: "a.py", Line: 1}}
+	problems := []Result{low, critical, high, moderate}
+
+	tests := []struct {
+		name     string
+		sortBy   string
+		groupBy  string
+		expected []problemGroup
+	}{
+		{
+			name:     "severity by default",
+			expected: []problemGroup{{Problems: []Result{critical, high, moderate, low}}},
+		},
+		{
+			name:     "file",
+			sortBy:   SortByFile,
+			expected: []problemGroup{{Problems: []Result{moderate, low, high, critical}}},
+		},
+		{
+			name:     "rule",
+			sortBy:   SortByRule,
+			expected: []problemGroup{{Problems: []Result{high, critical, moderate, low}}},
+		},
+		{
+			name:    "severity grouped by file",
+			sortBy:  SortBySeverity,
+			groupBy: GroupByFile,
+			expected: []problemGroup{
+				{Key: "a.py", Problems: []Result{high, moderate, low}},
+				{Key: "b.py", Problems: []Result{critical}},
+			},
+		},
+		{
+			name:    "file grouped by rule",
+			sortBy:  SortByFile,
+			groupBy: GroupByRule,
+			expected: []problemGroup{
+				{Key: "PyTypeChecker", Problems: []Result{high, critical}},
+				{Key: "PyUnusedLocal", Problems: []Result{moderate, low}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, groupProblems(problems, tt.sortBy, tt.groupBy))
+		})
+	}
+	assert.Equal(t, []Result{low, critical, high, moderate}, problems)
+}
+
+func TestLimitProblems(t *testing.T) {
+	low := Result{RuleId: "PyUnusedLocal", Severity: "Low", Location: Location{File: "a.py", Line: 3}}
+	critical := Result{RuleId: "PyTypeChecker", Severity: "Critical", Location: Location{File: "b.py", Line: 10}}
+	high := Result{RuleId: "PyTypeChecker", Severity: "High", Location: Location{File: "a.py", Line: 20}}
+	problems := []Result{low, critical, high}
+
+	assert.Equal(t, []Result{critical, high}, limitProblems(problems, SortBySeverity, 2))
+	assert.Equal(t, []Result{low}, limitProblems(problems, SortByFile, 1))
+	assert.Equal(t, problems, limitProblems(problems, SortBySeverity, 0))
+	assert.Equal(t, problems, limitProblems(problems, SortBySeverity, 5))
+	assert.Equal(t, []Result{low, critical, high}, problems)
+}
+
+func TestProblemFilter(t *testing.T) {
+	critical := Result{RuleId: "PyTypeChecker", Level: LevelError, Severity: "Critical"}
+	moderate := Result{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate"}
+	note := Result{RuleId: "PyTypeChecker", Level: LevelNote, Severity: "Low"}
+	problems := []Result{critical, moderate, note}
+
+	tests := []struct {
+		name     string
+		filter   ProblemFilter
+		expected []Result
+	}{
+		{"empty", ProblemFilter{}, problems},
+		{"levels", ProblemFilter{Severities: []string{"error", "warning"}}, []Result{critical, moderate}},
+		{"severity in another case", ProblemFilter{Severities: []string{"LOW"}}, []Result{note}},
+		{"rule", ProblemFilter{Rules: []string{"PyTypeChecker"}}, []Result{critical, note}},
+		{"severity and rule", ProblemFilter{Severities: []string{"note"}, Rules: []string{"PyTypeChecker"}}, []Result{note}},
+		{"nothing matched", ProblemFilter{Severities: []string{"warning"}, Rules: []string{"PyTypeChecker"}}, []Result{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, hidden := tt.filter.apply(problems)
+			assert.Equal(t, tt.expected, matched)
+			assert.Equal(t, len(problems)-len(tt.expected), hidden)
+		})
+	}
+
+	assert.NoError(t, ProblemFilter{Severities: []string{"High", "note"}}.Validate())
+	assert.Error(t, ProblemFilter{Severities: []string{"fatal"}}.Validate())
+}
+
+func TestValidateProblemsOrder(t *testing.T) {
+	assert.NoError(t, ValidateProblemsOrder("", ""))
+	assert.NoError(t, ValidateProblemsOrder(SortByRule, GroupByFile))
+	assert.Error(t, ValidateProblemsOrder("line", ""))
+	assert.Error(t, ValidateProblemsOrder(SortBySeverity, SortBySeverity))
+}
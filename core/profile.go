@@ -0,0 +1,276 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StubProfileName is the name of the starter inspection profile generated by qodana init --stub-profile.
+const StubProfileName = "qodana-starter"
+
+// singleInspectionProfilePrefix is the prefix of the built-in profiles running one inspection, e.g. qodana.single:ConstantValue.
+const singleInspectionProfilePrefix = "qodana.single:"
+
+// inspectRuleProfileName is the name of the profile generated for --inspect-rule.
+const inspectRuleProfileName = "qodana.inspect-rule"
+
+// builtinProfiles are the profiles available in every Qodana linter, they are used if the profiles can't be
+// discovered from the linter image.
+var builtinProfiles = []string{"empty", "qodana.recommended", "qodana.sanity", "qodana.starter"}
+
+// profileNamePattern extracts the profile name from an inspection profile file.
+var profileNamePattern = regexp.MustCompile(`<option\s+name="myName"\s+value="([^"]*)"`)
+
+// inspectionIdPattern matches inspection ids, e.g. ConstantValue or UnusedMember.Global.
+var inspectionIdPattern = regexp.MustCompile(`^[A-Za-z][\w.\-]*$`)
+
+// profile is an inspection profile that can be used with --profile-name.
+type profile struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // the linter image, "built-in" or the project-relative path of the profile file
+}
+
+// projectProfiles returns the inspection profiles stored in .idea/inspectionProfiles of the project.
+func projectProfiles(projectDir string) []profile {
+	dir := filepath.Join(".idea", "inspectionProfiles")
+	entries, err := os.ReadDir(filepath.Join(projectDir, dir))
+	if err != nil {
+		return nil
+	}
+	profiles := make([]profile, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".xml" || entry.Name() == "profiles_settings.xml" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".xml")
+		if content, err := os.ReadFile(filepath.Join(projectDir, dir, entry.Name())); err == nil {
+			if m := profileNamePattern.FindSubmatch(content); m != nil {
+				name = string(m[1])
+			}
+		}
+		profiles = append(profiles, profile{Name: name, Source: filepath.ToSlash(filepath.Join(dir, entry.Name()))})
+	}
+	return profiles
+}
+
+// GetProfiles returns the profiles of the linter and the profiles of the project. The linter profiles are discovered
+// from the linter image, the static builtinProfiles are used without the linter or if the image is not available.
+func GetProfiles(projectDir string, linter string) []profile {
+	names, source := builtinProfiles, "built-in"
+	if linter != "" {
+		if discovered, err := linterImageProfiles(linter); err != nil {
+			log.Debugf("Could not list the profiles of %s, the built-in profiles are used: %s", linter, err)
+		} else {
+			names, source = discovered, linter
+		}
+	}
+	profiles := make([]profile, 0, len(names))
+	for _, name := range names {
+		profiles = append(profiles, profile{Name: name, Source: source})
+	}
+	return append(profiles, projectProfiles(projectDir)...)
+}
+
+// ProfilesToJSON converts the given profiles to JSON.
+func ProfilesToJSON(profiles []profile) (string, error) {
+	out, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return string(out), nil
+}
+
+// ProfileNames returns the names of the linter and the project profiles, see GetProfiles.
+func ProfileNames(projectDir string, linter string) []string {
+	names := make([]string, 0)
+	for _, p := range GetProfiles(projectDir, linter) {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// checkProfileName returns a warning if the profile name is neither a built-in nor a project profile,
+// suggesting the closest known name for likely typos. An empty string is returned for known profiles.
+func checkProfileName(name string, known []string) string {
+	if Contains(known, name) || strings.HasPrefix(name, singleInspectionProfilePrefix) {
+		return ""
+	}
+	warning := fmt.Sprintf("Profile %s is neither a built-in profile nor found in .idea/inspectionProfiles", name)
+	closest, distance := "", len(name)
+	for _, k := range known {
+		if d := editDistance(lower(name), lower(k)); d < distance {
+			closest, distance = k, d
+		}
+	}
+	if closest != "" && distance <= 3 {
+		warning += fmt.Sprintf(", did you mean %s?", closest)
+	}
+	return warning
+}
+
+// recommendedInspections are the inspections enabled in the starter profile for each detected language.
+var recommendedInspections = map[string][]string{
+	"Java":              {"ConstantValue", "DataFlowIssue", "EqualsWhichDoesntCheckParameterClass", "NullableProblems", "StringEquality", "UNUSED_IMPORT"},
+	"Kotlin":            {"RedundantSemicolon", "RemoveRedundantQualifierName", "UnusedVariable"},
+	"PHP":               {"PhpUndefinedMethodInspection", "PhpUndefinedVariableInspection", "PhpUnusedLocalVariableInspection"},
+	"Python":            {"PyArgumentListInspection", "PyUnresolvedReferencesInspection", "PyUnusedLocalInspection"},
+	"JavaScript":        {"ES6UnusedImports", "JSUnusedLocalSymbols"},
+	"TypeScript":        {"ES6UnusedImports", "JSUnusedLocalSymbols", "TypeScriptValidateTypes"},
+	"Go":                {"GoUnhandledErrorResult", "GoUnusedParameter", "GoUnusedVariable"},
+	"C#":                {"RedundantUsingDirective", "UnusedMember.Global", "UnusedVariable"},
+	"Visual Basic .NET": {"VBUnusedImportsClause", "UnusedVariable"},
+}
+
+// stubProfilePath returns the project-relative path to the starter profile.
+func stubProfilePath() string {
+	return filepath.Join(".idea", "inspectionProfiles", StubProfileName+".xml")
+}
+
+// stubProfileInspections returns the sorted recommended inspections for the given languages.
+func stubProfileInspections(languages []string) []string {
+	seen := make(map[string]bool)
+	inspections := make([]string, 0)
+	for _, language := range languages {
+		for _, inspection := range recommendedInspections[language] {
+			if !seen[inspection] {
+				seen[inspection] = true
+				inspections = append(inspections, inspection)
+			}
+		}
+	}
+	sort.Strings(inspections)
+	return inspections
+}
+
+// stubProfileXml returns the inspection profile enabling the given inspections on top of the default profile.
+func stubProfileXml(inspections []string) string {
+	var b strings.Builder
+	b.WriteString("<component name=\"InspectionProjectProfileManager\">\n")
+	b.WriteString("  <profile version=\"1.0\">\n")
+	b.WriteString(fmt.Sprintf("    <option name=\"myName\" value=\"%s\" />\n", StubProfileName))
+	for _, inspection := range inspections {
+		b.WriteString(fmt.Sprintf("    <inspection_tool class=\"%s\" enabled=\"true\" level=\"WARNING\" enabled_by_default=\"true\" />\n", inspection))
+	}
+	b.WriteString("  </profile>\n")
+	b.WriteString("</component>\n")
+	return b.String()
+}
+
+// WriteStubProfile writes the starter inspection profile for the languages of the configured analyzer detected
+// in the project and references it from qodana.yaml. It returns the profile path, or an empty string if the profile
+// was not written because qodana.yaml already has a profile or the starter profile exists and force is not set.
+func WriteStubProfile(projectDir string, yamlName string, force bool) (string, error) {
+	qodanaYaml := LoadQodanaYaml(filepath.Join(projectDir, yamlName))
+	relPath := stubProfilePath()
+	path := filepath.Join(projectDir, relPath)
+	if !force {
+		if qodanaYaml.Profile.Name != "" || qodanaYaml.Profile.Path != "" {
+			return "", nil
+		}
+		if _, err := os.Stat(path); err == nil {
+			return "", nil
+		}
+	}
+
+	opts := &QodanaOptions{Linter: qodanaYaml.Linter, Ide: qodanaYaml.Ide}
+	product := opts.guessProduct()
+	if product == "" {
+		return "", errors.New("no linter is configured in " + yamlName + ", run qodana init first")
+	}
+	languages := make([]string, 0)
+	for _, language := range detectLanguages(projectDir) {
+		if Contains(codeLanguages(product), language) {
+			languages = append(languages, language)
+		}
+	}
+	if len(languages) == 0 {
+		languages = codeLanguages(product)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(stubProfileXml(stubProfileInspections(languages))), 0o644); err != nil {
+		return "", err
+	}
+	qodanaYaml.Profile = Profile{Path: filepath.ToSlash(relPath)}
+	if err := qodanaYaml.writeConfig(filepath.Join(projectDir, yamlName)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// inspectRuleProfileYaml returns the YAML profile enabling only the given inspection.
+func inspectRuleProfileYaml(rule string) string {
+	return fmt.Sprintf("name: %s\nbaseProfile: empty\ninspections:\n  - inspection: %s\n    enabled: true\n", inspectRuleProfileName, rule)
+}
+
+// resolveInspectRule generates the profile running only the --inspect-rule inspection and uses it as --profile-path.
+func (o *QodanaOptions) resolveInspectRule() error {
+	if o.InspectRule == "" {
+		return nil
+	}
+	if !inspectionIdPattern.MatchString(o.InspectRule) {
+		return configErrorf("Invalid --inspect-rule %q: expected an inspection id, e.g. ConstantValue", o.InspectRule)
+	}
+	if o.ProfileName != "" {
+		WarningMessage("--profile-name %s is ignored, only the %s inspection is run\n", o.ProfileName, o.InspectRule)
+		o.ProfileName = ""
+	}
+	path := filepath.Join(o.GetLinterDir(), "inspect-rule", inspectRuleProfileName+".yaml")
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = os.WriteFile(path, []byte(inspectRuleProfileYaml(o.InspectRule)), 0o644)
+	}
+	if err != nil {
+		return configErrorf("Could not write the profile for --inspect-rule: %s", err)
+	}
+	o.ProfilePath = path
+	return nil
+}
+
+// CheckInspectRuleResults warns if the --inspect-rule inspection reported no problems: the id is case-sensitive,
+// and an inspection not available in the linter is silently skipped.
+func CheckInspectRuleResults(sarifPath string, options *QodanaOptions) {
+	if options.InspectRule == "" {
+		return
+	}
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range problems {
+		if p.RuleId == options.InspectRule {
+			return
+		}
+	}
+	WarningMessage(
+		"The %s inspection reported no problems: check the inspection id (it is case-sensitive) is available in %s, otherwise the inspection is not run",
+		options.InspectRule,
+		options.analyzerName(),
+	)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStubProfile(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "main.py"), []byte("print('hello')\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("linter: "+Image(QDPY)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := WriteStubProfile(projectDir, "qodana.yaml", false)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectDir, stubProfilePath()), path)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `<inspection_tool class="PyUnresolvedReferencesInspection" enabled="true"`)
+	assert.NotContains(t, string(content), "GoUnusedVariable")
+	assert.Equal(t, ".idea/inspectionProfiles/qodana-starter.xml", LoadQodanaYaml(filepath.Join(projectDir, "qodana.yaml")).Profile.Path)
+
+	path, err = WriteStubProfile(projectDir, "qodana.yaml", false)
+	assert.NoError(t, err)
+	assert.Empty(t, path, "existing profile is kept without force")
+
+	path, err = WriteStubProfile(projectDir, "qodana.yaml", true)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, path)
+}
+
+func TestWriteStubProfileWithoutLinter(t *testing.T) {
+	_, err := WriteStubProfile(t.TempDir(), "qodana.yaml", false)
+	assert.Error(t, err)
+}
+
+func TestGetProfiles(t *testing.T) {
+	projectDir := t.TempDir()
+	dir := filepath.Join(projectDir, ".idea", "inspectionProfiles")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"Project_Default.xml":   `<component name="InspectionProjectProfileManager"><profile version="1.0"><option name="myName" value="Project Default" /></profile></component>`,
+		"strict.xml":            `<component name="InspectionProjectProfileManager"><profile version="1.0" /></component>`,
+		"profiles_settings.xml": `<component name="InspectionProjectProfileManager"><settings /></component>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, []string{"empty", "qodana.recommended", "qodana.sanity", "qodana.starter", "Project Default", "strict"}, ProfileNames(projectDir, ""))
+	assert.Equal(t, builtinProfiles, ProfileNames(t.TempDir(), ""))
+
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "docker.sock"))
+	profiles := GetProfiles(t.TempDir(), "jvm")
+	assert.Len(t, profiles, len(builtinProfiles), "the built-in profiles are used if the image is not available")
+	assert.Equal(t, "built-in", profiles[0].Source)
+}
+
+func TestParseImageProfiles(t *testing.T) {
+	output := "/opt/idea/plugins/qodana/profiles/qodana.recommended.yaml\n" +
+		"profiles/qodana.starter.yaml\n" +
+		"com/intellij/profiles/qodana.sanity.xml\n" +
+		"profiles/qodana.recommended.yaml\n\n"
+	assert.Equal(t, []string{"qodana.recommended", "qodana.sanity", "qodana.starter"}, parseImageProfiles(output))
+	assert.Empty(t, parseImageProfiles(""))
+}
+
+func TestCachedImageProfiles(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "profiles", "sha256-abc.json")
+	queries := 0
+	query := func() ([]string, error) {
+		queries++
+		return []string{"qodana.recommended", "qodana.jvm"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		names, err := cachedImageProfiles(cachePath, query)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qodana.recommended", "qodana.jvm"}, names)
+	}
+	assert.Equal(t, 1, queries, "the image is queried once per digest")
+
+	_, err := cachedImageProfiles(filepath.Join(t.TempDir(), "empty.json"), func() ([]string, error) { return nil, nil })
+	assert.Error(t, err, "an image without profiles is not cached")
+	_, err = cachedImageProfiles(filepath.Join(t.TempDir(), "failed.json"), func() ([]string, error) { return nil, errors.New("no sh") })
+	assert.EqualError(t, err, "no sh")
+}
+
+func TestCheckProfileName(t *testing.T) {
+	known := append(builtinProfiles, "Project Default")
+	tests := []struct {
+		name     string
+		profile  string
+		expected string
+	}{
+		{"built-in", "qodana.starter", ""},
+		{"project", "Project Default", ""},
+		{"single inspection", "qodana.single:ConstantValue", ""},
+		{"typo", "qodana.recomended", "Profile qodana.recomended is neither a built-in profile nor found in .idea/inspectionProfiles, did you mean qodana.recommended?"},
+		{"case", "project default", "Profile project default is neither a built-in profile nor found in .idea/inspectionProfiles, did you mean Project Default?"},
+		{"unknown", "custom", "Profile custom is neither a built-in profile nor found in .idea/inspectionProfiles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, checkProfileName(tt.profile, known))
+		})
+	}
+}
+
+func TestResolveInspectRule(t *testing.T) {
+	options := &QodanaOptions{
+		ProjectDir:  t.TempDir(),
+		Linter:      "jetbrains/qodana-jvm:latest",
+		CacheDir:    filepath.Join(t.TempDir(), "linter", "cache"),
+		ProfileName: "qodana.recommended",
+		InspectRule: "ConstantValue",
+	}
+	assert.NoError(t, options.resolveInspectRule())
+	assert.Equal(t, "", options.ProfileName)
+	content, err := os.ReadFile(options.ProfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "name: qodana.inspect-rule\nbaseProfile: empty\ninspections:\n  - inspection: ConstantValue\n    enabled: true\n", string(content))
+
+	assert.True(t, inspectionIdPattern.MatchString("UnusedMember.Global"))
+	assert.False(t, inspectionIdPattern.MatchString("Constant Value"))
+	assert.False(t, inspectionIdPattern.MatchString("1st"))
+}
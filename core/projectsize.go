@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// DefaultMaxFiles is the default --max-files: high enough for large repositories, but it stops a scan of e.g. $HOME.
+const DefaultMaxFiles = 500000
+
+// countProjectFiles counts the project files, the .git directory, the paths matched by the excludes and the skipped
+// directories are not counted. The walk stops once the count exceeds limit.
+func countProjectFiles(projectDir string, excludes []string, limit int, skip ...string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(projectDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		excluded := d.IsDir() && (d.Name() == ".git" || Contains(skip, p))
+		for _, pattern := range excludes {
+			excluded = excluded || matchesExclude(pattern, rel)
+		}
+		if excluded {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			count++
+			if count > limit {
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// checkProjectSize stops the run if the project has more than MaxFiles files, so a wrong --project-dir
+// (e.g. the home directory) doesn't start a very long analysis. --force and --files skip the check.
+func (o *QodanaOptions) checkProjectSize() error {
+	if o.MaxFiles <= 0 || o.Force || len(o.Files) > 0 {
+		return nil
+	}
+	skip := make([]string, 0)
+	for _, dir := range []string{o.ResultsDir, o.CacheDir, o.ReportDir} {
+		if abs, err := filepath.Abs(dir); err == nil {
+			skip = append(skip, abs)
+		}
+	}
+	projectDir, err := filepath.Abs(o.ProjectDir)
+	if err != nil {
+		return configErrorf("Could not count the project files: %s", err)
+	}
+	count, err := countProjectFiles(projectDir, o.Excludes, o.MaxFiles, skip...)
+	if err != nil {
+		return configErrorf("Could not count the project files: %s", err)
+	}
+	if count > o.MaxFiles {
+		return configErrorf(
+			"%s has more than %d files, check --project-dir or exclude the generated files with --exclude. Pass a higher --max-files or --force to analyze it anyway",
+			o.ProjectDir,
+			o.MaxFiles,
+		)
+	}
+	return nil
+}
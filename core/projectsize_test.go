@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountProjectFiles(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, name := range []string{"main.py", "src/util.py", "node_modules/lib/index.js", "node_modules/lib/util.js", ".git/HEAD", "results/qodana.sarif.json"} {
+		path := filepath.Join(projectDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := countProjectFiles(projectDir, nil, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	count, err = countProjectFiles(projectDir, []string{"node_modules"}, 100, filepath.Join(projectDir, "results"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = countProjectFiles(projectDir, nil, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
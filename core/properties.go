@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2023/cloud"
 	log "github.com/sirupsen/logrus"
@@ -170,6 +171,10 @@ func GetProperties(opts *QodanaOptions, yamlProps map[string]string, dotNetOptio
 		props[k] = v
 	}
 
+	if opts.NoStatistics {
+		props["-Didea.headless.enable.statistics"] = "false"
+	}
+
 	for k, v := range props {
 		lines = append(lines, fmt.Sprintf("%s=%s", k, v))
 	}
@@ -191,3 +196,29 @@ func writeProperties(opts *QodanaOptions) { // opts.confDirPath(Prod.Version)  o
 		log.Fatal(err)
 	}
 }
+
+// parsePropertyFile reads key=value IDE properties from the file, blank lines and lines starting with # or ! are skipped.
+func parsePropertyFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	properties := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected property.name=value, got %q", path, lineNumber, line)
+		}
+		properties = append(properties, key+"="+strings.TrimSpace(value))
+	}
+	return properties, scanner.Err()
+}
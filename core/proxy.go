@@ -0,0 +1,169 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/v2023/cloud"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	httpProxyEnv  = "HTTP_PROXY"
+	httpsProxyEnv = "HTTPS_PROXY"
+	noProxyEnv    = "NO_PROXY"
+)
+
+// proxyConfig is the proxy configuration set by ConfigureProxy, until then the environment is used.
+var proxyConfig atomic.Pointer[httpproxy.Config]
+
+// proxyTransport is the transport of the CLI HTTP requests, so they go through the configured proxy without
+// changing http.DefaultTransport of the code embedding core.
+var proxyTransport = newProxyTransport()
+
+func newProxyTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFromConfig
+	return transport
+}
+
+// httpClient returns the client for the CLI HTTP requests with the given timeout, 0 means no timeout.
+func httpClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: proxyTransport, Timeout: timeout}
+}
+
+// proxyFromConfig is used by proxyTransport, so all CLI requests go through the configured proxy.
+func proxyFromConfig(req *http.Request) (*url.URL, error) {
+	config := proxyConfig.Load()
+	if config == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	return config.ProxyFunc()(req.URL)
+}
+
+// proxySettings returns the proxy configuration from the options, unset options fall back to the environment.
+func (o *QodanaOptions) proxySettings() *httpproxy.Config {
+	config := httpproxy.FromEnvironment()
+	if o.HttpProxy != "" {
+		config.HTTPProxy = o.HttpProxy
+	}
+	if o.HttpsProxy != "" {
+		config.HTTPSProxy = o.HttpsProxy
+	}
+	if o.NoProxy != "" {
+		config.NoProxy = o.NoProxy
+	}
+	return config
+}
+
+// ConfigureProxy makes the CLI HTTP requests use the proxy from the options.
+func (o *QodanaOptions) ConfigureProxy() {
+	config := o.proxySettings()
+	proxyConfig.Store(config)
+	cloud.Transport = proxyTransport
+	if config.HTTPProxy != "" {
+		log.Debugf("Using HTTP proxy %s", maskProxyCredentials(config.HTTPProxy))
+	}
+	if config.HTTPSProxy != "" {
+		log.Debugf("Using HTTPS proxy %s", maskProxyCredentials(config.HTTPSProxy))
+	}
+}
+
+// setProxyEnv passes the proxy environment variables to the linter, both upper and lower case variants are set.
+func (o *QodanaOptions) setProxyEnv(setEnvironmentFunc func(string, string)) {
+	config := o.proxySettings()
+	for _, env := range [][2]string{
+		{httpProxyEnv, config.HTTPProxy},
+		{httpsProxyEnv, config.HTTPSProxy},
+		{noProxyEnv, config.NoProxy},
+	} {
+		setEnvironmentFunc(env[0], env[1])
+		setEnvironmentFunc(strings.ToLower(env[0]), env[1])
+	}
+}
+
+// maskProxyCredentials hides the password from the proxy URL, so it can be logged.
+func maskProxyCredentials(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if _, ok := u.User.Password(); !ok {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+// maskProxyEnv hides the proxy credentials in a KEY=VALUE environment variable.
+func maskProxyEnv(env string) string {
+	key, value, found := strings.Cut(env, "=")
+	if !found {
+		return env
+	}
+	switch strings.ToUpper(key) {
+	case httpProxyEnv, httpsProxyEnv:
+		return key + "=" + maskProxyCredentials(value)
+	}
+	return env
+}
+
+// proxyJavaArgs returns JVM system properties for the proxy, Java does not read the proxy environment variables.
+func (o *QodanaOptions) proxyJavaArgs() []string {
+	config := o.proxySettings()
+	args := make([]string, 0)
+	for _, p := range [][2]string{{"http", config.HTTPProxy}, {"https", config.HTTPSProxy}} {
+		scheme, proxy := p[0], p[1]
+		if proxy != "" && !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		u, err := url.Parse(proxy)
+		if proxy == "" || err != nil || u.Hostname() == "" {
+			continue
+		}
+		args = append(args, "-D"+scheme+".proxyHost="+u.Hostname())
+		if u.Port() != "" {
+			args = append(args, "-D"+scheme+".proxyPort="+u.Port())
+		}
+	}
+	if config.NoProxy != "" {
+		hosts := make([]string, 0)
+		for _, host := range strings.Split(config.NoProxy, ",") {
+			host = strings.TrimSpace(host)
+			if host == "" {
+				continue
+			}
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if strings.HasPrefix(host, ".") {
+				host = "*" + host
+			}
+			hosts = append(hosts, host)
+		}
+		if len(hosts) > 0 {
+			args = append(args, "-Dhttp.nonProxyHosts="+strings.Join(hosts, "|"))
+		}
+	}
+	return args
+}
@@ -30,7 +30,6 @@ import (
 	cp "github.com/otiai10/copy"
 	log "github.com/sirupsen/logrus"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 )
@@ -80,21 +79,21 @@ func SendReport(opts *QodanaOptions, token string) {
 
 	publisherCommand := getPublisherArgs(Prod.JbrJava(), publisherPath, opts, token, cloud.GetEnvWithDefault(cloud.QodanaEndpoint, cloud.DefaultEndpoint))
 	if res := RunCmd("", publisherCommand...); res > 0 {
-		os.Exit(res)
+		Exit(res)
 	}
 }
 
 // getPublisherArgs returns args for the publisher.
 func getPublisherArgs(java string, publisher string, opts *QodanaOptions, token string, endpoint string) []string {
-	publisherArgs := []string{
-		QuoteForWindows(java),
+	publisherArgs := append([]string{QuoteForWindows(java)}, opts.proxyJavaArgs()...)
+	publisherArgs = append(publisherArgs,
 		"-jar",
 		QuoteForWindows(publisher),
 		"--analysis-id", opts.AnalysisId,
 		"--sources-path", QuoteForWindows(opts.ProjectDir),
 		"--report-path", QuoteForWindows(opts.ReportResultsPath()),
 		"--token", token,
-	}
+	)
 	var tools []string
 	tool := os.Getenv(QodanaToolEnv)
 	if tool != "" {
@@ -112,7 +111,7 @@ func getPublisherArgs(java string, publisher string, opts *QodanaOptions, token
 }
 
 func publisherVersion() versioning {
-	resp, err := http.Get("https://packages.jetbrains.team/maven/p/ij/intellij-dependencies/org/jetbrains/qodana/publisher/maven-metadata.xml")
+	resp, err := httpClient(0).Get("https://packages.jetbrains.team/maven/p/ij/intellij-dependencies/org/jetbrains/qodana/publisher/maven-metadata.xml")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -157,7 +156,7 @@ func verifyMd5Hash(version string, path string) {
 		log.Fatal(err)
 	}
 	url := getPublisherUrl(version) + ".md5"
-	resp, err := http.Get(url)
+	resp, err := httpClient(0).Get(url)
 	if err != nil {
 		log.Fatalf("Error downloading md5 hash: %v", err)
 	}
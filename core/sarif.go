@@ -17,9 +17,18 @@
 package core
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/owenrumney/go-sarif/v2/sarif"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	cp "github.com/otiai10/copy"
 )
 
 const (
@@ -29,41 +38,414 @@ const (
 	baselineStateNew = "new"
 	// baselineStateUnchanged unchanged baseline state
 	baselineStateUnchanged = "unchanged"
+	// baselineStateAbsent absent baseline state (present only in the baseline)
+	baselineStateAbsent = "absent"
+	// sarifExtension is the extension of SARIF reports
+	sarifExtension = ".sarif.json"
+	// equalIndicatorFingerprint is the partial fingerprint Qodana uses to match problems between runs
+	equalIndicatorFingerprint = "equalIndicator/v1"
+	// qodanaSeverityProperty is the result property Qodana stores its own severity in
+	qodanaSeverityProperty = "qodanaSeverity"
 )
 
-// ReadSarif prints Qodana Scan result into stdout
-func ReadSarif(sarifPath string, printProblems bool) {
-	newProblems := 0
+// Level is the SARIF level of a result.
+type Level string
+
+const (
+	// LevelNone is the level of a result that is not a problem.
+	LevelNone Level = "none"
+	// LevelNote is the level of a minor problem.
+	LevelNote Level = "note"
+	// LevelWarning is the level of a problem.
+	LevelWarning Level = "warning"
+	// LevelError is the level of a serious problem.
+	LevelError Level = "error"
+)
+
+// SarifReport is a typed representation of a SARIF report.
+type SarifReport struct {
+	Runs []Run `json:"runs"`
+}
+
+// Run is a single analysis run of a SARIF report.
+type Run struct {
+	Tool    string   `json:"tool"`
+	Results []Result `json:"results"`
+	// Suppressed is the number of suppressed results, they are not included in Results.
+	Suppressed int `json:"suppressed,omitempty"`
+	// Date is when the run finished (or started if the end is not reported), nil if the report has no invocations.
+	Date *time.Time `json:"date,omitempty"`
+}
+
+// Location is the location of a result in the project.
+type Location struct {
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Column      int    `json:"column,omitempty"`
+	ContextLine int    `json:"-"`
+	Snippet     string `json:"-"`
+}
+
+// Result is a problem found by the analysis, missing fields are left empty.
+type Result struct {
+	RuleId   string `json:"ruleId"`
+	Level    Level  `json:"level,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Location
+	BaselineState string `json:"baselineState,omitempty"`
+	Fingerprint   string `json:"fingerprint,omitempty"`
+}
+
+// newResult converts the SARIF result, the severity is taken from Qodana properties and falls back to the level.
+func newResult(r *sarif.Result) Result {
+	p := Result{}
+	if r.RuleID != nil {
+		p.RuleId = *r.RuleID
+	}
+	if r.Message.Text != nil {
+		p.Message = *r.Message.Text
+	}
+	if r.Level != nil {
+		p.Level = Level(*r.Level)
+	}
+	if severity, ok := r.Properties[qodanaSeverityProperty].(string); ok && severity != "" {
+		p.Severity = severity
+	} else {
+		p.Severity = string(p.Level)
+	}
+	if r.BaselineState != nil {
+		p.BaselineState = *r.BaselineState
+	}
+	if fingerprint, ok := r.PartialFingerprints[equalIndicatorFingerprint].(string); ok {
+		p.Fingerprint = fingerprint
+	}
+	if len(r.Locations) > 0 && r.Locations[0].PhysicalLocation != nil {
+		p.Location = newLocation(r.Locations[0].PhysicalLocation)
+	}
+	return p
+}
+
+// newLocation converts the SARIF physical location.
+func newLocation(location *sarif.PhysicalLocation) Location {
+	l := Location{}
+	if location.ArtifactLocation != nil && location.ArtifactLocation.URI != nil {
+		l.File = *location.ArtifactLocation.URI
+	}
+	if location.Region != nil {
+		if location.Region.StartLine != nil {
+			l.Line = *location.Region.StartLine
+		}
+		if location.Region.StartColumn != nil {
+			l.Column = *location.Region.StartColumn
+		}
+	}
+	if location.ContextRegion != nil {
+		if location.ContextRegion.StartLine != nil {
+			l.ContextLine = *location.ContextRegion.StartLine
+		}
+		if location.ContextRegion.Snippet != nil && location.ContextRegion.Snippet.Text != nil {
+			l.Snippet = *location.ContextRegion.Snippet.Text
+		}
+	}
+	return l
+}
+
+// supportedSarifVersion is the SARIF version the CLI is tested with.
+const supportedSarifVersion = "2.1.0"
+
+// sarifSchemaVersion extracts the version from the $schema URL, e.g. .../sarif-schema-2.1.0.json.
+var sarifSchemaVersion = regexp.MustCompile(`(\d+\.\d+\.\d+)`)
+
+// warnedSarifVersions are the unsupported SARIF versions already reported, each one is reported once.
+var warnedSarifVersions = make(map[string]bool)
+
+// sarifVersion returns the SARIF version of the report: the version field or the version from the $schema URL.
+func sarifVersion(s *sarif.Report) string {
+	if s.Version != "" {
+		return s.Version
+	}
+	return sarifSchemaVersion.FindString(s.Schema)
+}
+
+// checkSarifVersion warns if the report has a SARIF version the CLI is not tested with, the report is still read.
+func checkSarifVersion(sarifPath string, s *sarif.Report) {
+	version := sarifVersion(s)
+	log.Debugf("SARIF %s: version %q, schema %q", sarifPath, s.Version, s.Schema)
+	if version == supportedSarifVersion || warnedSarifVersions[version] {
+		return
+	}
+	warnedSarifVersions[version] = true
+	if version == "" {
+		WarningMessage("%s doesn't specify the SARIF version, it may be displayed incorrectly\n", sarifPath)
+	} else {
+		WarningMessage(
+			"%s has SARIF version %s, but the CLI is tested with %s: the report may be displayed incorrectly, check for a newer CLI version\n",
+			sarifPath,
+			version,
+			supportedSarifVersion,
+		)
+	}
+}
+
+// ValidateSarif checks that the file is valid JSON with the top-level runs array,
+// so a truncated report (e.g. of a killed scan) is reported as ErrIncompleteResults instead of a parsing error.
+func ValidateSarif(sarifPath string) error {
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		return err
+	}
+	var report struct {
+		Runs []json.RawMessage `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil || report.Runs == nil {
+		return fmt.Errorf("%s: %w", sarifPath, ErrIncompleteResults)
+	}
+	return nil
+}
+
+// RequireCompleteSarif exits with an actionable message if the SARIF file can't be read or is incomplete.
+func RequireCompleteSarif(sarifPath string) {
+	err := ValidateSarif(sarifPath)
+	if err == nil {
+		return
+	}
+	ErrorMessage("%s", err)
+	if errors.Is(err, ErrIncompleteResults) {
+		WarningMessage("Re-run `qodana scan` to get the complete results\n")
+	}
+	Exit(1)
+}
+
+// ParseSarif reads the SARIF report from the given file, the suppressed results are only counted.
+// Runs without results are returned with no results, only malformed files are reported as errors.
+func ParseSarif(sarifPath string) (*SarifReport, error) {
 	s, err := sarif.Open(sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	checkSarifVersion(sarifPath, s)
+	report := &SarifReport{Runs: make([]Run, 0, len(s.Runs))}
+	for _, r := range s.Runs {
+		if r == nil {
+			continue
+		}
+		run := Run{Results: make([]Result, 0, len(r.Results))}
+		if r.Tool.Driver != nil {
+			run.Tool = r.Tool.Driver.Name
+		}
+		for _, invocation := range r.Invocations {
+			if invocation == nil {
+				continue
+			}
+			if invocation.EndTimeUTC != nil {
+				run.Date = invocation.EndTimeUTC
+			} else if run.Date == nil {
+				run.Date = invocation.StartTimeUTC
+			}
+		}
+		for _, result := range r.Results {
+			if result == nil {
+				continue
+			}
+			if isSuppressed(result) {
+				run.Suppressed++
+			} else {
+				run.Results = append(run.Results, newResult(result))
+			}
+		}
+		log.Debugf("SARIF %s: run of %s with %d results", sarifPath, run.Tool, len(run.Results))
+		report.Runs = append(report.Runs, run)
+	}
+	log.Debugf("SARIF %s: parsed %d runs", sarifPath, len(report.Runs))
+	return report, nil
+}
+
+// Results returns the results of all runs.
+func (r *SarifReport) Results() []Result {
+	results := make([]Result, 0)
+	for _, run := range r.Runs {
+		results = append(results, run.Results...)
+	}
+	return results
+}
+
+// Suppressed returns the number of suppressed results of all runs.
+func (r *SarifReport) Suppressed() int {
+	suppressed := 0
+	for _, run := range r.Runs {
+		suppressed += run.Suppressed
+	}
+	return suppressed
+}
+
+// readProblems returns all results from the given SARIF file.
+func readProblems(sarifPath string) ([]Result, error) {
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	return report.Results(), nil
+}
+
+// SarifToJSON returns the JSON array of problems found in the given SARIF file that match the filter
+// and the number of the problems hidden by the filter.
+func SarifToJSON(sarifPath string, filter ProblemFilter) (string, int, error) {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return "", 0, err
+	}
+	problems, hidden := filter.apply(problems)
+	out, err := json.MarshalIndent(problems, "", "  ")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return string(out), hidden, nil
+}
+
+// isNewResult returns true if the result is not present in the baseline, only such results are checked against the fail threshold.
+func isNewResult(r Result) bool {
+	return r.BaselineState == baselineStateNew || r.BaselineState == baselineStateEmpty
+}
+
+// levelCounts returns the number of new results for each level.
+func levelCounts(results []Result) map[Level]int {
+	counts := make(map[Level]int)
+	for _, r := range results {
+		if isNewResult(r) {
+			counts[r.Level]++
+		}
+	}
+	return counts
+}
+
+// pluralize returns the count with the singular or plural noun.
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+// getSummary returns the one-line summary of the new results grouped by level.
+func getSummary(results []Result) string {
+	counts := levelCounts(results)
+	return fmt.Sprintf(
+		"Qodana found %s, %s, %s",
+		pluralize(counts[LevelError], "error", "errors"),
+		pluralize(counts[LevelWarning], "warning", "warnings"),
+		pluralize(counts[LevelNote], "weak warning", "weak warnings"),
+	)
+}
+
+// PrintSarifSummary prints the number of new problems grouped by level.
+func PrintSarifSummary(sarifPath string) {
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	EmptyMessage()
+	SuccessMessage(getSummary(report.Results()))
+}
+
+// exceededThresholds returns the levels which have more new results than allowed by the thresholds.
+func exceededThresholds(results []Result, thresholds map[Level]int) []Level {
+	counts := levelCounts(results)
+	exceeded := make([]Level, 0)
+	for _, level := range []Level{LevelError, LevelWarning, LevelNote} {
+		if threshold, ok := thresholds[level]; ok && counts[level] > threshold {
+			exceeded = append(exceeded, level)
+		}
+	}
+	return exceeded
+}
+
+// CheckLevelThresholds prints the exceeded per-level fail thresholds and returns true if there are any.
+func CheckLevelThresholds(sarifPath string, options *QodanaOptions) bool {
+	thresholds := options.levelThresholds()
+	if len(thresholds) == 0 {
+		return false
+	}
+	report, err := ParseSarif(sarifPath)
 	if err != nil {
 		log.Fatal(err)
 	}
+	results := report.Results()
+	counts := levelCounts(results)
+	exceeded := exceededThresholds(results, thresholds)
+	for _, level := range exceeded {
+		ErrorMessage("The number of new %s problems (%d) exceeds the fail threshold %d", level, counts[level], thresholds[level])
+	}
+	return len(exceeded) > 0
+}
+
+// ThresholdExitCode returns QodanaFailThresholdExitCode if the analysis succeeded but the per-level fail thresholds are exceeded,
+// otherwise the analysis exit code is returned as is.
+func ThresholdExitCode(exitCode int, sarifPath string, options *QodanaOptions) int {
+	if exitCode == QodanaSuccessExitCode && CheckLevelThresholds(sarifPath, options) {
+		return QodanaFailThresholdExitCode
+	}
+	return exitCode
+}
+
+// MaxProblemsExitCode returns QodanaFailThresholdExitCode if the analysis found at least options.MaxProblems new problems,
+// otherwise the analysis exit code is returned as is.
+func MaxProblemsExitCode(exitCode int, sarifPath string, options *QodanaOptions) int {
+	if options.MaxProblems <= 0 || (exitCode != QodanaSuccessExitCode && exitCode != QodanaFailThresholdExitCode) {
+		return exitCode
+	}
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	found := 0
+	for _, r := range report.Results() {
+		if isNewResult(r) {
+			found++
+		}
+	}
+	if found < options.MaxProblems {
+		return exitCode
+	}
+	ErrorMessage("Reported %d of %d new problems: the --max-problems limit of %d is reached", options.MaxProblems, found, options.MaxProblems)
+	return QodanaFailThresholdExitCode
+}
+
+// printedProblems returns the problems printed by ReadSarif in the printed order: the unchanged ones are skipped.
+func printedProblems(results []Result, sortBy string, groupBy string, maxProblems int) []problemGroup {
+	problems := make([]Result, 0)
+	for _, r := range results {
+		if r.BaselineState != baselineStateUnchanged {
+			problems = append(problems, r)
+		}
+	}
+	return groupProblems(limitProblems(problems, sortBy, maxProblems), sortBy, groupBy)
+}
+
+// ReadSarif prints Qodana Scan result into stdout, at most maxProblems problems are printed if it is positive.
+// Only the problems matching the filter are printed and counted, the problem paths are linked relative to projectDir.
+func ReadSarif(sarifPath string, projectDir string, printProblems bool, sortBy string, groupBy string, maxProblems int, filter ProblemFilter) {
+	newProblems := 0
+	report, err := ParseSarif(sarifPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	results, hidden := filter.apply(report.Results())
+	for _, r := range results {
+		if isNewResult(r) {
+			newProblems++
+		}
+	}
 	if printProblems {
 		EmptyMessage()
-	}
-	for _, run := range s.Runs {
-		for _, r := range run.Results {
-			ruleId := *r.RuleID
-			message := *r.Message.Text
-			level := *r.Level
-			baselineState := baselineStateEmpty
-			if r.BaselineState != nil {
-				baselineState = *r.BaselineState
-			}
-			if baselineState == baselineStateNew || baselineState == baselineStateEmpty {
-				newProblems++
+		index := 0
+		for _, group := range printedProblems(results, sortBy, groupBy, maxProblems) {
+			if group.Key != "" {
+				printGroupHeader(group.Key, len(group.Problems))
 			}
-			if printProblems && len(r.Locations) > 0 && baselineState != baselineStateUnchanged {
-				if r.Locations[0].PhysicalLocation != nil {
-					startLine := *r.Locations[0].PhysicalLocation.Region.StartLine
-					contextLine := *r.Locations[0].PhysicalLocation.ContextRegion.StartLine
-					startColumn := *r.Locations[0].PhysicalLocation.Region.StartColumn
-					filePath := *r.Locations[0].PhysicalLocation.ArtifactLocation.URI
-					context := *r.Locations[0].PhysicalLocation.ContextRegion.Snippet.Text
-					printProblem(ruleId, level, message, filePath, startLine, startColumn, contextLine, context)
-				} else {
-					printProblem(ruleId, level, message, "", 0, 0, 0, "")
-				}
+			for _, r := range group.Problems {
+				index++
+				printProblem(projectDir, index, r.RuleId, string(r.Level), r.Message, r.File, r.Line, r.Column, r.ContextLine, r.Snippet)
 			}
 		}
 	}
@@ -74,6 +456,9 @@ func ReadSarif(sarifPath string, printProblems bool) {
 			ErrorMessage("Found %d new problems according to the checks applied", newProblems)
 		}
 	}
+	if hidden > 0 {
+		WarningMessage("%s hidden by the filter", pluralize(hidden, "problem is", "problems are"))
+	}
 }
 
 func saveSarifProperty(path string, key string, value string) error {
@@ -90,3 +475,44 @@ func saveSarifProperty(path string, key string, value string) error {
 	}
 	return s.WriteFile(path)
 }
+
+// SaveBaseline copies the SARIF report from the results directory to the baseline path,
+// a relative baseline path is resolved against the project directory. It returns the written path.
+func SaveBaseline(options *QodanaOptions) (string, error) {
+	baseline := options.BaselineGenerate
+	if !filepath.IsAbs(baseline) {
+		baseline = filepath.Join(options.ProjectDir, baseline)
+	}
+	if err := cp.Copy(filepath.Join(options.ResultsDir, QodanaSarifName), baseline); err != nil {
+		return "", err
+	}
+	return baseline, nil
+}
+
+// findLatestSarif returns the name of the most recently modified SARIF report in the given directory.
+func findLatestSarif(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	latest := ""
+	var latestTime int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sarifExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		modTime := info.ModTime().UnixNano()
+		if latest == "" || modTime > latestTime || (modTime == latestTime && entry.Name() > latest) {
+			latest = entry.Name()
+			latestTime = modTime
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no *%s files found", sarifExtension)
+	}
+	return latest, nil
+}
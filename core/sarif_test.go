@@ -0,0 +1,331 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owenrumney/go-sarif/v2/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestSarif writes a SARIF report with the given problems to dir and returns its path.
+func writeTestSarif(t *testing.T, dir string, problems []Result) string {
+	report, err := sarif.New(sarif.Version210)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := sarif.NewRunWithInformationURI("QDTEST", "https://jb.gg/qodana")
+	for _, p := range problems {
+		r := sarif.NewRuleResult(p.RuleId).WithMessage(sarif.NewTextMessage(p.Message))
+		if p.Level != "" {
+			r.WithLevel(string(p.Level))
+		}
+		if p.Severity != "" {
+			r.Properties = sarif.Properties{qodanaSeverityProperty: p.Severity}
+		}
+		if p.Fingerprint != "" {
+			r.WithPartialFingerPrints(map[string]interface{}{equalIndicatorFingerprint: p.Fingerprint})
+		}
+		if p.BaselineState != "" {
+			r.WithBaselineState(p.BaselineState)
+		}
+		if p.File != "" {
+			r.WithLocations([]*sarif.Location{
+				sarif.NewLocationWithPhysicalLocation(
+					sarif.NewPhysicalLocation().
+						WithArtifactLocation(sarif.NewSimpleArtifactLocation(p.File)).
+						WithRegion(sarif.NewSimpleRegion(p.Line, p.Line).WithStartColumn(p.Column)),
+				),
+			})
+		}
+		run.AddResult(r)
+	}
+	report.AddRun(run)
+	path := filepath.Join(dir, QodanaSarifName)
+	if err = report.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSarifToJSON(t *testing.T) {
+	expected := []Result{
+		{RuleId: "PyUnusedLocal", Level: LevelError, Severity: "High", Message: "Unused variable", Location: Location{File: "main.py", Line: 3, Column: 5}},
+		{RuleId: "PyTypeChecker", Level: LevelWarning, Severity: "Moderate", Message: "Wrong type", Location: Location{File: "util.py", Line: 10, Column: 1}, BaselineState: baselineStateUnchanged},
+		{RuleId: "ProjectLevel", Level: LevelWarning, Severity: "warning", Message: "No location"},
+	}
+	path := writeTestSarif(t, t.TempDir(), expected)
+
+	out, hidden, err := SarifToJSON(path, ProblemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, hidden)
+	var actual []Result
+	if err = json.Unmarshal([]byte(out), &actual); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, actual)
+
+	out, hidden, err = SarifToJSON(path, ProblemFilter{Severities: []string{"warning"}, Rules: []string{"PyTypeChecker"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal([]byte(out), &actual); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected[1:2], actual)
+	assert.Equal(t, 2, hidden)
+}
+
+func TestFindLatestSarif(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"2023-01-01.sarif.json", "2023-03-01.sarif.json", "2023-02-01.sarif.json", "notes.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := findLatestSarif(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2023-02-01.sarif.json", latest)
+
+	_, err = findLatestSarif(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestParseSarif(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected *SarifReport
+		wantErr  bool
+	}{
+		{
+			"Minimal valid report",
+			`{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": [{"ruleId": "UNUSED_IMPORT", "level": "note", "message": {"text": "Unused import"}, "locations": [{"physicalLocation": {"artifactLocation": {"uri": "Main.java"}, "region": {"startLine": 1, "startColumn": 1}}}]}]}]}`,
+			&SarifReport{Runs: []Run{{Tool: "QDJVM", Results: []Result{
+				{RuleId: "UNUSED_IMPORT", Level: LevelNote, Severity: "note", Message: "Unused import", Location: Location{File: "Main.java", Line: 1, Column: 1}},
+			}}}},
+			false,
+		},
+		{
+			"Missing results",
+			`{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}}]}`,
+			&SarifReport{Runs: []Run{{Tool: "QDJVM", Results: []Result{}}}},
+			false,
+		},
+		{
+			"Empty runs",
+			`{"version": "2.1.0", "runs": []}`,
+			&SarifReport{Runs: []Run{}},
+			false,
+		},
+		{
+			"Malformed report",
+			`{"version": "2.1.0", "runs": [`,
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), QodanaSarifName)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			report, err := ParseSarif(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSarif() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.expected, report)
+		})
+	}
+}
+
+func TestGetSummary(t *testing.T) {
+	results := []Result{
+		{RuleId: "A", Level: LevelError},
+		{RuleId: "B", Level: LevelWarning, BaselineState: baselineStateNew},
+		{RuleId: "C", Level: LevelWarning},
+		{RuleId: "D", Level: LevelNote, BaselineState: baselineStateUnchanged},
+		{RuleId: "E", Level: LevelError, BaselineState: baselineStateAbsent},
+	}
+	assert.Equal(t, "Qodana found 1 error, 2 warnings, 0 weak warnings", getSummary(results))
+}
+
+func TestExceededThresholds(t *testing.T) {
+	results := []Result{
+		{RuleId: "A", Level: LevelError},
+		{RuleId: "B", Level: LevelWarning},
+		{RuleId: "C", Level: LevelWarning},
+		{RuleId: "D", Level: LevelWarning, BaselineState: baselineStateUnchanged},
+		{RuleId: "E", Level: LevelNote},
+	}
+	tests := []struct {
+		name       string
+		thresholds map[Level]int
+		expected   []Level
+	}{
+		{"No thresholds", map[Level]int{}, []Level{}},
+		{"Error threshold exceeded", map[Level]int{LevelError: 0}, []Level{LevelError}},
+		{"Warning threshold reached but not exceeded", map[Level]int{LevelWarning: 2}, []Level{}},
+		{"Several thresholds exceeded", map[Level]int{LevelError: 0, LevelWarning: 1, LevelNote: 1}, []Level{LevelError, LevelWarning}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exceededThresholds(results, tt.thresholds))
+		})
+	}
+}
+
+func TestThresholdExitCode(t *testing.T) {
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{
+		{RuleId: "A", Level: LevelError, Message: "error"},
+		{RuleId: "B", Level: LevelWarning, Message: "warning"},
+	})
+
+	tests := []struct {
+		name     string
+		exitCode int
+		options  *QodanaOptions
+		expected int
+	}{
+		{"Threshold exceeded", QodanaSuccessExitCode, &QodanaOptions{FailThresholdError: 0, FailThresholdWarning: -1, FailThresholdNote: -1}, QodanaFailThresholdExitCode},
+		{"Threshold not exceeded", QodanaSuccessExitCode, &QodanaOptions{FailThresholdError: 1, FailThresholdWarning: 1, FailThresholdNote: -1}, QodanaSuccessExitCode},
+		{"No thresholds", QodanaSuccessExitCode, &QodanaOptions{FailThresholdError: -1, FailThresholdWarning: -1, FailThresholdNote: -1}, QodanaSuccessExitCode},
+		{"Analysis failure is kept", QodanaEapLicenseExpiredExitCode, &QodanaOptions{FailThresholdError: 0, FailThresholdWarning: -1, FailThresholdNote: -1}, QodanaEapLicenseExpiredExitCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ThresholdExitCode(tt.exitCode, sarifPath, tt.options))
+		})
+	}
+}
+
+func TestMaxProblemsExitCode(t *testing.T) {
+	sarifPath := writeTestSarif(t, t.TempDir(), []Result{
+		{RuleId: "A", Level: LevelError, Message: "error"},
+		{RuleId: "B", Level: LevelWarning, Message: "warning"},
+		{RuleId: "C", Level: LevelNote, Message: "unchanged", BaselineState: baselineStateUnchanged},
+	})
+
+	tests := []struct {
+		name        string
+		exitCode    int
+		maxProblems int
+		expected    int
+	}{
+		{"Limit reached", QodanaSuccessExitCode, 2, QodanaFailThresholdExitCode},
+		{"Limit not reached", QodanaSuccessExitCode, 3, QodanaSuccessExitCode},
+		{"No limit", QodanaSuccessExitCode, 0, QodanaSuccessExitCode},
+		{"Analysis failure is kept", QodanaEapLicenseExpiredExitCode, 1, QodanaEapLicenseExpiredExitCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MaxProblemsExitCode(tt.exitCode, sarifPath, &QodanaOptions{MaxProblems: tt.maxProblems}))
+		})
+	}
+}
+
+func TestSaveBaseline(t *testing.T) {
+	projectDir := t.TempDir()
+	resultsDir := t.TempDir()
+	expected := []Result{{RuleId: "PyUnusedLocal", Level: LevelError, Severity: "High", Message: "Unused variable"}}
+	writeTestSarif(t, resultsDir, expected)
+
+	baseline, err := SaveBaseline(&QodanaOptions{ProjectDir: projectDir, ResultsDir: resultsDir, BaselineGenerate: filepath.Join(".qodana", QodanaSarifName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filepath.Join(projectDir, ".qodana", QodanaSarifName), baseline)
+	actual, err := readProblems(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSarifVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   *sarif.Report
+		expected string
+	}{
+		{"version", &sarif.Report{Version: "2.1.0"}, "2.1.0"},
+		{"schema", &sarif.Report{Schema: "https://json.schemastore.org/sarif-2.2.0.json"}, "2.2.0"},
+		{"none", &sarif.Report{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sarifVersion(tt.report))
+		})
+	}
+
+	checkSarifVersion("qodana.sarif.json", &sarif.Report{Version: "2.1.0"})
+	assert.False(t, warnedSarifVersions["2.1.0"])
+	checkSarifVersion("qodana.sarif.json", &sarif.Report{Version: "3.0.0"})
+	assert.True(t, warnedSarifVersions["3.0.0"])
+	delete(warnedSarifVersions, "3.0.0")
+}
+
+func TestValidateSarif(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		incomplete bool
+	}{
+		{"Complete report", `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}}]}`, false},
+		{"Empty runs", `{"version": "2.1.0", "runs": []}`, false},
+		{"Truncated report", `{"version": "2.1.0", "runs": [{"tool": {"dri`, true},
+		{"Empty file", ``, true},
+		{"Missing runs", `{"version": "2.1.0"}`, true},
+		{"Runs is not an array", `{"version": "2.1.0", "runs": {}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), QodanaSarifName)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateSarif(path)
+			if tt.incomplete {
+				assert.ErrorIs(t, err, ErrIncompleteResults)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	err := ValidateSarif(filepath.Join(t.TempDir(), QodanaSarifName))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrIncompleteResults)
+}
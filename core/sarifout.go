@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+)
+
+// validateSarifOut checks the --sarif-out value is a file name with a SARIF extension, e.g. qodana-jvm.sarif.json.
+func validateSarifOut(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("SARIF output %q is not a file name, it is saved to the results directory", name)
+	}
+	if !strings.HasSuffix(name, sarifExtension) && !strings.HasSuffix(name, ".sarif") {
+		return fmt.Errorf("SARIF output %q must have the %s or .sarif extension", name, sarifExtension)
+	}
+	return nil
+}
+
+// sarifOutNames returns the names the SARIF report is saved with in the results directory: the canonical one
+// and the --sarif-out one.
+func (o *QodanaOptions) sarifOutNames() []string {
+	if o.SarifOut == "" || o.SarifOut == QodanaSarifName {
+		return []string{QodanaSarifName}
+	}
+	return []string{QodanaSarifName, o.SarifOut}
+}
+
+// SaveSarifOut copies the SARIF report to the --sarif-out name next to it, the canonical report is kept,
+// so qodana view and the other steps still find it. It returns the written path or "" if --sarif-out is not set.
+func SaveSarifOut(sarifPath string, options *QodanaOptions) (string, error) {
+	names := options.sarifOutNames()
+	if len(names) == 1 {
+		return "", nil
+	}
+	out := filepath.Join(filepath.Dir(sarifPath), names[1])
+	if err := cp.Copy(sarifPath, out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSarifOut(t *testing.T) {
+	for _, name := range []string{"qodana-jvm.sarif.json", "jvm.sarif", QodanaSarifName} {
+		assert.NoError(t, validateSarifOut(name), name)
+	}
+	for _, name := range []string{"", "report.json", "out/jvm.sarif.json", `out\jvm.sarif.json`, ".."} {
+		assert.Error(t, validateSarifOut(name), name)
+	}
+}
+
+func TestSaveSarifOut(t *testing.T) {
+	resultsDir := t.TempDir()
+	sarifPath := writeTestSarif(t, resultsDir, []Result{{RuleId: "PyUnusedLocal", Level: LevelWarning, Message: "Unused variable"}})
+
+	out, err := SaveSarifOut(sarifPath, &QodanaOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", out)
+	out, err = SaveSarifOut(sarifPath, &QodanaOptions{SarifOut: QodanaSarifName})
+	assert.NoError(t, err)
+	assert.Equal(t, "", out)
+
+	out, err = SaveSarifOut(sarifPath, &QodanaOptions{SarifOut: "qodana-python.sarif.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(resultsDir, "qodana-python.sarif.json"), out)
+	expected, _ := os.ReadFile(sarifPath)
+	actual, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
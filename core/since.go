@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sinceUnits are the units of the relative --since values, e.g. 12h, 7d or 2w.
+var sinceUnits = map[byte]time.Duration{
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseSince returns the time of the --since value: a duration before now (e.g. 7d) or a date (e.g. 2023-01-01).
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if len(since) > 1 {
+		if unit, ok := sinceUnits[since[len(since)-1]]; ok {
+			if n, err := strconv.Atoi(since[:len(since)-1]); err == nil && n > 0 {
+				return now.Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, since, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("since %q is neither a duration like 12h, 7d or 2w nor a date like 2023-01-01", since)
+}
+
+// gitCommitBefore returns the last commit of HEAD made before the given time, "" if all commits are newer.
+func gitCommitBefore(cwd string, t time.Time) (string, error) {
+	if _, err := gitCommandOutput(cwd, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", cwd, err)
+	}
+	return gitCommandOutput(cwd, "rev-list", "-1", "--before="+t.Format(time.RFC3339), "HEAD")
+}
+
+// resolveSince sets Commit to the last commit before Since, so only the files changed since then are analyzed.
+// Without git history the whole project is analyzed.
+func (o *QodanaOptions) resolveSince() error {
+	if o.Since == "" {
+		return nil
+	}
+	since, err := parseSince(o.Since, time.Now())
+	if err != nil {
+		return configErrorf("%s", err)
+	}
+	if !isInstalled("git") {
+		WarningMessage("git is not installed, analyzing the whole project instead of the changes since %s", o.Since)
+		return nil
+	}
+	commit, err := gitCommitBefore(o.ProjectDir, since)
+	if err != nil {
+		WarningMessage("Could not find the changes since %s, analyzing the whole project: %s", o.Since, err)
+		return nil
+	}
+	if commit == "" {
+		WarningMessage("All commits are newer than %s, analyzing the whole project", since.Format(time.DateTime))
+		return nil
+	}
+	SuccessMessage("Analyzing changes since %s (commit %s)", since.Format(time.DateTime), commit)
+	o.Commit = commit
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since    string
+		expected time.Time
+	}{
+		{"12h", time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"7d", time.Date(2023, 6, 8, 12, 0, 0, 0, time.UTC)},
+		{"2w", time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{"2023-01-01", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2023-01-01T10:00:00Z", time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.since, func(t *testing.T) {
+			actual, err := parseSince(tt.since, now)
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(actual), actual)
+		})
+	}
+	for _, since := range []string{"", "d", "0d", "-1d", "7y", "01/01/2023"} {
+		_, err := parseSince(since, now)
+		assert.Error(t, err, since)
+	}
+}
+
+func TestGitCommitBefore(t *testing.T) {
+	repo := t.TempDir()
+	commit := func(name string, date string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "."}, {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", name}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repo
+			cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE="+date, "GIT_AUTHOR_DATE="+date)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+	}
+
+	_, err := gitCommitBefore(repo, time.Now())
+	assert.Error(t, err)
+
+	if _, err = gitCommandOutput(repo, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	commit("main.go", "2023-01-01T10:00:00Z")
+	old, err := gitCommandOutput(repo, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit("util.go", "2023-03-01T10:00:00Z")
+
+	actual, err := gitCommitBefore(repo, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, old, actual)
+	actual, err = gitCommitBefore(repo, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "", actual)
+}
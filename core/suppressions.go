@@ -0,0 +1,187 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/owenrumney/go-sarif/v2/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// anyRule is the --suppressions rule id matching all rules.
+	anyRule = "*"
+	// suppressionStatusAccepted is the status of the SARIF suppressions that hide the result.
+	suppressionStatusAccepted = "accepted"
+)
+
+// suppression is a --suppressions entry: the rule id, the project-relative file glob and the optional line.
+type suppression struct {
+	rule    string
+	pattern string
+	line    int
+}
+
+// parseSuppressions reads the --suppressions file: one "<rule id> <file glob>[:<line>]" entry per line, e.g.
+// "PyUnusedLocal tests/*.py" or "* generated". Empty lines and lines starting with # are skipped.
+func parseSuppressions(suppressionsPath string) ([]suppression, error) {
+	file, err := os.Open(suppressionsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	suppressions := make([]suppression, 0)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected <rule id> <file>[:<line>], got %q", n, line)
+		}
+		s := suppression{rule: fields[0], pattern: fields[1]}
+		if pattern, number, found := strings.Cut(fields[1], ":"); found {
+			if s.line, err = strconv.Atoi(number); err != nil || s.line <= 0 {
+				return nil, fmt.Errorf("line %d: invalid line number %q", n, number)
+			}
+			s.pattern = pattern
+		}
+		if _, err = path.Match(s.pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid file pattern %q: %w", n, s.pattern, err)
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions, scanner.Err()
+}
+
+// matches checks if the suppression applies to the result: a pattern without a slash matches a file or directory
+// name at any depth, other patterns match the file or one of its parent directories.
+func (s suppression) matches(r Result) bool {
+	if (s.rule != anyRule && s.rule != r.RuleId) || (s.line > 0 && s.line != r.Line) || r.File == "" {
+		return false
+	}
+	for p := path.Clean(r.File); p != "." && p != "/"; p = path.Dir(p) {
+		if matchesExclude(s.pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSuppressed checks if the SARIF result has an accepted suppression, the suppressed results are not reported.
+func isSuppressed(r *sarif.Result) bool {
+	for _, s := range r.Suppressions {
+		if s != nil && (s.Status == nil || *s.Status == suppressionStatusAccepted) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSuppressions reads the --suppressions file, so its errors are reported before the analysis is started.
+func (o *QodanaOptions) resolveSuppressions() error {
+	if o.Suppressions == "" {
+		return nil
+	}
+	suppressions, err := parseSuppressions(o.Suppressions)
+	if err != nil {
+		return configErrorf("Invalid --suppressions file %s: %s", o.Suppressions, err)
+	}
+	o.suppressions = suppressions
+	return nil
+}
+
+// suppressResults marks the results of the SARIF report matching the suppressions as suppressed
+// and returns the number of newly suppressed results.
+func suppressResults(sarifPath string, suppressions []suppression, justification string) (int, error) {
+	s, err := sarif.Open(sarifPath)
+	if err != nil {
+		return 0, err
+	}
+	suppressed := 0
+	for _, run := range s.Runs {
+		if run == nil {
+			continue
+		}
+		for _, r := range run.Results {
+			if r == nil || isSuppressed(r) {
+				continue
+			}
+			result := newResult(r)
+			for _, sup := range suppressions {
+				if sup.matches(result) {
+					r.AddSuppression(sarif.NewSuppression("external").WithStatus(suppressionStatusAccepted).WithJustifcation(justification))
+					suppressed++
+					break
+				}
+			}
+		}
+	}
+	if suppressed == 0 {
+		return 0, nil
+	}
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return suppressed, os.WriteFile(sarifPath, out, 0o644)
+}
+
+// SuppressionsExitCode marks the results matching the --suppressions entries as suppressed in the SARIF report,
+// so they are excluded from the counts and the quality gates. If the linter failed the fail threshold only because
+// of the suppressed results, QodanaSuccessExitCode is returned, otherwise the exit code is returned as is.
+func SuppressionsExitCode(exitCode int, sarifPath string, options *QodanaOptions) int {
+	if len(options.suppressions) == 0 || (exitCode != QodanaSuccessExitCode && exitCode != QodanaFailThresholdExitCode) {
+		return exitCode
+	}
+	suppressed, err := suppressResults(sarifPath, options.suppressions, "Suppressed by "+options.Suppressions)
+	if err != nil {
+		log.Fatalf("Failed to apply the suppressions: %s", err)
+	}
+	SuccessMessage("%s suppressed by %s", pluralize(suppressed, "problem is", "problems are"), options.Suppressions)
+	if suppressed == 0 || exitCode != QodanaFailThresholdExitCode || options.FailThreshold == "" {
+		return exitCode
+	}
+	if LoadQodanaYaml(options.qodanaYamlPath()).FailureConditions.SeverityThresholds != nil {
+		return exitCode
+	}
+	threshold, _ := strconv.Atoi(options.FailThreshold)
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	found := 0
+	for _, r := range problems {
+		if isNewResult(r) {
+			found++
+		}
+	}
+	if found <= threshold {
+		log.Infof("%d new problems are left after the suppressions, the fail threshold %d is not exceeded", found, threshold)
+		return QodanaSuccessExitCode
+	}
+	return exitCode
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSuppressions(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "suppressions.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseSuppressions(t *testing.T) {
+	suppressions, err := parseSuppressions(writeSuppressions(t, `# generated code
+* generated
+
+PyUnusedLocal tests/*.py
+PyTypeChecker src/main.py:12`))
+	assert.NoError(t, err)
+	assert.Equal(t, []suppression{
+		{rule: "*", pattern: "generated"},
+		{rule: "PyUnusedLocal", pattern: "tests/*.py"},
+		{rule: "PyTypeChecker", pattern: "src/main.py", line: 12},
+	}, suppressions)
+
+	for content, expected := range map[string]string{
+		"PyUnusedLocal":              `line 1: expected <rule id> <file>[:<line>], got "PyUnusedLocal"`,
+		"PyUnusedLocal main.py:zero": `line 1: invalid line number "zero"`,
+		"\nPyUnusedLocal [main.py":   `line 2: invalid file pattern "[main.py": syntax error in pattern`,
+	} {
+		_, err = parseSuppressions(writeSuppressions(t, content))
+		assert.EqualError(t, err, expected)
+	}
+}
+
+func TestSuppressionMatches(t *testing.T) {
+	result := Result{RuleId: "PyUnusedLocal", Location: Location{File: "src/generated/models.py", Line: 3}}
+	for s, expected := range map[suppression]bool{
+		{rule: "*", pattern: "generated"}:                    true,
+		{rule: "PyUnusedLocal", pattern: "src"}:              true,
+		{rule: "PyUnusedLocal", pattern: "src/generated/*"}:  true,
+		{rule: "PyUnusedLocal", pattern: "*.py", line: 3}:    true,
+		{rule: "PyUnusedLocal", pattern: "*.py", line: 4}:    false,
+		{rule: "PyTypeChecker", pattern: "src"}:              false,
+		{rule: "PyUnusedLocal", pattern: "generated/*"}:      false,
+		{rule: "PyUnusedLocal", pattern: "src/generated.py"}: false,
+	} {
+		assert.Equal(t, expected, s.matches(result), "%+v", s)
+	}
+}
+
+func TestSuppressionsExitCode(t *testing.T) {
+	generated := Result{RuleId: "PyUnusedLocal", Severity: "High", Message: "Unused variable", Location: Location{File: "generated/models.py", Line: 3, Column: 5}}
+	kept := Result{RuleId: "PyTypeChecker", Severity: "Moderate", Message: "Wrong type", Location: Location{File: "main.py", Line: 10, Column: 1}}
+	dir := t.TempDir()
+	sarifPath := writeTestSarif(t, dir, []Result{generated, kept})
+	options := &QodanaOptions{ProjectDir: dir, YamlName: "qodana.yaml", FailThreshold: "1", Suppressions: writeSuppressions(t, "* generated")}
+	assert.NoError(t, options.resolveSuppressions())
+
+	assert.Equal(t, QodanaSuccessExitCode, SuppressionsExitCode(QodanaFailThresholdExitCode, sarifPath, options))
+	report, err := ParseSarif(sarifPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{kept}, report.Results())
+	assert.Equal(t, 1, report.Suppressed())
+
+	options.FailThreshold = "0"
+	assert.Equal(t, QodanaFailThresholdExitCode, SuppressionsExitCode(QodanaFailThresholdExitCode, sarifPath, options), "the exit code is kept if nothing is newly suppressed")
+}
@@ -20,17 +20,23 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/docker/docker/client"
+	cp "github.com/otiai10/copy"
 
 	cienvironment "github.com/cucumber/ci-environment/go"
 
@@ -43,16 +49,32 @@ var (
 	// DisableCheckUpdates flag to disable checking for updates
 	DisableCheckUpdates = false
 
-	scanStages []string
 	releaseUrl = "https://api.github.com/repos/JetBrains/qodana-cli/releases/latest"
 )
 
+const (
+	// noUpdateCheckOption is the option disabling update checks, it can be set with QODANA_NO_UPDATE_CHECK as well.
+	noUpdateCheckOption = "no-update-check"
+	// noStatisticsOption is the option disabling the usage statistics and update checks, it can be set with QODANA_NO_STATISTICS as well.
+	noStatisticsOption = "no-statistics"
+	// updateCheckInterval is the minimal time between two update checks.
+	updateCheckInterval = 24 * time.Hour
+)
+
 // CheckForUpdates check GitHub https://github.com/JetBrains/qodana-cli/ for the latest version of CLI release.
+// The check is done at most once per updateCheckInterval and is skipped in CI.
 func CheckForUpdates(currentVersion string) {
-	if currentVersion == "dev" || IsContainer() || cienvironment.DetectCIEnvironment() != nil || DisableCheckUpdates {
+	if currentVersion == "dev" || isUpdateCheckDisabled() {
+		return
+	}
+	lastCheckPath := updateCheckPath()
+	if !isUpdateCheckDue(lastCheckPath, time.Now()) {
 		return
 	}
 	latestVersion := getLatestVersion()
+	if latestVersion != "" {
+		saveUpdateCheckTime(lastCheckPath, time.Now())
+	}
 	if latestVersion != "" && latestVersion != currentVersion {
 		WarningMessage(
 			"New version of %s CLI is available: %s. See https://jb.gg/qodana-cli/update\n",
@@ -63,9 +85,73 @@ func CheckForUpdates(currentVersion string) {
 	}
 }
 
+// isCI checks if the CLI runs in CI.
+func isCI() bool {
+	return IsGitHubActions() || os.Getenv("CI") != "" || cienvironment.DetectCIEnvironment() != nil
+}
+
+// isUpdateCheckDisabled returns true if the update check is switched off by the flag or the environment, or the CLI runs in CI.
+func isUpdateCheckDisabled() bool {
+	if DisableCheckUpdates || IsContainer() || isCI() {
+		return true
+	}
+	return isOptionEnvSet(noUpdateCheckOption) || isOptionEnvSet(noStatisticsOption)
+}
+
+// isOptionEnvSet checks if the boolean option is switched on by its environment variable, invalid values count as set.
+func isOptionEnvSet(option string) bool {
+	value, ok := LookupOptionEnv(option)
+	if !ok {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err != nil || enabled
+}
+
+// IsNoStatisticsRequested checks if --no-statistics is passed in the command line arguments,
+// so the update check started before the arguments are parsed can be skipped.
+func IsNoStatisticsRequested(args []string) bool {
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--"+noStatisticsOption); ok && (value == "" || value == "=true") {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCheckPath returns the path of the file storing the time of the last update check.
+func updateCheckPath() string {
+	userCacheDir, _ := os.UserCacheDir()
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "last-update-check")
+}
+
+// isUpdateCheckDue returns true if the last update check was done more than updateCheckInterval ago.
+func isUpdateCheckDue(lastCheckPath string, now time.Time) bool {
+	content, err := os.ReadFile(lastCheckPath)
+	if err != nil {
+		return true
+	}
+	lastCheck, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	if err != nil {
+		return true
+	}
+	return now.Sub(lastCheck) >= updateCheckInterval || now.Before(lastCheck)
+}
+
+// saveUpdateCheckTime stores the time of the update check, failures are ignored as the check is not essential.
+func saveUpdateCheckTime(lastCheckPath string, now time.Time) {
+	if err := os.MkdirAll(filepath.Dir(lastCheckPath), os.ModePerm); err != nil {
+		log.Debugf("Could not save the update check time: %s", err)
+		return
+	}
+	if err := os.WriteFile(lastCheckPath, []byte(now.UTC().Format(time.RFC3339)), 0o644); err != nil {
+		log.Debugf("Could not save the update check time: %s", err)
+	}
+}
+
 // getLatestVersion returns the latest published version of the CLI.
 func getLatestVersion() string {
-	resp, err := http.Get(releaseUrl)
+	resp, err := httpClient(0).Get(releaseUrl)
 	if err != nil {
 		return ""
 	}
@@ -90,35 +176,146 @@ func getLatestVersion() string {
 	return result["tag_name"].(string)
 }
 
-// openReport serves the report on the given port and opens the browser.
-func openReport(cloudUrl string, path string, port int) {
-	if cloudUrl != "" {
-		resp, err := http.Get(cloudUrl)
-		if err == nil && resp.StatusCode == 200 {
-			err = openBrowser(cloudUrl)
-			if err != nil {
-				return
+// OpenInNone is the --open-in value to print the report URL instead of opening it.
+const OpenInNone = "none"
+
+const (
+	// reportReadyTimeout is how long --wait-for-report waits for the report to respond.
+	reportReadyTimeout = 30 * time.Second
+	// reportReadyPollInterval is the interval between the --wait-for-report checks.
+	reportReadyPollInterval = 200 * time.Millisecond
+)
+
+// waitForReport polls the url until it responds with 200 OK, an error is returned after the timeout.
+func waitForReport(url string, timeout time.Duration, interval time.Duration) error {
+	client := httpClient(interval * 5)
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
 			}
+			err = fmt.Errorf("%s responded with %s", url, resp.Status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the report is not available after %s: %w", timeout, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// openWhenReady opens the report url with openUrl if it responds. With wait, the url is polled until it is ready
+// and then printed, so scripts can use it right away.
+func openWhenReady(url string, openIn string, wait bool) {
+	if wait {
+		if err := waitForReport(url, reportReadyTimeout, reportReadyPollInterval); err != nil {
+			WarningMessage("%s", err)
+			return
 		}
+		SuccessMessage("The report is ready at %s", PrimaryBold(url))
+	} else if resp, err := httpClient(0).Get(url); err != nil || resp.StatusCode != http.StatusOK {
 		return
-	} else {
-		url := fmt.Sprintf("http://localhost:%d", port)
-		go func() {
-			resp, err := http.Get(url)
-			if err == nil && resp.StatusCode == 200 {
-				err := openBrowser(url)
-				if err != nil {
-					return
-				}
+	}
+	if err := openUrl(url, openIn); err != nil {
+		WarningMessage("Could not open %s: %s", url, err)
+	}
+}
+
+// openUrl opens the url in the default browser, with the given command (split with splitCommand, the url is appended)
+// or, if openIn is OpenInNone, just prints it.
+func openUrl(url string, openIn string) error {
+	switch openIn {
+	case "":
+		return openBrowser(url)
+	case OpenInNone:
+		SuccessMessage("The report is available at %s", PrimaryBold(url))
+		return nil
+	}
+	args, err := splitCommand(openIn)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return openBrowser(url)
+	}
+	return exec.Command(args[0], append(args[1:], url)...).Start()
+}
+
+// splitCommand splits the command line into arguments by spaces, single or double quotes keep the spaces inside,
+// e.g. "C:\Program Files\Mozilla Firefox\firefox.exe" -new-window. Backslashes are kept as is for Windows paths.
+// An unquoted path of an existing file is returned as a single argument.
+func splitCommand(command string) ([]string, error) {
+	command = strings.TrimSpace(command)
+	if info, err := os.Stat(command); err == nil && !info.IsDir() {
+		return []string{command}, nil
+	}
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
 			}
-		}()
-		http.Handle("/", noCache(http.FileServer(http.Dir(path))))
-		err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-		if err != nil {
-			WarningMessage("Problem serving report, %s\n", err.Error())
-			return
+		default:
+			current.WriteRune(r)
+			inArg = true
 		}
 	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %s", quote, command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// listenReport binds the port to serve the report on, 0 picks a free port.
+func listenReport(port int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil && isAddrInUse(err) {
+		return nil, fmt.Errorf("port %d is already in use, use --port 0 to pick a free port", port)
+	}
+	return listener, err
+}
+
+// isAddrInUse returns true if the error is caused by the port being already taken.
+func isAddrInUse(err error) bool {
+	errMsg := lower(err.Error())
+	return errors.Is(err, syscall.EADDRINUSE) ||
+		strings.Contains(errMsg, "address already in use") ||
+		strings.Contains(errMsg, "only one usage of each socket address")
+}
+
+// reportUrl returns the local URL of the report served by the listener.
+func reportUrl(listener net.Listener) string {
+	return fmt.Sprintf("http://localhost:%d", listener.Addr().(*net.TCPAddr).Port)
+}
+
+// serveReport serves the report with the given listener and opens it with openWhenReady.
+func serveReport(listener net.Listener, path string, openIn string, wait bool) {
+	url := reportUrl(listener)
+	go openWhenReady(url, openIn, wait)
+	mux := http.NewServeMux()
+	mux.Handle("/", noCache(http.FileServer(http.Dir(path))))
+	if err := http.Serve(listener, mux); err != nil {
+		WarningMessage("Problem serving report, %s\n", err.Error())
+		return
+	}
 	_, _ = fmt.Scan()
 }
 
@@ -188,18 +385,73 @@ func noCache(h http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// isResultsDir returns true if the directory is empty or contains Qodana SARIF reports.
+func isResultsDir(entries []os.DirEntry) bool {
+	if len(entries) == 0 {
+		return true
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && (entry.Name() == QodanaSarifName || entry.Name() == "qodana-short.sarif.json") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanResultsDir removes the contents of the results directory and returns the number of removed files.
+// It refuses to clean a directory that doesn't look like a Qodana results directory.
+func cleanResultsDir(resultsDir string) (int, error) {
+	entries, err := os.ReadDir(resultsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !isResultsDir(entries) || IsHomeDirectory(resultsDir) {
+		return 0, fmt.Errorf("%s doesn't look like a Qodana results directory, refusing to delete it", resultsDir)
+	}
+	removed := 0
+	err = filepath.WalkDir(resultsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err = os.RemoveAll(filepath.Join(resultsDir, entry.Name())); err != nil {
+			return 0, err
+		}
+	}
+	return removed, nil
+}
+
 // prepareHost gets the current user, creates the necessary folders for the analysis.
-func prepareHost(opts *QodanaOptions) {
+func prepareHost(opts *QodanaOptions) error {
 	if opts.ClearCache {
 		err := os.RemoveAll(opts.CacheDir)
 		if err != nil {
 			log.Errorf("Could not clear local Qodana cache: %s", err)
 		}
 	}
+	if opts.CleanResults {
+		removed, err := cleanResultsDir(opts.ResultsDir)
+		if err != nil {
+			return fmt.Errorf("Could not clean the results directory: %w", err)
+		}
+		SuccessMessage("Removed %d file(s) from %s", removed, opts.ResultsDir)
+	}
 	warnIfPrivateFeedDetected(opts.ProjectDir)
 	if isNugetConfigNeeded() {
 		prepareNugetConfig(os.Getenv("HOME"))
 	}
+	prepareCache(opts)
 	if err := os.MkdirAll(opts.CacheDir, os.ModePerm); err != nil {
 		log.Fatal("couldn't create a directory ", err.Error())
 	}
@@ -207,7 +459,9 @@ func prepareHost(opts *QodanaOptions) {
 		log.Fatal("couldn't create a directory ", err.Error())
 	}
 	if opts.Linter != "" {
-		PrepareContainerEnvSettings()
+		if err := CheckContainerEngine(); err != nil {
+			return err
+		}
 	}
 	if opts.Ide != "" {
 		if Contains(AllNativeCodes, strings.TrimSuffix(opts.Ide, EapSuffix)) {
@@ -217,17 +471,40 @@ func prepareHost(opts *QodanaOptions) {
 				}
 				opts.Ide = downloadAndInstallIDE(opts, opts.getQodanaSystemDir(), spinner)
 			}, fmt.Sprintf("Downloading %s", opts.Ide), fmt.Sprintf("downloading IDE distribution to %s", opts.getQodanaSystemDir()))
-		} else {
-			val, exists := os.LookupEnv(QodanaDistEnv)
-			if !exists || val == "" || opts.Ide != val {
-				log.Fatalf("Product code %s is not supported", opts.Ide)
+		} else if val, exists := os.LookupEnv(QodanaDistEnv); !exists || val == "" || opts.Ide != val {
+			ideHome, err := filepath.Abs(opts.Ide)
+			if err == nil {
+				err = validateIdeHome(ideHome)
+			}
+			if err != nil {
+				return configErrorf(
+					"%s is neither a supported product code (%s) nor a valid IDE installation: %s",
+					opts.Ide,
+					strings.Join(AllNativeCodes, ", "),
+					err,
+				)
 			}
+			opts.Ide = ideHome
 		}
 		prepareLocalIdeSettings(opts)
 	}
-	if opts.RequiresToken() {
-		opts.ValidateToken(false)
+	return opts.prepareLicenseToken()
+}
+
+// prepareLicenseToken passes --license-token to the linter as QODANA_TOKEN and checks the linter that requires
+// a license gets one.
+func (o *QodanaOptions) prepareLicenseToken() error {
+	if o.LicenseToken != "" {
+		o.setenv(QodanaToken, o.LicenseToken)
+	}
+	if o.RequiresToken() && !o.hasLicense() && o.ValidateToken(false) == "" {
+		return configErrorf(
+			"%s requires a license token: pass it with --license-token, --license-token-stdin or the %s environment variable",
+			o.analyzerName(),
+			QodanaToken,
+		)
 	}
+	return nil
 }
 
 func GetDefaultUser() string {
@@ -239,6 +516,19 @@ func GetDefaultUser() string {
 	}
 }
 
+// containerUser returns the user to run the container as, UserAuto is resolved to the current host user,
+// so the files created by the analysis are owned by the invoking user.
+func containerUser(user string) string {
+	if user != UserAuto {
+		return user
+	}
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		log.Infof("--user %s has no effect on Windows, the container runs as root", UserAuto)
+	}
+	return GetDefaultUser()
+}
+
 // IsHomeDirectory returns true if the given path is the user's home directory.
 func IsHomeDirectory(path string) bool {
 	absPath, err := filepath.Abs(path)
@@ -266,12 +556,40 @@ func AskUserConfirm(what string) bool {
 	return answer
 }
 
-// RunAnalysis runs the linter with the given options.
+// RunAnalysis runs the linter with the given options, the CLI exits if the analysis can't be run.
 func RunAnalysis(ctx context.Context, options *QodanaOptions) int {
-	log.Debugf("Running analysis with options: %+v", options)
-	prepareHost(options)
+	exitCode, err := Analyze(ctx, options)
+	if err != nil {
+		options.exitWithError(err)
+	}
+	return exitCode
+}
+
+// Analyze runs the linter with the given options and returns its exit code, or the error if the analysis can't be run.
+func Analyze(ctx context.Context, options *QodanaOptions) (int, error) {
+	log.Debugf("Running analysis with options: %+v", options.masked())
+	if err := options.checkLinterCompatibility(); err != nil {
+		return 0, err
+	}
+	options.logRequestedPlugins()
+	if err := prepareHost(options); err != nil {
+		return 0, err
+	}
+	if err := options.checkConfigSupported(); err != nil {
+		return 0, err
+	}
+	if err := options.checkScriptsSupported(); err != nil {
+		return 0, err
+	}
+	if err := options.resolveChangesSinceBranch(); err != nil {
+		return 0, err
+	}
+	if err := options.resolveSince(); err != nil {
+		return 0, err
+	}
 
 	var exitCode int
+	var err error
 
 	if options.FullHistory && isInstalled("git") {
 		remoteUrl := gitRemoteUrl(options.ProjectDir)
@@ -309,50 +627,110 @@ func RunAnalysis(ctx context.Context, options *QodanaOptions) int {
 			}
 			EmptyMessage()
 
-			exitCode = runQodana(ctx, options)
+			exitCode, err = runQodana(ctx, options)
 			options.unsetenv(qodanaRevision)
+			if err != nil {
+				return exitCode, err
+			}
 		}
 		err = gitCheckout(options.ProjectDir, branch)
 		if err != nil {
 			log.Fatal(err)
 		}
-	} else if options.Commit != "" && isInstalled("git") {
-		options.GitReset = false
-		err := gitReset(options.ProjectDir, options.Commit)
+	} else if options.Commit != "" {
+		if !isInstalled("git") {
+			return 0, configErrorf("git is required to analyze the files changed since %s", options.Commit)
+		}
+		changedFiles, err := gitChangedFiles(options.ProjectDir, options.Commit)
 		if err != nil {
-			WarningMessage("Could not reset git repository, no --commit option will be applied: %s", err)
+			return 0, configErrorf("Could not get the files changed since %s: %s", options.Commit, err)
+		}
+		if len(changedFiles) == 0 {
+			WarningMessage("No files changed since %s", options.Commit)
 		} else {
-			options.GitReset = true
+			log.Debugf("Files changed since %s: %v", options.Commit, changedFiles)
+			SuccessMessage("Analyzing %s changed since %s", pluralize(len(changedFiles), "file", "files"), options.Commit)
+		}
+		options.GitReset = false
+		err = gitReset(options.ProjectDir, options.Commit)
+		if err != nil {
+			return 0, configErrorf("Could not reset git repository to %s: %s", options.Commit, err)
 		}
+		options.GitReset = true
 
-		exitCode = runQodana(ctx, options)
+		exitCode, err = runQodana(ctx, options)
 
 		if options.GitReset && !strings.HasPrefix(options.Commit, "CI") {
 			_ = gitResetBack(options.ProjectDir)
 		}
 	} else {
-		exitCode = runQodana(ctx, options)
+		exitCode, err = runQodana(ctx, options)
 	}
 
-	return exitCode
+	return exitCode, err
 }
 
-func runQodana(ctx context.Context, options *QodanaOptions) int {
-	var exitCode int
-	if options.Linter != "" {
-		exitCode = runQodanaContainer(ctx, options)
-	} else if options.Ide != "" {
-		unsetNugetVariables() // TODO: get rid of it from 241 release
-		exitCode = runQodanaLocal(options)
-	} else {
-		log.Fatal("No linter or IDE specified")
+// Scan resolves the options, runs the analysis and checks the quality gate for the code embedding core: the failures
+// are returned as errors to tell apart with errors.Is, e.g. ErrInvalidConfig, ErrDockerNotFound, ErrImagePull,
+// ErrAnalysisFailed or ErrThresholdExceeded, nothing is exited.
+func Scan(ctx context.Context, options *QodanaOptions) error {
+	if err := options.ValidateInput(); err != nil {
+		return err
 	}
+	if err := options.ResolveAnalyzerSettings(); err != nil {
+		return err
+	}
+	defer options.CleanupTmpResults()
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	exitCode, err := Analyze(ctx, options)
+	if err != nil {
+		return err
+	}
+	exitCode = ThresholdExitCode(exitCode, filepath.Join(options.ResultsDir, QodanaSarifName), options)
+	return ExitCodeError(exitCode)
+}
 
-	return exitCode
+func runQodana(ctx context.Context, options *QodanaOptions) (int, error) {
+	start := time.Now()
+	options.emitEvent(event{Event: eventScanStart, Linter: options.analyzerName()})
+	var err error
+	exitCode := runWithFixesReport(options, func() int {
+		var exitCode int
+		if options.Linter != "" {
+			exitCode, err = runQodanaContainer(ctx, options)
+		} else if options.Ide != "" {
+			if options.MountCacheAsReadonly {
+				WarningMessage("--mount-cache-as-readonly has no effect in native mode, the cache %s is used as is\n", options.CacheDir)
+			}
+			unsetNugetVariables() // TODO: get rid of it from 241 release
+			exitCode = runQodanaLocal(options)
+		} else {
+			log.Fatal("No linter or IDE specified")
+		}
+		return exitCode
+	})
+	if err != nil {
+		return exitCode, err
+	}
+	elapsed := time.Since(start).Round(time.Second)
+	SuccessMessage("Analysis took %s", elapsed)
+	if exitCode == QodanaSuccessExitCode || exitCode == QodanaFailThresholdExitCode {
+		saveScanDuration(options, elapsed)
+	}
+	return exitCode, nil
 }
 
 // followLinter follows the linter logs and prints the progress.
-func followLinter(client *client.Client, containerName string, progress *pterm.SpinnerPrinter) {
+// The writes to a read-only mount reported by the linter are recorded in readOnlyWriteFailed.
+func followLinter(
+	options *QodanaOptions,
+	client *client.Client,
+	containerName string,
+	progress *pterm.SpinnerPrinter,
+	readOnlyWriteFailed *atomic.Bool,
+) {
 	reader, err := client.ContainerLogs(context.Background(), containerName, containerLogsOptions)
 	if err != nil {
 		log.Fatal(err.Error())
@@ -374,20 +752,26 @@ func followLinter(client *client.Client, containerName string, progress *pterm.S
 		line = strings.TrimSuffix(line, "\n")
 		if err == nil || len(line) > 0 {
 			if strings.Contains(line, "Starting up") {
-				updateText(progress, scanStages[2])
+				options.updateScanStage(progress, 2)
 			}
 			if strings.Contains(line, "The Project opening stage completed in") {
-				updateText(progress, scanStages[3])
+				options.updateScanStage(progress, 3)
 			}
 			if strings.Contains(line, "The Project configuration stage completed in") {
-				updateText(progress, scanStages[4])
+				options.updateScanStage(progress, 4)
 			}
 			if strings.Contains(line, "Detailed summary") {
-				updateText(progress, scanStages[5])
+				options.updateScanStage(progress, 5)
 				if !IsInteractive() {
 					EmptyMessage()
 				}
 			}
+			if isReadOnlyWriteError(line) {
+				readOnlyWriteFailed.Store(true)
+			}
+			if options.parallelName != "" {
+				line = fmt.Sprintf("[%s] %s", options.parallelName, line)
+			}
 			printLinterLog(line)
 		}
 		if err != nil {
@@ -399,15 +783,38 @@ func followLinter(client *client.Client, containerName string, progress *pterm.S
 	}
 }
 
-func resetScanStages() {
-	scanStages = []string{
-		"Preparing Qodana Docker images",
-		"Starting the analysis engine",
-		"Opening the project",
-		"Configuring the project",
-		"Analyzing the project",
-		"Preparing the report",
+// scanDurationFile is the file in the linter directory the duration of the last successful analysis is saved to.
+const scanDurationFile = "last-scan-duration"
+
+// lastScanDuration returns the duration of the last successful analysis of the project with the linter, 0 if unknown.
+func lastScanDuration(options *QodanaOptions) time.Duration {
+	content, err := os.ReadFile(filepath.Join(options.GetLinterDir(), scanDurationFile))
+	if err != nil {
+		return 0
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0
 	}
+	return duration
+}
+
+// saveScanDuration saves the duration of the analysis to estimate the next one.
+func saveScanDuration(options *QodanaOptions, duration time.Duration) {
+	path := filepath.Join(options.GetLinterDir(), scanDurationFile)
+	if err := os.WriteFile(path, []byte(duration.String()), 0o644); err != nil {
+		log.Debugf("Could not save the analysis duration to %s: %s", path, err)
+	}
+}
+
+// scanStageNames are the stages of a container analysis shown in the progress.
+var scanStageNames = []string{
+	"Preparing Qodana Docker images",
+	"Starting the analysis engine",
+	"Opening the project",
+	"Configuring the project",
+	"Analyzing the project",
+	"Preparing the report",
 }
 
 const (
@@ -416,6 +823,34 @@ const (
 	nuget                 = "nuget"
 )
 
+// ExportReport makes sure the HTML report is in the report directory: the report written by the linter to the results
+// directory is copied there, otherwise it is rendered from the SARIF report. It returns the report directory.
+func ExportReport(opts *QodanaOptions) (string, error) {
+	linterReport := filepath.Join(opts.ResultsDir, "report")
+	reportDir, err := filepath.Abs(opts.ReportDir)
+	if err != nil {
+		return "", err
+	}
+	linterReport, err = filepath.Abs(linterReport)
+	if err != nil {
+		return "", err
+	}
+	if reportDir != linterReport {
+		if _, err = os.Stat(linterReport); err == nil {
+			if err = cp.Copy(linterReport, reportDir); err != nil {
+				return "", err
+			}
+		}
+	}
+	if _, err = os.Stat(filepath.Join(reportDir, "index.html")); err == nil {
+		return reportDir, nil
+	}
+	if err = writeSarifReport(filepath.Join(opts.ResultsDir, QodanaSarifName), reportDir); err != nil {
+		return "", fmt.Errorf("HTML report was not generated: %w", err)
+	}
+	return reportDir, nil
+}
+
 // saveReport saves web files to expect, and generates json.
 func saveReport(opts *QodanaOptions) {
 	if IsContainer() {
@@ -426,10 +861,23 @@ func saveReport(opts *QodanaOptions) {
 		}
 		log.Println("Generating HTML report ...")
 		if res := RunCmd("", QuoteForWindows(Prod.JbrJava()), "-jar", QuoteForWindows(reportConverter), "-s", QuoteForWindows(opts.ProjectDir), "-d", QuoteForWindows(opts.ResultsDir), "-o", QuoteForWindows(opts.ReportResultsPath()), "-n", "result-allProblems.json", "-f"); res > 0 {
-			os.Exit(res)
+			Exit(res)
 		}
 		if res := RunCmd("", "sh", "-c", fmt.Sprintf("cp -r %s/web/* ", Prod.Home)+opts.ReportDir); res > 0 {
-			os.Exit(res)
+			Exit(res)
 		}
 	}
 }
+
+// ExitCode returns the code the CLI exits with for the given result: with IgnoreExitCode the code is printed and 0 is
+// returned instead, unless StrictErrors is set and the code reports a failure rather than the exceeded quality gate.
+func (o *QodanaOptions) ExitCode(code int) int {
+	if !o.IgnoreExitCode || code == QodanaSuccessExitCode {
+		return code
+	}
+	if o.StrictErrors && code != QodanaFailThresholdExitCode {
+		return code
+	}
+	WarningMessage("Exiting with code 0 because of --ignore-exit-code, the exit code would be %d", code)
+	return QodanaSuccessExitCode
+}
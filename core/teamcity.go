@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// teamCityVersionEnv is set by TeamCity build agents.
+const teamCityVersionEnv = "TEAMCITY_VERSION"
+
+// IsTeamCity returns true if the CLI is running inside a TeamCity build.
+func IsTeamCity() bool {
+	return os.Getenv(teamCityVersionEnv) != ""
+}
+
+// escapeTeamCityValue escapes an attribute value of a service message.
+func escapeTeamCityValue(s string) string {
+	return strings.NewReplacer(
+		"|", "||",
+		"'", "|'",
+		"\n", "|n",
+		"\r", "|r",
+		"[", "|[",
+		"]", "|]",
+		"\u0085", "|x",
+		"\u2028", "|l",
+		"\u2029", "|p",
+	).Replace(s)
+}
+
+// teamCityMessage formats a service message with the given name and attributes given as name, value pairs.
+func teamCityMessage(name string, attributes ...string) string {
+	var b strings.Builder
+	b.WriteString("##teamcity[" + name)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		b.WriteString(fmt.Sprintf(" %s='%s'", attributes[i], escapeTeamCityValue(attributes[i+1])))
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// teamCitySeverity returns the TeamCity inspection severity for the given Qodana (or SARIF) severity.
+func teamCitySeverity(severity string) string {
+	switch lower(severity) {
+	case "critical", "high", "error":
+		return "ERROR"
+	case "moderate", "warning":
+		return "WARNING"
+	case "low", "note":
+		return "WEAK WARNING"
+	default:
+		return "INFO"
+	}
+}
+
+// teamCityMessages returns the service messages reporting the new problems as inspections, the problem counts
+// as build statistics and the exceeded quality gate as a build problem.
+func teamCityMessages(problems []Result, exitCode int) []string {
+	messages := make([]string, 0)
+	types := map[string]bool{}
+	total := 0
+	for _, p := range problems {
+		if !isNewResult(p) {
+			continue
+		}
+		total++
+		if !types[p.RuleId] {
+			types[p.RuleId] = true
+			messages = append(messages, teamCityMessage("inspectionType", "id", p.RuleId, "name", p.RuleId, "category", "Qodana", "description", p.RuleId))
+		}
+		attributes := []string{"typeId", p.RuleId, "message", p.Message, "file", p.File}
+		if p.Line > 0 {
+			attributes = append(attributes, "line", fmt.Sprint(p.Line))
+		}
+		attributes = append(attributes, "SEVERITY", teamCitySeverity(p.Severity))
+		messages = append(messages, teamCityMessage("inspection", attributes...))
+	}
+	counts := levelCounts(problems)
+	for _, statistic := range []struct {
+		key   string
+		value int
+	}{
+		{"QodanaProblemsTotal", total},
+		{"QodanaProblemsError", counts[LevelError]},
+		{"QodanaProblemsWarning", counts[LevelWarning]},
+		{"QodanaProblemsNote", counts[LevelNote]},
+	} {
+		messages = append(messages, teamCityMessage("buildStatisticValue", "key", statistic.key, "value", fmt.Sprint(statistic.value)))
+	}
+	if exitCode == QodanaFailThresholdExitCode {
+		messages = append(messages, teamCityMessage(
+			"buildProblem",
+			"description", fmt.Sprintf("Qodana quality gate failed: %s", pluralize(total, "new problem", "new problems")),
+			"identity", "qodana-quality-gate",
+		))
+	}
+	return messages
+}
+
+// PrintTeamCityMessages writes TeamCity service messages for the problems found in the given SARIF file
+// and the analysis exit code.
+func PrintTeamCityMessages(w io.Writer, sarifPath string, exitCode int) error {
+	problems, err := readProblems(sarifPath)
+	if err != nil {
+		return err
+	}
+	for _, message := range teamCityMessages(problems, exitCode) {
+		if _, err = fmt.Fprintln(w, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamCityMessages(t *testing.T) {
+	problems := []Result{
+		{RuleId: "PyUnusedLocal", Level: LevelError, Severity: "High", Message: "Unused variable 'x'", Location: Location{File: "main.py", Line: 3}},
+		{RuleId: "PyUnusedLocal", Level: LevelWarning, Severity: "Moderate", Message: "Unused [import]\nos", Location: Location{File: "util.py", Line: 1}},
+		{RuleId: "PyOld", Level: LevelError, Severity: "Critical", Message: "Old problem", BaselineState: baselineStateUnchanged},
+	}
+
+	assert.Equal(t, []string{
+		"##teamcity[inspectionType id='PyUnusedLocal' name='PyUnusedLocal' category='Qodana' description='PyUnusedLocal']",
+		"##teamcity[inspection typeId='PyUnusedLocal' message='Unused variable |'x|'' file='main.py' line='3' SEVERITY='ERROR']",
+		"##teamcity[inspection typeId='PyUnusedLocal' message='Unused |[import|]|nos' file='util.py' line='1' SEVERITY='WARNING']",
+		"##teamcity[buildStatisticValue key='QodanaProblemsTotal' value='2']",
+		"##teamcity[buildStatisticValue key='QodanaProblemsError' value='1']",
+		"##teamcity[buildStatisticValue key='QodanaProblemsWarning' value='1']",
+		"##teamcity[buildStatisticValue key='QodanaProblemsNote' value='0']",
+		"##teamcity[buildProblem description='Qodana quality gate failed: 2 new problems' identity='qodana-quality-gate']",
+	}, teamCityMessages(problems, QodanaFailThresholdExitCode))
+
+	messages := teamCityMessages(problems, QodanaSuccessExitCode)
+	assert.NotContains(t, messages[len(messages)-1], "buildProblem")
+	assert.Equal(t, "a||b|rc", escapeTeamCityValue("a|b\rc"))
+}
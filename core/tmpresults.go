@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+
+	cp "github.com/otiai10/copy"
+	log "github.com/sirupsen/logrus"
+)
+
+// resolveTmpResults replaces the results directory with a new temporary one if --tmp-results is set,
+// so parallel scans on the same machine don't overwrite each other's results.
+func (o *QodanaOptions) resolveTmpResults() error {
+	if !o.TmpResults {
+		if o.KeepTmp {
+			return configErrorf("--keep-tmp requires --tmp-results")
+		}
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "qodana-results-")
+	if err != nil {
+		return configErrorf("Could not create a temporary results directory: %s", err)
+	}
+	log.Infof("Qodana results are saved to the temporary directory %s", dir)
+	o.tmpResultsTarget = o.resultsDirPath()
+	o.tmpResultsDir = dir
+	o.ResultsDir = dir
+	return nil
+}
+
+// CleanupTmpResults copies the SARIF report from the temporary results directory to the requested --results-dir
+// and removes the temporary directory unless --keep-tmp is set. It does nothing the second time, so it can be
+// registered with OnExit as well.
+func (o *QodanaOptions) CleanupTmpResults() {
+	if o.tmpResultsDir == "" {
+		return
+	}
+	dir := o.tmpResultsDir
+	o.tmpResultsDir = ""
+	for _, name := range o.sarifOutNames() {
+		sarifPath := filepath.Join(dir, name)
+		if _, err := os.Stat(sarifPath); o.tmpResultsTarget != "" && err == nil {
+			if err = cp.Copy(sarifPath, filepath.Join(o.tmpResultsTarget, name)); err != nil {
+				WarningMessage("Could not copy the SARIF report %s to %s: %s", name, o.tmpResultsTarget, err)
+			} else {
+				log.Infof("SARIF report %s is copied to %s", name, o.tmpResultsTarget)
+			}
+		}
+	}
+	if o.KeepTmp {
+		SuccessMessage("Temporary results are kept in %s", dir)
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warnf("Could not remove the temporary results directory %s: %s", dir, err)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpResults(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "results")
+	for _, keep := range []bool{false, true} {
+		opts := QodanaOptions{ResultsDir: target, TmpResults: true, KeepTmp: keep}
+		assert.NoError(t, opts.resolveTmpResults())
+		dir := opts.ResultsDir
+		assert.NotEqual(t, target, dir)
+		assert.DirExists(t, dir)
+		if err := os.WriteFile(filepath.Join(dir, QodanaSarifName), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		opts.CleanupTmpResults()
+		assert.FileExists(t, filepath.Join(target, QodanaSarifName))
+		if keep {
+			assert.DirExists(t, dir)
+			assert.NoError(t, os.RemoveAll(dir))
+		} else {
+			assert.NoDirExists(t, dir)
+		}
+		opts.CleanupTmpResults()
+	}
+}
+
+func TestTmpResultsSarifOut(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "results")
+	opts := QodanaOptions{ResultsDir: target, TmpResults: true, SarifOut: "qodana-jvm.sarif.json"}
+	assert.NoError(t, opts.resolveTmpResults())
+	for _, name := range []string{QodanaSarifName, opts.SarifOut} {
+		if err := os.WriteFile(filepath.Join(opts.ResultsDir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts.CleanupTmpResults()
+	assert.FileExists(t, filepath.Join(target, QodanaSarifName))
+	assert.FileExists(t, filepath.Join(target, "qodana-jvm.sarif.json"))
+}
+
+func TestTmpResultsDefaultResultsDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	projectDir := t.TempDir()
+	expected := (&QodanaOptions{ProjectDir: projectDir, Linter: "jetbrains/qodana-python:2023.3"}).resultsDirPath()
+
+	opts := QodanaOptions{ProjectDir: projectDir, Linter: "jetbrains/qodana-python:2023.3", TmpResults: true}
+	assert.NoError(t, opts.resolveTmpResults())
+	assert.NotEqual(t, expected, opts.ResultsDir)
+	if err := os.WriteFile(filepath.Join(opts.ResultsDir, QodanaSarifName), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts.CleanupTmpResults()
+	assert.FileExists(t, filepath.Join(expected, QodanaSarifName))
+}
@@ -17,18 +17,23 @@
 package core
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2023/cloud"
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando/go-keyring"
+	"io"
 	"os"
+	"strings"
 )
 
 const defaultService = "qodana-cli"
 
 func (o *QodanaOptions) loadToken(refresh bool) string {
 	tokenFetchers := []func(bool) string{
+		func(_ bool) string { return o.getTokenFromFlag() },
 		func(_ bool) string { return o.getTokenFromDockerArgs() },
 		func(_ bool) string { return o.getTokenFromEnv() },
 		o.getTokenFromKeychain,
@@ -43,6 +48,32 @@ func (o *QodanaOptions) loadToken(refresh bool) string {
 	return ""
 }
 
+func (o *QodanaOptions) getTokenFromFlag() string {
+	if o.LicenseToken != "" {
+		log.Debug("Loaded token from --license-token")
+	}
+	return o.LicenseToken
+}
+
+// ReadLicenseToken reads the license token from the first line of r, so it doesn't appear in the process list.
+func ReadLicenseToken(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("no license token in stdin")
+	}
+	return token, nil
+}
+
+// hasLicense reports whether the linter gets the license from QODANA_LICENSE_ONLY_TOKEN or QODANA_LICENSE
+// instead of the Qodana Cloud token.
+func (o *QodanaOptions) hasLicense() bool {
+	return os.Getenv(QodanaLicenseOnlyToken) != "" || o.getenv(QodanaLicenseOnlyToken) != "" || os.Getenv(QodanaLicense) != ""
+}
+
 func (o *QodanaOptions) getTokenFromDockerArgs() string {
 	tokenFromCliArgs := o.getenv(QodanaToken)
 	if tokenFromCliArgs != "" {
@@ -110,7 +141,7 @@ func (o *QodanaOptions) ValidateToken(refresh bool) string {
 		if projectName := client.ValidateToken(); projectName == "" {
 			if token != "" {
 				ErrorMessage(cloud.InvalidTokenMessage)
-				os.Exit(1)
+				Exit(1)
 			}
 		} else {
 			SuccessMessage("Linked %s project: %s", cloud.GetEnvWithDefault(cloud.QodanaEndpoint, cloud.DefaultEndpoint), projectName)
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLicenseToken(t *testing.T) {
+	token, err := ReadLicenseToken(strings.NewReader("  secret \nignored\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", token)
+	token, err = ReadLicenseToken(strings.NewReader("secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", token)
+	_, err = ReadLicenseToken(strings.NewReader("\n"))
+	assert.Error(t, err)
+}
+
+func TestHasLicense(t *testing.T) {
+	t.Setenv(QodanaLicenseOnlyToken, "")
+	t.Setenv(QodanaLicense, "")
+	assert.False(t, (&QodanaOptions{}).hasLicense())
+	assert.True(t, (&QodanaOptions{Env: []string{QodanaLicenseOnlyToken + "=token"}}).hasLicense())
+	t.Setenv(QodanaLicense, "license")
+	assert.True(t, (&QodanaOptions{}).hasLicense())
+}
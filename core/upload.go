@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021-2023 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reportUploadTimeout is the timeout of the report archive upload.
+const reportUploadTimeout = 10 * time.Minute
+
+// parseUploadHeaders parses the "Name: value" headers passed with --report-upload-header.
+func parseUploadHeaders(headers []string) (http.Header, error) {
+	parsed := make(http.Header)
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected 'Name: value'", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// validateUploadUrl checks that the report can be uploaded to the given URL.
+func validateUploadUrl(uploadUrl string) error {
+	u, err := url.Parse(uploadUrl)
+	if err != nil {
+		return redactUrlError(err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q, expected an http:// or https:// URL", redactUploadUrl(uploadUrl))
+	}
+	return nil
+}
+
+// archiveReport writes the zip archive of the report directory to w.
+func archiveReport(reportDir string, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	err := filepath.WalkDir(reportDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(reportDir, path)
+		if err != nil {
+			return err
+		}
+		entry, err := archive.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		_, err = io.Copy(entry, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// redactUploadUrl removes the query and the password from the upload URL, so a pre-signed URL signature is not printed.
+func redactUploadUrl(uploadUrl string) string {
+	u, err := url.Parse(uploadUrl)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.Redacted()
+}
+
+// redactUrlError removes the query and the password from the URL of the *url.Error returned by net/url and net/http.
+func redactUrlError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactUploadUrl(urlErr.URL)
+	}
+	return err
+}
+
+// UploadReport PUTs the zip archive of the report directory to uploadUrl with the given "Name: value" headers.
+// It returns the HTTP status and the final URL after redirects without the query, so it can be printed.
+func UploadReport(reportDir string, uploadUrl string, headers []string) (string, string, error) {
+	parsedHeaders, err := parseUploadHeaders(headers)
+	if err != nil {
+		return "", "", err
+	}
+	var body bytes.Buffer
+	if err = archiveReport(reportDir, &body); err != nil {
+		return "", "", fmt.Errorf("could not archive the report: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPut, uploadUrl, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return "", "", redactUrlError(err)
+	}
+	req.Header = parsedHeaders
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/zip")
+	}
+	log.Debugf("Uploading the report archive (%s) to %s", formatBytes(int64(body.Len())), redactUploadUrl(uploadUrl))
+	resp, err := httpClient(reportUploadTimeout).Do(req)
+	if err != nil {
+		return "", "", redactUrlError(err)
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	finalUrl := redactUploadUrl(resp.Request.URL.String())
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.Status, finalUrl, fmt.Errorf("%s responded with %s", finalUrl, resp.Status)
+	}
+	return resp.Status, finalUrl, nil
+}
@@ -23,7 +23,6 @@ import (
 	"fmt"
 	"github.com/pterm/pterm"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -50,6 +49,28 @@ func Contains(s []string, str string) bool {
 	return false
 }
 
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr := make([]int, len(rb)+1)
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(rb)]
+}
+
 // reverse reverses the given string slice.
 func reverse(s []string) []string {
 	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
@@ -284,13 +305,13 @@ func createUser(fn string) {
 }
 
 func DownloadFile(filepath string, url string, spinner *pterm.SpinnerPrinter) error {
-	response, err := http.Head(url)
+	response, err := httpClient(0).Head(url)
 	if err != nil {
 		return err
 	}
 	size, _ := strconv.Atoi(response.Header.Get("Content-Length"))
 
-	resp, err := http.Get(url)
+	resp, err := httpClient(0).Get(url)
 	if err != nil {
 		return err
 	}
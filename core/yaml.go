@@ -19,9 +19,12 @@ package core
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -40,11 +43,12 @@ func GetQodanaYaml(project string) QodanaYaml {
 	if _, err := os.Stat(qodanaYamlPath); errors.Is(err, os.ErrNotExist) {
 		return *q
 	}
-	yamlFile, err := os.ReadFile(qodanaYamlPath)
+	mapping, err := loadYamlWithIncludes(qodanaYamlPath, nil)
 	if err != nil {
-		log.Printf("Problem loading qodana.yaml: %v ", err)
+		log.Printf("Not a valid qodana.yaml: %v ", err)
+		return *q
 	}
-	err = yaml.Unmarshal(yamlFile, q)
+	err = mapping.Decode(q)
 	if err != nil {
 		log.Printf("Not a valid qodana.yaml: %v ", err)
 	}
@@ -67,7 +71,8 @@ type QodanaYaml struct {
 	Profile Profile `yaml:"profile,omitempty"`
 
 	// FailThreshold is a number of problems to fail the analysis (to exit from Qodana with code 255).
-	FailThreshold int `yaml:"failThreshold,omitempty"`
+	// It is used when --fail-threshold is not passed, nil means it is not set.
+	FailThreshold *int `yaml:"failThreshold,omitempty"`
 
 	// Clude property to disable the wanted checks on the wanted paths.
 	Excludes []Clude `yaml:"exclude,omitempty"`
@@ -75,6 +80,9 @@ type QodanaYaml struct {
 	// Include property to enable the wanted checks.
 	Includes []Clude `yaml:"include,omitempty"`
 
+	// IncludeFiles are the other yaml files listed in the include section, their values are merged in.
+	IncludeFiles []string `yaml:"-"`
+
 	// Properties property to override IDE properties.
 	Properties map[string]string `yaml:"properties,omitempty"`
 
@@ -135,16 +143,30 @@ type QodanaYaml struct {
 	// RunPromoInspections property to run promo inspections.
 	RunPromoInspections string `yaml:"runPromoInspections,omitempty"`
 
+	// Volumes property to mount additional volumes to the Qodana container in the host:container form, --volume values take precedence.
+	Volumes []string `yaml:"volumes,omitempty"`
+
+	// Env property to pass additional KEY=VALUE environment variables to the Qodana container, --env values take precedence.
+	Env []string `yaml:"env,omitempty"`
+
+	// Baseline is the SARIF report (relative to the project directory) to compare the results with, --baseline takes precedence.
+	Baseline string `yaml:"baseline,omitempty"`
+
 	// IncludeAbsent property to include absent problems from baseline.
 	IncludeAbsent string `yaml:"includeAbsent,omitempty"`
 }
 
 // writeConfig writes QodanaYaml to the given path.
 func (q *QodanaYaml) writeConfig(path string) error {
+	var mapping yaml.Node
+	if err := mapping.Encode(q); err != nil {
+		return err
+	}
+	setYamlIncludeFiles(&mapping, q.IncludeFiles)
 	var b bytes.Buffer
 	yamlEncoder := yaml.NewEncoder(&b)
 	yamlEncoder.SetIndent(2)
-	err := yamlEncoder.Encode(&q)
+	err := yamlEncoder.Encode(&mapping)
 	if err != nil {
 		return err
 	}
@@ -185,6 +207,16 @@ type Plugin struct {
 	Id string `yaml:"id"`
 }
 
+// UnmarshalYAML reads a plugin given by its id (- org.intellij.scala) or as a mapping (- id: org.intellij.scala).
+func (p *Plugin) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Id = value.Value
+		return nil
+	}
+	type plugin Plugin
+	return value.Decode((*plugin)(p))
+}
+
 // DependencyIgnore is a dependency to ignore for license checks in Qodana
 //
 //goland:noinspection GoUnnecessarilyExportedIdentifiers
@@ -338,22 +370,61 @@ func FindQodanaYaml(project string) string {
 	}
 }
 
-// LoadQodanaYaml gets Qodana YAML from the project.
-func LoadQodanaYaml(project string, filename string) *QodanaYaml {
+// LoadQodanaYaml gets Qodana YAML from the given file, a missing file results in an empty configuration.
+func LoadQodanaYaml(qodanaYamlPath string) *QodanaYaml {
+	q, err := readQodanaYaml(qodanaYamlPath)
+	if err != nil {
+		exitWithError(err)
+	}
+	return q
+}
+
+// readQodanaYaml reads qodana.yaml with the included files merged in, an empty configuration is returned if the file
+// doesn't exist. Invalid files are reported as errors matching ErrInvalidConfig.
+func readQodanaYaml(qodanaYamlPath string) (*QodanaYaml, error) {
 	q := &QodanaYaml{}
-	qodanaYamlPath := filepath.Join(project, filename)
 	if _, err := os.Stat(qodanaYamlPath); errors.Is(err, os.ErrNotExist) {
-		return q
+		return q, nil
 	}
-	yamlFile, err := os.ReadFile(qodanaYamlPath)
+	mapping, err := loadYamlWithIncludes(qodanaYamlPath, nil)
 	if err != nil {
-		log.Printf("yamlFile.Get err   #%v ", err)
+		return nil, configErrorf("%s", err)
 	}
-	err = yaml.Unmarshal(yamlFile, q)
-	if err != nil {
-		log.Fatalf("Unmarshal: %v", err)
+	if err = mapping.Decode(q); err != nil {
+		return nil, configErrorf("%s: %s", qodanaYamlPath, err)
 	}
-	return q
+	if local, err := readYamlMapping(qodanaYamlPath); err == nil {
+		q.IncludeFiles = extractYamlIncludeFiles(local)
+	}
+	if err = q.validateContainerSettings(); err != nil {
+		return nil, configErrorf("%s: %s", qodanaYamlPath, err)
+	}
+	return q, nil
+}
+
+// checkQodanaYaml reads the configuration once before the other options are resolved, so an invalid file is reported
+// as an error instead of stopping the run where it is read next.
+func (o *QodanaOptions) checkQodanaYaml() error {
+	_, err := readQodanaYaml(o.qodanaYamlPath())
+	return err
+}
+
+// validateContainerSettings checks the volumes and env values and the fail threshold of qodana.yaml.
+func (q *QodanaYaml) validateContainerSettings() error {
+	if q.FailThreshold != nil && *q.FailThreshold < 0 {
+		return fmt.Errorf("failThreshold %d is not a non-negative integer", *q.FailThreshold)
+	}
+	for _, volume := range q.Volumes {
+		if err := validateVolume(volume); err != nil {
+			return err
+		}
+	}
+	for _, env := range q.Env {
+		if err := validateEnvEntry(env); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // sort makes QodanaYaml prettier.
@@ -392,7 +463,7 @@ func (q *QodanaYaml) sort() *QodanaYaml {
 
 // SetQodanaLinter adds the linter to the qodana.yaml file.
 func SetQodanaLinter(path string, linter string, filename string) {
-	q := LoadQodanaYaml(path, filename)
+	q := loadLocalQodanaYaml(filepath.Join(path, filename))
 	if q.Version == "" {
 		q.Version = "1.0"
 	}
@@ -414,7 +485,7 @@ func (q *QodanaYaml) IsDotNet() bool {
 
 // setQodanaDotNet adds the .NET configuration to the qodana.yaml file.
 func setQodanaDotNet(path string, dotNet *DotNet, filename string) bool {
-	q := LoadQodanaYaml(path, filename)
+	q := loadLocalQodanaYaml(filepath.Join(path, filename))
 	q.DotNet = *dotNet
 	err := q.writeConfig(filepath.Join(path, filename))
 	if err != nil {
@@ -422,3 +493,368 @@ func setQodanaDotNet(path string, dotNet *DotNet, filename string) bool {
 	}
 	return true
 }
+
+// ValidateQodanaYaml checks the given qodana.yaml against the known schema,
+// it returns unknown keys, wrong value types and invalid linter names with line numbers.
+func ValidateQodanaYaml(project string, filename string) ([]string, error) {
+	path := filepath.Join(project, filename)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	problems := make([]string, 0)
+	var root yaml.Node
+	rootErr := yaml.Unmarshal(content, &root)
+	includeLines := yamlIncludeFileLines(&root)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&QodanaYaml{})
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		for _, e := range typeErr.Errors {
+			var line int
+			if _, scanErr := fmt.Sscanf(e, "line %d:", &line); scanErr == nil && includeLines[line] {
+				continue // a file include, not an inspection one
+			}
+			problems = append(problems, humanizeYamlError(e))
+		}
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return append(problems, strings.TrimPrefix(err.Error(), "yaml: ")), nil
+	}
+	if rootErr != nil {
+		return nil, rootErr
+	}
+	if len(includeLines) > 0 {
+		if _, err = loadYamlWithIncludes(path, nil); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return append(problems, validateValues(&root)...), nil
+}
+
+// yamlIncludeFileLines returns the lines of the file entries in the include section of the parsed qodana.yaml.
+func yamlIncludeFileLines(root *yaml.Node) map[int]bool {
+	lines := make(map[int]bool)
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return lines
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "include" {
+			continue
+		}
+		for _, item := range mapping.Content[i+1].Content {
+			if item.Kind == yaml.ScalarNode {
+				lines[item.Line] = true
+			}
+		}
+	}
+	return lines
+}
+
+// humanizeYamlError makes the yaml decoder unknown field errors shorter: "line 3: unknown key foo".
+func humanizeYamlError(e string) string {
+	if i := strings.Index(e, " not found in type "); i >= 0 {
+		return strings.Replace(e[:i], ": field ", ": unknown key ", 1)
+	}
+	return e
+}
+
+// validateValues checks the top-level linter, ide, fail threshold, volumes, env and inspection scopes of the parsed qodana.yaml.
+func validateValues(root *yaml.Node) []string {
+	problems := make([]string, 0)
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return problems
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		switch key.Value {
+		case "linter":
+			if _, err := resolveLinter(value.Value); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: invalid linter: %s", value.Line, err))
+			}
+		case "ide":
+			code := strings.TrimSuffix(value.Value, EapSuffix)
+			if _, ok := Products[code]; !ok && !strings.ContainsAny(value.Value, `/\`) {
+				problems = append(problems, fmt.Sprintf("line %d: unknown ide %s, available codes are: %s", value.Line, value.Value, strings.Join(AllCodes, ", ")))
+			}
+		case "volumes", "env":
+			for _, item := range value.Content {
+				validate := validateVolume
+				if key.Value == "env" {
+					validate = validateEnvEntry
+				}
+				if err := validate(item.Value); err != nil {
+					problems = append(problems, fmt.Sprintf("line %d: %s", item.Line, err))
+				}
+			}
+		case "failThreshold":
+			if threshold, err := strconv.Atoi(value.Value); err == nil && threshold < 0 {
+				problems = append(problems, fmt.Sprintf("line %d: failThreshold %d is not a non-negative integer", value.Line, threshold))
+			}
+		case "include", "exclude":
+			problems = append(problems, validateInspectionScope(key.Value, value)...)
+		case "plugins":
+			problems = append(problems, validatePlugins(value)...)
+		}
+	}
+	return problems
+}
+
+// validatePlugins checks the entries of the plugins section have non-empty unique ids. Entries of a wrong type
+// are reported by decoding.
+func validatePlugins(value *yaml.Node) []string {
+	problems := make([]string, 0)
+	if value.Kind != yaml.SequenceNode {
+		return problems
+	}
+	seen := make(map[string]bool)
+	for _, item := range value.Content {
+		id := item
+		if item.Kind == yaml.MappingNode {
+			id = nil
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				if item.Content[i].Value == "id" {
+					id = item.Content[i+1]
+				}
+			}
+		}
+		if id == nil || id.Kind != yaml.ScalarNode {
+			if item.Kind == yaml.MappingNode {
+				problems = append(problems, fmt.Sprintf("line %d: plugins entry has no id", item.Line))
+			}
+			continue
+		}
+		if strings.TrimSpace(id.Value) == "" {
+			problems = append(problems, fmt.Sprintf("line %d: plugins entry has no id", item.Line))
+			continue
+		}
+		if seen[id.Value] {
+			problems = append(problems, fmt.Sprintf("line %d: plugin %s is listed more than once", item.Line, id.Value))
+		}
+		seen[id.Value] = true
+	}
+	return problems
+}
+
+// validateInspectionScope checks the entries of the include or exclude section: an entry needs the inspection name,
+// and its paths must be relative to the project root. Entries of a wrong type are reported by decoding.
+func validateInspectionScope(section string, value *yaml.Node) []string {
+	problems := make([]string, 0)
+	if value.Kind != yaml.SequenceNode {
+		return problems
+	}
+	for _, item := range value.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		var name, paths *yaml.Node
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			switch item.Content[i].Value {
+			case "name":
+				name = item.Content[i+1]
+			case "paths":
+				paths = item.Content[i+1]
+			}
+		}
+		if name == nil || name.Kind != yaml.ScalarNode || strings.TrimSpace(name.Value) == "" {
+			problems = append(problems, fmt.Sprintf("line %d: %s entry has no inspection name", item.Line, section))
+			continue
+		}
+		if paths == nil {
+			continue
+		}
+		if paths.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, p := range paths.Content {
+			if p.Kind == yaml.ScalarNode && p.Value == "" {
+				problems = append(problems, fmt.Sprintf("line %d: %s %s has an empty path", p.Line, section, name.Value))
+			} else if filepath.IsAbs(p.Value) || strings.HasPrefix(p.Value, "/") || p.Value == ".." || strings.HasPrefix(filepath.ToSlash(p.Value), "../") {
+				problems = append(problems, fmt.Sprintf("line %d: path %s of %s %s must be relative to the project root", p.Line, p.Value, section, name.Value))
+			}
+		}
+	}
+	return problems
+}
+
+// validateYamlScopes checks the include and exclude sections of qodana.yaml before the analysis is started,
+// so malformed entries are reported with their lines instead of being ignored by the linter.
+func (o *QodanaOptions) validateYamlScopes() error {
+	mapping, err := readYamlMapping(o.qodanaYamlPath())
+	if err != nil {
+		return nil
+	}
+	problems := make([]string, 0)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if section := mapping.Content[i].Value; section == "include" || section == "exclude" {
+			problems = append(problems, validateInspectionScope(section, mapping.Content[i+1])...)
+		}
+	}
+	if len(problems) > 0 {
+		return configErrorf("Invalid inspection scopes in %s:\n%s", o.qodanaYamlPath(), strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// loadLocalQodanaYaml reads the qodana.yaml without merging in the included files, to update and write it back.
+func loadLocalQodanaYaml(qodanaYamlPath string) *QodanaYaml {
+	q := &QodanaYaml{}
+	mapping, err := readYamlMapping(qodanaYamlPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return q
+	} else if err != nil {
+		log.Fatalf("Unmarshal: %v", err)
+	}
+	q.IncludeFiles = extractYamlIncludeFiles(mapping)
+	if err = mapping.Decode(q); err != nil {
+		log.Fatalf("Unmarshal: %v", err)
+	}
+	return q
+}
+
+// readYamlMapping reads the yaml file as a mapping node, an empty file gives an empty mapping.
+func readYamlMapping(path string) (*yaml.Node, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of options", path)
+	}
+	return document.Content[0], nil
+}
+
+// extractYamlIncludeFiles removes the file entries (plain strings) from the include section of the mapping and
+// returns them, the inspection entries ({name: ..., paths: ...}) are kept. An include section left empty is removed.
+func extractYamlIncludeFiles(mapping *yaml.Node) []string {
+	var files []string
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Value != "include" || value.Kind != yaml.SequenceNode {
+			continue
+		}
+		inspections := make([]*yaml.Node, 0, len(value.Content))
+		for _, item := range value.Content {
+			if item.Kind == yaml.ScalarNode {
+				files = append(files, item.Value)
+			} else {
+				inspections = append(inspections, item)
+			}
+		}
+		value.Content = inspections
+		if len(inspections) == 0 && len(files) > 0 {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		}
+		break
+	}
+	return files
+}
+
+// setYamlIncludeFiles adds the file entries to the include section of the mapping, before the inspection entries.
+func setYamlIncludeFiles(mapping *yaml.Node, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	items := make([]*yaml.Node, 0, len(files))
+	for _, file := range files {
+		items = append(items, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: file})
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "include" {
+			mapping.Content[i+1].Content = append(items, mapping.Content[i+1].Content...)
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "include"},
+		&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: items},
+	)
+}
+
+// loadYamlWithIncludes reads the yaml file and merges it over the files listed in its include section, recursively.
+// The include paths are relative to the including file, later includes and the file itself override the earlier values.
+// chain contains the files being included, to report include cycles.
+func loadYamlWithIncludes(path string, chain []string) (*yaml.Node, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, absPath)
+	if Contains(chain[:len(chain)-1], absPath) {
+		return nil, fmt.Errorf("include cycle in qodana.yaml: %s", strings.Join(chain, " -> "))
+	}
+	mapping, err := readYamlMapping(absPath)
+	if err != nil {
+		return nil, err
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, file := range extractYamlIncludeFiles(mapping) {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(absPath), file)
+		}
+		included, err := loadYamlWithIncludes(file, chain)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%s includes %s which does not exist", absPath, file)
+			}
+			return nil, err
+		}
+		merged = mergeYamlMappings(merged, included)
+	}
+	return mergeYamlMappings(merged, mapping), nil
+}
+
+// mergeYamlMappings returns the base mapping with the keys of the override mapping set over it,
+// nested mappings are merged, other values (including lists) are replaced.
+func mergeYamlMappings(base *yaml.Node, override *yaml.Node) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: append([]*yaml.Node{}, base.Content...)}
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value != key.Value {
+				continue
+			}
+			if existing := merged.Content[j+1]; existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				merged.Content[j+1] = mergeYamlMappings(existing, value)
+			} else {
+				merged.Content[j+1] = value
+			}
+			found = true
+			break
+		}
+		if !found {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// writeMergedConfig writes the qodana.yaml merged with its included files to outPath.
+func writeMergedConfig(configPath string, outPath string) error {
+	mapping, err := loadYamlWithIncludes(configPath, nil)
+	if err != nil {
+		return err
+	}
+	config := make(map[string]interface{}) // decoded to resolve the aliases to anchors in other files
+	if err = mapping.Decode(&config); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(outPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0o600)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -81,14 +82,218 @@ dotnet:
 				},
 			},
 		},
+		{
+			description: "file exists with volumes and env",
+			setup: func(name string) {
+				content := `version: 1.0
+volumes:
+  - /opt/cache:/data/cache
+env:
+  - FOO=bar`
+				setupTestFile(name, content)
+			},
+			project:  os.TempDir(),
+			filename: "volumes.yaml",
+			expected: &QodanaYaml{
+				Version: "1.0",
+				Volumes: []string{"/opt/cache:/data/cache"},
+				Env:     []string{"FOO=bar"},
+			},
+		},
+		{
+			description: "file exists with plugins",
+			setup: func(name string) {
+				content := `version: 1.0
+plugins:
+  - org.intellij.scala
+  - id: Pythonid`
+				setupTestFile(name, content)
+			},
+			project:  os.TempDir(),
+			filename: "plugins.yaml",
+			expected: &QodanaYaml{
+				Version: "1.0",
+				Plugins: []Plugin{{Id: "org.intellij.scala"}, {Id: "Pythonid"}},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
 			tc.setup(tc.filename)
-			actual := LoadQodanaYaml(tc.project, tc.filename)
+			actual := LoadQodanaYaml(filepath.Join(tc.project, tc.filename))
 			_ = os.Remove(filepath.Join(tc.project, tc.filename))
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
 }
+
+func TestValidateQodanaYaml(t *testing.T) {
+	testCases := []struct {
+		description string
+		content     string
+		expected    []string
+	}{
+		{
+			description: "valid config",
+			content: `version: "1.0"
+linter: jetbrains/qodana-jvm:2023.3
+failThreshold: 10`,
+			expected: []string{},
+		},
+		{
+			description: "unknown key",
+			content: `version: "1.0"
+failTreshold: 10`,
+			expected: []string{"line 2: unknown key failTreshold"},
+		},
+		{
+			description: "wrong type",
+			content: `version: "1.0"
+failThreshold: many`,
+			expected: []string{"line 2: cannot unmarshal !!str `many` into int"},
+		},
+		{
+			description: "negative fail threshold",
+			content: `version: "1.0"
+failThreshold: -1`,
+			expected: []string{"line 2: failThreshold -1 is not a non-negative integer"},
+		},
+		{
+			description: "invalid linter and ide",
+			content: `linter: cobol
+ide: QDCOBOL`,
+			expected: []string{
+				"line 1: invalid linter: " + func() string { _, err := resolveLinter("cobol"); return err.Error() }(),
+				"line 2: unknown ide QDCOBOL, available codes are: " + strings.Join(AllCodes, ", "),
+			},
+		},
+		{
+			description: "invalid volumes and env",
+			content: `volumes:
+  - /cache:/data/cache
+  - /cache
+env:
+  - FOO=bar
+  - FOO`,
+			expected: []string{
+				"line 3: invalid volume \"/cache\", expected host:container",
+				"line 6: invalid env \"FOO\", expected KEY=VALUE",
+			},
+		}, {
+			description: "invalid inspection scopes",
+			content: `include:
+  - name: CheckDependencyLicenses
+  - name: ""
+exclude:
+  - name: All
+    paths:
+      - build
+      - ../shared
+      - /tmp/generated
+  - paths:
+      - vendor
+      - ""`,
+			expected: []string{
+				"line 3: include entry has no inspection name",
+				"line 8: path ../shared of exclude All must be relative to the project root",
+				"line 9: path /tmp/generated of exclude All must be relative to the project root",
+				"line 10: exclude entry has no inspection name",
+			},
+		},
+		{
+			description: "invalid plugins",
+			content: `plugins:
+  - org.intellij.scala
+  - id: Pythonid
+  - ""
+  - id: ""
+  - name: Pythonid
+  - Pythonid`,
+			expected: []string{
+				"line 4: plugins entry has no id",
+				"line 5: plugins entry has no id",
+				"line 6: plugins entry has no id",
+				"line 7: plugin Pythonid is listed more than once",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			project := t.TempDir()
+			if err := os.WriteFile(filepath.Join(project, "qodana.yaml"), []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			actual, err := ValidateQodanaYaml(project, "qodana.yaml")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestQodanaYamlIncludes(t *testing.T) {
+	project := t.TempDir()
+	files := map[string]string{
+		"common/base.yaml": `version: "1.0"
+linter: jetbrains/qodana-jvm:2023.3
+failThreshold: 10
+properties: &props
+  idea.max.intellisense.filesize: "4096"
+  foo: base
+exclude:
+  - name: All
+    paths: [build]`,
+		"common/strict.yaml": `failThreshold: 0`,
+		"module/qodana.yaml": `include:
+  - ../common/base.yaml
+  - ../common/strict.yaml
+  - name: ConstantValue
+properties:
+  foo: module`,
+	}
+	for name, content := range files {
+		path := filepath.Join(project, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modulePath := filepath.Join(project, "module", "qodana.yaml")
+
+	q := LoadQodanaYaml(modulePath)
+	assert.Equal(t, "jetbrains/qodana-jvm:2023.3", q.Linter)
+	assert.Equal(t, 0, *q.FailThreshold)
+	assert.Equal(t, map[string]string{"idea.max.intellisense.filesize": "4096", "foo": "module"}, q.Properties)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"build"}}}, q.Excludes)
+	assert.Equal(t, []Clude{{Name: "ConstantValue"}}, q.Includes)
+	assert.Equal(t, []string{"../common/base.yaml", "../common/strict.yaml"}, q.IncludeFiles)
+
+	problems, err := ValidateQodanaYaml(filepath.Join(project, "module"), "qodana.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, problems)
+
+	SetQodanaLinter(filepath.Join(project, "module"), "jetbrains/qodana-python:2023.3", "qodana.yaml")
+	local := loadLocalQodanaYaml(modulePath)
+	assert.Equal(t, []string{"../common/base.yaml", "../common/strict.yaml"}, local.IncludeFiles)
+	assert.Nil(t, local.FailThreshold, "included values are not copied to the file")
+	assert.Equal(t, "jetbrains/qodana-python:2023.3", LoadQodanaYaml(modulePath).Linter)
+
+	mergedPath := filepath.Join(t.TempDir(), "qodana.yaml")
+	assert.NoError(t, writeMergedConfig(modulePath, mergedPath))
+	merged := LoadQodanaYaml(mergedPath)
+	assert.Nil(t, merged.IncludeFiles)
+	assert.Equal(t, 0, *merged.FailThreshold)
+	assert.Equal(t, "module", merged.Properties["foo"])
+
+	if err = os.WriteFile(filepath.Join(project, "common", "strict.yaml"), []byte("include: [../module/qodana.yaml]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadYamlWithIncludes(modulePath, nil)
+	assert.ErrorContains(t, err, "include cycle in qodana.yaml")
+	problems, err = ValidateQodanaYaml(filepath.Join(project, "module"), "qodana.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+}
@@ -37,7 +37,7 @@ func main() {
 		core.CheckForUpdates(core.Version)
 		core.ContainerCleanup()
 		_ = core.QodanaSpinner.Stop()
-		os.Exit(0)
+		core.Exit(core.QodanaInterruptedExitCode)
 	}()
 	cmd.Execute()
 }